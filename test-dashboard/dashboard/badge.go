@@ -0,0 +1,151 @@
+package dashboard
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Badge colours, from the dashboard's theme.
+const (
+	badgeHigh    = "#6366f1"
+	badgeMedium  = "#f59e0b"
+	badgeLow     = "#ef4444"
+	badgePassing = "#10b981"
+	badgeUnknown = "#9f9f9f"
+	badgeLabel   = "#555"
+)
+
+// Badge is a shields-style badge: a grey label next to a coloured message.
+type Badge struct {
+	Label   string
+	Message string
+	Color   string
+}
+
+// coverageLevel buckets a coverage percentage the way the UI colours it.
+func coverageLevel(coverage float64) string {
+	switch {
+	case coverage >= 80:
+		return "high"
+	case coverage >= 60:
+		return "medium"
+	}
+	return "low"
+}
+
+// badgeResult returns the latest stored run and, if pkg is set, its result of
+// that package by relative or import path. run is nil if nothing is stored
+// yet; ok is false if the run has no such package.
+func (td *TestDashboard) badgeResult(pkg string) (run *RunRecord, result *TestResult, ok bool) {
+	run, err := td.History.Latest()
+	if err != nil {
+		return nil, nil, true
+	}
+	if pkg == "" {
+		return run, nil, true
+	}
+	for i := range run.Data.Results {
+		r := &run.Data.Results[i]
+		if r.Package == pkg || r.ImportPath == pkg || r.Package == "./"+pkg {
+			return run, r, true
+		}
+	}
+	return run, nil, false
+}
+
+// CoverageBadge shows the coverage of the latest stored run, or of one of its
+// packages. It returns false if the run has no such package.
+func (td *TestDashboard) CoverageBadge(pkg string) (Badge, bool) {
+	badge := Badge{Label: "coverage", Message: "unknown", Color: badgeUnknown}
+	run, result, ok := td.badgeResult(pkg)
+	if run == nil || !ok {
+		return badge, ok
+	}
+	coverage := run.Data.OverallCoverage
+	if result != nil {
+		if result.NoTests {
+			badge.Message, badge.Color = "no tests", badgeLow
+			return badge, true
+		}
+		coverage = result.Coverage
+	}
+	badge.Message = fmt.Sprintf("%.1f%%", coverage)
+	switch coverageLevel(coverage) {
+	case "high":
+		badge.Color = badgeHigh
+	case "medium":
+		badge.Color = badgeMedium
+	default:
+		badge.Color = badgeLow
+	}
+	return badge, true
+}
+
+// StatusBadge shows whether the latest stored run, or one of its packages,
+// passed. It returns false if the run has no such package.
+func (td *TestDashboard) StatusBadge(pkg string) (Badge, bool) {
+	badge := Badge{Label: "tests", Message: "unknown", Color: badgeUnknown}
+	run, result, ok := td.badgeResult(pkg)
+	if run == nil || !ok {
+		return badge, ok
+	}
+	switch {
+	case result == nil:
+		if failed := run.Data.TotalTests - run.Data.PassedTests; failed > 0 {
+			badge.Message, badge.Color = fmt.Sprintf("%d failing", failed), badgeLow
+		} else {
+			badge.Message, badge.Color = "passing", badgePassing
+		}
+	case result.NoTests:
+		badge.Message = "no tests"
+	case result.Passed:
+		badge.Message, badge.Color = "passing", badgePassing
+	default:
+		badge.Message, badge.Color = "failing", badgeLow
+	}
+	return badge, true
+}
+
+// badgeTextWidth estimates the width in pixels of s in 11px Verdana, the font
+// shields-style badges use.
+func badgeTextWidth(s string) int {
+	width := 0.0
+	for _, r := range s {
+		switch {
+		case strings.ContainsRune("fijlrtI.,:;|!'() ", r):
+			width += 4
+		case strings.ContainsRune("mwMW%", r):
+			width += 10.5
+		case r >= 'A' && r <= 'Z':
+			width += 7.5
+		default:
+			width += 6.8
+		}
+	}
+	return int(width + 0.5)
+}
+
+// SVG renders the badge in the flat shields.io style.
+func (b Badge) SVG() []byte {
+	label, message := html.EscapeString(b.Label), html.EscapeString(b.Message)
+	lw, mw := badgeTextWidth(b.Label)+10, badgeTextWidth(b.Message)+10
+	w := lw + mw
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+<title>%[4]s: %[5]s</title>
+<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)">
+<rect width="%[2]d" height="20" fill="%[6]s"/>
+<rect x="%[2]d" width="%[3]d" height="20" fill="%[7]s"/>
+<rect width="%[1]d" height="20" fill="url(#s)"/>
+</g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[4]s</text>
+<text x="%[8]d" y="14">%[4]s</text>
+<text x="%[9]d" y="15" fill="#010101" fill-opacity=".3">%[5]s</text>
+<text x="%[9]d" y="14">%[5]s</text>
+</g>
+</svg>
+`, w, lw, mw, label, message, badgeLabel, html.EscapeString(b.Color), lw/2, lw+mw/2))
+}
@@ -0,0 +1,343 @@
+package dashboard
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Benchmark holds every sample collected for one benchmark, keyed by unit
+// ("ns/op", "B/op", "allocs/op" or a custom b.ReportMetric unit). There is one
+// sample per -count iteration.
+type Benchmark struct {
+	Package    string               `json:"package"`
+	Name       string               `json:"name"`
+	Iterations []int64              `json:"iterations"`
+	Samples    map[string][]float64 `json:"samples"`
+}
+
+// BenchmarkRun is a stored `go test -bench` run over one or more packages.
+type BenchmarkRun struct {
+	ID         string      `json:"id"`
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt time.Time   `json:"finished_at"`
+	Commit     string      `json:"commit,omitempty"`
+	TreeHash   string      `json:"tree_hash,omitempty"`
+	Packages   []string    `json:"packages"`
+	Pattern    string      `json:"pattern"`
+	Count      int         `json:"count"`
+	Benchmarks []Benchmark `json:"benchmarks"`
+	Output     string      `json:"output"`
+	Passed     bool        `json:"passed"`
+}
+
+// BenchmarkOptions selects what a benchmark run executes.
+type BenchmarkOptions struct {
+	Packages []string `json:"packages"`
+	Pattern  string   `json:"pattern"`
+	Count    int      `json:"count"`
+}
+
+// BenchmarkProgress is pushed over the WebSocket while a benchmark run is going.
+type BenchmarkProgress struct {
+	RunID   string `json:"run_id"`
+	Package string `json:"package,omitempty"`
+	Done    int    `json:"done"`
+	Total   int    `json:"total"`
+	Status  string `json:"status"` // "running" or "finished"
+}
+
+// BenchmarkDelta compares one benchmark metric between two runs.
+type BenchmarkDelta struct {
+	Package      string        `json:"package"`
+	Name         string        `json:"name"`
+	Unit         string        `json:"unit"`
+	Base         SampleSummary `json:"base"`
+	Head         SampleSummary `json:"head"`
+	DeltaPercent float64       `json:"delta_percent"`
+	PValue       float64       `json:"p_value"`
+	Significant  bool          `json:"significant"`
+	Verdict      string        `json:"verdict"` // "regression", "improvement", "~", "added" or "removed"
+}
+
+// BenchmarkComparison is the benchstat-style comparison of two benchmark runs.
+type BenchmarkComparison struct {
+	Base   string           `json:"base"`
+	Head   string           `json:"head"`
+	Alpha  float64          `json:"alpha"`
+	Deltas []BenchmarkDelta `json:"deltas"`
+}
+
+// BenchmarkHistory stores benchmark runs and the chosen baseline run.
+type BenchmarkHistory struct {
+	store        recordStore
+	baselinePath string
+}
+
+// NewBenchmarkHistory returns the benchmark store for a project.
+func NewBenchmarkHistory(projectPath string) *BenchmarkHistory {
+	dir := projectDataDir(projectPath)
+	return &BenchmarkHistory{
+		store:        recordStore{Dir: filepath.Join(dir, "benchmarks")},
+		baselinePath: filepath.Join(dir, "benchmark-baseline"),
+	}
+}
+
+// Load reads a stored benchmark run.
+func (bh *BenchmarkHistory) Load(id string) (*BenchmarkRun, error) {
+	var run BenchmarkRun
+	if err := bh.store.load(id, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// List returns the stored benchmark runs without their raw output, newest first.
+func (bh *BenchmarkHistory) List() ([]BenchmarkRun, error) {
+	ids, err := bh.store.ids()
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]BenchmarkRun, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		run, err := bh.Load(ids[i])
+		if err != nil {
+			continue
+		}
+		run.Output = ""
+		runs = append(runs, *run)
+	}
+	return runs, nil
+}
+
+// Baseline returns the ID of the baseline run, or "" if none was chosen.
+func (bh *BenchmarkHistory) Baseline() string {
+	content, err := os.ReadFile(bh.baselinePath)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// SetBaseline marks a stored run as the baseline for comparisons.
+func (bh *BenchmarkHistory) SetBaseline(id string) error {
+	if _, err := bh.Load(id); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(bh.baselinePath), 0o755); err != nil {
+		return fmt.Errorf("could not create data directory: %w", err)
+	}
+	return os.WriteFile(bh.baselinePath, []byte(id+"\n"), 0o644)
+}
+
+// Latest returns the ID of the most recent benchmark run, or "".
+func (bh *BenchmarkHistory) Latest() string {
+	ids, err := bh.store.ids()
+	if err != nil || len(ids) == 0 {
+		return ""
+	}
+	return ids[len(ids)-1]
+}
+
+// StartBenchmarks validates the options, records a new benchmark run and runs it
+// in the background. Benchmarks wait for any test run to finish first so they
+// don't compete for the CPU.
+func (td *TestDashboard) StartBenchmarks(opts BenchmarkOptions) (*BenchmarkRun, error) {
+	if len(opts.Packages) == 0 {
+		return nil, fmt.Errorf("at least one package is required")
+	}
+	packages, err := td.resolvePackages(opts.Packages...)
+	if err != nil {
+		return nil, err
+	}
+	opts.Packages = packages
+	if opts.Pattern == "" {
+		opts.Pattern = "."
+	}
+	if opts.Count < 1 {
+		opts.Count = 6 // enough samples for a 95% confidence interval
+	}
+
+	startedAt := time.Now()
+	run := &BenchmarkRun{
+		ID:        newRunID(startedAt),
+		StartedAt: startedAt,
+		Packages:  opts.Packages,
+		Pattern:   opts.Pattern,
+		Count:     opts.Count,
+	}
+	started := *run // the background run keeps mutating its own copy
+	go td.runBenchmarks(run)
+	return &started, nil
+}
+
+func (td *TestDashboard) runBenchmarks(run *BenchmarkRun) {
+	td.lockRun("benchmark")
+	defer td.runMu.Unlock()
+
+	log.Printf("Running benchmarks %q in %d packages", run.Pattern, len(run.Packages))
+	run.Commit = headCommit(td.ProjectPath)
+	run.TreeHash = workingTreeHash(td.ProjectPath)
+	run.Passed = true
+
+	var output strings.Builder
+	for i, pkg := range run.Packages {
+		td.Notify("benchmark", BenchmarkProgress{RunID: run.ID, Package: pkg, Done: i, Total: len(run.Packages), Status: "running"})
+
+		cmd := td.projectCtx().goCommand(pkg, "test", "-run", "^$", "-bench", run.Pattern, "-benchmem",
+			fmt.Sprintf("-count=%d", run.Count))
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			run.Passed = false
+		}
+		output.Write(out)
+		run.Benchmarks = append(run.Benchmarks, parseBenchmarkOutput(pkg, string(out))...)
+	}
+	run.Output = output.String()
+	run.FinishedAt = time.Now()
+
+	if err := td.Benchmarks.store.save(run.ID, run); err != nil {
+		log.Printf("Error storing benchmark run %s: %v", run.ID, err)
+	}
+	td.Notify("benchmark", BenchmarkProgress{RunID: run.ID, Done: len(run.Packages), Total: len(run.Packages), Status: "finished"})
+	log.Printf("Benchmark run %s complete: %d benchmarks", run.ID, len(run.Benchmarks))
+}
+
+// parseBenchmarkOutput extracts benchmark results from `go test -bench` text
+// output. Result lines look like
+//
+//	BenchmarkAdd-8   1000000000   0.2563 ns/op   0 B/op   0 allocs/op   12.0 widgets/op
+//
+// and repeat once per -count iteration.
+func parseBenchmarkOutput(pkg, output string) []Benchmark {
+	var benchmarks []Benchmark
+	index := make(map[string]int)
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") || len(fields)%2 != 0 {
+			continue
+		}
+		iterations, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		name := fields[0]
+		i, ok := index[name]
+		if !ok {
+			i = len(benchmarks)
+			index[name] = i
+			benchmarks = append(benchmarks, Benchmark{Package: pkg, Name: name, Samples: make(map[string][]float64)})
+		}
+		b := &benchmarks[i]
+		b.Iterations = append(b.Iterations, iterations)
+		for j := 2; j+1 < len(fields); j += 2 {
+			value, err := strconv.ParseFloat(fields[j], 64)
+			if err != nil {
+				continue
+			}
+			unit := fields[j+1]
+			b.Samples[unit] = append(b.Samples[unit], value)
+		}
+	}
+	return benchmarks
+}
+
+// CompareBenchmarks compares every metric of every benchmark in two stored runs.
+func (bh *BenchmarkHistory) CompareBenchmarks(baseID, headID string) (*BenchmarkComparison, error) {
+	base, err := bh.Load(baseID)
+	if err != nil {
+		return nil, fmt.Errorf("base run: %w", err)
+	}
+	head, err := bh.Load(headID)
+	if err != nil {
+		return nil, fmt.Errorf("head run: %w", err)
+	}
+
+	key := func(b Benchmark) string { return b.Package + " " + b.Name }
+	baseByKey := make(map[string]Benchmark)
+	for _, b := range base.Benchmarks {
+		baseByKey[key(b)] = b
+	}
+
+	comparison := &BenchmarkComparison{Base: baseID, Head: headID, Alpha: significanceAlpha}
+	seen := make(map[string]bool)
+	for _, h := range head.Benchmarks {
+		seen[key(h)] = true
+		b, inBase := baseByKey[key(h)]
+		for _, unit := range sortedUnits(h.Samples) {
+			delta := BenchmarkDelta{Package: h.Package, Name: h.Name, Unit: unit, Head: summarizeSamples(h.Samples[unit])}
+			baseSamples := b.Samples[unit]
+			if !inBase || len(baseSamples) == 0 {
+				delta.Verdict = "added"
+				comparison.Deltas = append(comparison.Deltas, delta)
+				continue
+			}
+			delta.Base = summarizeSamples(baseSamples)
+			if delta.Base.Median != 0 {
+				delta.DeltaPercent = (delta.Head.Median - delta.Base.Median) / delta.Base.Median * 100
+			}
+			delta.PValue = mannWhitneyU(baseSamples, h.Samples[unit])
+			delta.Significant = delta.PValue < significanceAlpha && delta.DeltaPercent != 0
+			delta.Verdict = "~"
+			if delta.Significant {
+				worse := delta.DeltaPercent > 0
+				if higherIsBetter(unit) {
+					worse = !worse
+				}
+				delta.Verdict = "improvement"
+				if worse {
+					delta.Verdict = "regression"
+				}
+			}
+			comparison.Deltas = append(comparison.Deltas, delta)
+		}
+	}
+	for _, b := range base.Benchmarks {
+		if seen[key(b)] {
+			continue
+		}
+		for _, unit := range sortedUnits(b.Samples) {
+			comparison.Deltas = append(comparison.Deltas, BenchmarkDelta{
+				Package: b.Package, Name: b.Name, Unit: unit,
+				Base: summarizeSamples(b.Samples[unit]), Verdict: "removed",
+			})
+		}
+	}
+	return comparison, nil
+}
+
+// higherIsBetter reports whether larger values of a metric are improvements.
+// Like benchstat, throughput units ("MB/s", "ops/s", ...) count as such.
+func higherIsBetter(unit string) bool {
+	return strings.HasSuffix(unit, "/s")
+}
+
+// sortedUnits lists the units of a benchmark with the standard ones first.
+func sortedUnits(samples map[string][]float64) []string {
+	order := map[string]int{"ns/op": 0, "B/op": 1, "allocs/op": 2}
+	units := make([]string, 0, len(samples))
+	for unit := range samples {
+		units = append(units, unit)
+	}
+	sort.Slice(units, func(i, j int) bool {
+		oi, iok := order[units[i]]
+		oj, jok := order[units[j]]
+		switch {
+		case iok && jok:
+			return oi < oj
+		case iok != jok:
+			return iok
+		}
+		return units[i] < units[j]
+	})
+	return units
+}
@@ -0,0 +1,66 @@
+package dashboard
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseBenchmarkOutput(t *testing.T) {
+	// Output of go test -bench . -benchmem -count=2 -cpu=1,2 -benchtime=2000x,
+	// with a custom widgets/op metric reported by BenchmarkParse.
+	output, err := os.ReadFile("testdata/bench.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := parseBenchmarkOutput("./benchfix", string(output))
+
+	iterations := []int64{2000, 2000}
+	parse := func(name string, ns ...float64) Benchmark {
+		return Benchmark{Package: "./benchfix", Name: name, Iterations: iterations, Samples: map[string][]float64{
+			"ns/op":      ns,
+			"widgets/op": {12, 12},
+			"B/op":       {24, 24},
+			"allocs/op":  {1, 1},
+		}}
+	}
+	want := []Benchmark{
+		{Package: "./benchfix", Name: "BenchmarkJoin", Iterations: iterations, Samples: map[string][]float64{
+			"ns/op":     {55.81, 55.27},
+			"B/op":      {8, 8},
+			"allocs/op": {1, 1},
+		}},
+		{Package: "./benchfix", Name: "BenchmarkJoin-2", Iterations: iterations, Samples: map[string][]float64{
+			"ns/op":     {76.36, 119.0},
+			"B/op":      {8, 8},
+			"allocs/op": {1, 1},
+		}},
+		parse("BenchmarkParse/size=small", 52.86, 51.28),
+		parse("BenchmarkParse/size=small-2", 67.33, 82.57),
+		parse("BenchmarkParse/size=large", 83.10, 54.23),
+		parse("BenchmarkParse/size=large-2", 110.7, 97.80),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseBenchmarkOutput:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseBenchmarkOutputSkipsNoise(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   int
+	}{
+		{"log line", "BenchmarkJoin starting up now\n", 0},
+		{"odd field count", "BenchmarkJoin 100 5 ns/op 8\n", 0},
+		{"no iteration count", "BenchmarkJoin many 5 ns/op\n", 0},
+		{"too short", "BenchmarkJoin 100\n", 0},
+		{"failure line", "--- FAIL: BenchmarkJoin\n", 0},
+		{"result between logs", "BenchmarkJoin\n    join_test.go:12: warming up\nBenchmarkJoin 100 5 ns/op\n", 1},
+	}
+	for _, tt := range tests {
+		if got := parseBenchmarkOutput("./p", tt.output); len(got) != tt.want {
+			t.Errorf("%s: parsed %d benchmarks from %q; want %d", tt.name, len(got), tt.output, tt.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package dashboard
+
+import (
+	"math"
+	"sort"
+)
+
+// The statistics below follow benchstat: each sample set is summarised by its
+// median with a distribution-free confidence interval, and two sample sets are
+// compared with a two-sided Mann-Whitney U test.
+
+// confidenceLevel is used for the confidence interval around each median.
+const confidenceLevel = 0.95
+
+// significanceAlpha is the p-value below which a delta is reported as real.
+const significanceAlpha = 0.05
+
+// SampleSummary describes one set of benchmark samples.
+type SampleSummary struct {
+	N      int     `json:"n"`
+	Median float64 `json:"median"`
+	CILow  float64 `json:"ci_low"`
+	CIHigh float64 `json:"ci_high"`
+	HasCI  bool    `json:"has_ci"` // false when there are too few samples for the confidence level
+}
+
+func summarizeSamples(samples []float64) SampleSummary {
+	sorted := append([]float64{}, samples...)
+	sort.Float64s(sorted)
+	summary := SampleSummary{N: len(sorted)}
+	if len(sorted) == 0 {
+		return summary
+	}
+	summary.Median = median(sorted)
+	if lo, hi, ok := medianCI(len(sorted), confidenceLevel); ok {
+		summary.CILow, summary.CIHigh, summary.HasCI = sorted[lo], sorted[hi], true
+	}
+	return summary
+}
+
+// median of an already sorted, non-empty slice.
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// medianCI returns the indices of the order statistics that bound the median
+// with at least the given confidence, using the Binomial(n, 1/2) distribution
+// of the number of samples below the true median.
+func medianCI(n int, level float64) (lo, hi int, ok bool) {
+	// Start from the outermost interval, sorted[0] to sorted[n-1], and narrow
+	// it symmetrically while it still covers the median at the requested level.
+	coverage := func(k int) float64 {
+		// The interval sorted[k] to sorted[n-1-k] holds the median when
+		// k+1 <= B <= n-1-k for B ~ Binomial(n, 1/2).
+		p := 0.0
+		for i := k + 1; i <= n-1-k; i++ {
+			p += binomialPMF(n, i)
+		}
+		return p
+	}
+	best := -1
+	for k := 0; k < n/2; k++ {
+		if coverage(k) >= level {
+			best = k
+		} else {
+			break
+		}
+	}
+	if best < 0 {
+		return 0, 0, false
+	}
+	return best, n - 1 - best, true
+}
+
+func binomialPMF(n, k int) float64 {
+	lg := func(x int) float64 { v, _ := math.Lgamma(float64(x + 1)); return v }
+	return math.Exp(lg(n) - lg(k) - lg(n-k) - float64(n)*math.Ln2)
+}
+
+// mannWhitneyU returns the two-sided p-value of the Mann-Whitney U test for
+// the hypothesis that x and y come from the same distribution. Small samples
+// without ties use the exact distribution of U; otherwise the normal
+// approximation with tie and continuity correction is used.
+func mannWhitneyU(x, y []float64) float64 {
+	m, n := len(x), len(y)
+	if m == 0 || n == 0 {
+		return 1
+	}
+
+	type value struct {
+		v     float64
+		fromX bool
+	}
+	all := make([]value, 0, m+n)
+	for _, v := range x {
+		all = append(all, value{v, true})
+	}
+	for _, v := range y {
+		all = append(all, value{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+
+	// Assign average ranks to ties and accumulate the tie correction term.
+	rankSumX := 0.0
+	tieTerm := 0.0
+	hasTies := false
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // average of ranks i+1..j
+		for k := i; k < j; k++ {
+			if all[k].fromX {
+				rankSumX += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			hasTies = true
+			tieTerm += t*t*t - t
+		}
+		i = j
+	}
+	u := rankSumX - float64(m*(m+1))/2
+
+	if !hasTies && m <= 50 && n <= 50 {
+		return exactMannWhitneyP(u, m, n)
+	}
+
+	N := float64(m + n)
+	mu := float64(m*n) / 2
+	sigma := math.Sqrt(float64(m*n) / 12 * ((N + 1) - tieTerm/(N*(N-1))))
+	if sigma == 0 {
+		return 1
+	}
+	z := math.Abs(u-mu) - 0.5
+	if z < 0 {
+		z = 0
+	}
+	return math.Min(1, math.Erfc(z/sigma/math.Sqrt2))
+}
+
+// exactMannWhitneyP computes the two-sided p-value from the exact null
+// distribution of U, built with the recurrence
+// f(u; m, n) = f(u-n; m-1, n) + f(u; m, n-1).
+func exactMannWhitneyP(u float64, m, n int) float64 {
+	maxU := m * n
+	// dist[j] holds the distribution for (i, j) as i grows row by row.
+	dist := make([][]float64, n+1)
+	for j := range dist {
+		dist[j] = make([]float64, maxU+1)
+		dist[j][0] = 1 // i = 0: U is always 0
+	}
+	for i := 1; i <= m; i++ {
+		next := make([][]float64, n+1)
+		next[0] = make([]float64, maxU+1)
+		next[0][0] = 1 // j = 0: U is always 0
+		for j := 1; j <= n; j++ {
+			row := make([]float64, maxU+1)
+			for v := 0; v <= i*j; v++ {
+				row[v] = next[j-1][v]
+				if v >= j {
+					row[v] += dist[j][v-j]
+				}
+			}
+			next[j] = row
+		}
+		dist = next
+	}
+
+	counts := dist[n]
+	total := 0.0
+	for _, c := range counts {
+		total += c
+	}
+	lower, upper := 0.0, 0.0
+	for v, c := range counts {
+		if float64(v) <= u {
+			lower += c
+		}
+		if float64(v) >= u {
+			upper += c
+		}
+	}
+	return math.Min(1, 2*math.Min(lower, upper)/total)
+}
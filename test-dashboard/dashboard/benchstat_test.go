@@ -0,0 +1,103 @@
+package dashboard
+
+import (
+	"math"
+	"testing"
+)
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestMedianCI(t *testing.T) {
+	tests := []struct {
+		n      int
+		lo, hi int
+		ok     bool
+	}{
+		{n: 0},
+		{n: 1},
+		{n: 5}, // the outermost interval only covers 93.75%
+		{n: 6, lo: 0, hi: 5, ok: true},
+		{n: 8, lo: 0, hi: 7, ok: true},
+		{n: 10, lo: 1, hi: 8, ok: true},
+		{n: 20, lo: 5, hi: 14, ok: true},
+	}
+	for _, tt := range tests {
+		lo, hi, ok := medianCI(tt.n, 0.95)
+		if ok != tt.ok || (ok && (lo != tt.lo || hi != tt.hi)) {
+			t.Errorf("medianCI(%d, 0.95) = %d, %d, %v; want %d, %d, %v", tt.n, lo, hi, ok, tt.lo, tt.hi, tt.ok)
+		}
+	}
+}
+
+func TestSummarizeSamples(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []float64
+		want    SampleSummary
+	}{
+		{"empty", nil, SampleSummary{}},
+		{"too few for a CI", []float64{3, 1, 2, 5, 4}, SampleSummary{N: 5, Median: 3}},
+		{"even count", []float64{6, 1, 5, 2, 4, 3}, SampleSummary{N: 6, Median: 3.5, CILow: 1, CIHigh: 6, HasCI: true}},
+		{"ten samples", []float64{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, SampleSummary{N: 10, Median: 5.5, CILow: 2, CIHigh: 9, HasCI: true}},
+	}
+	for _, tt := range tests {
+		if got := summarizeSamples(tt.samples); got != tt.want {
+			t.Errorf("%s: summarizeSamples(%v) = %+v; want %+v", tt.name, tt.samples, got, tt.want)
+		}
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	seq := func(from, to int) []float64 {
+		var s []float64
+		for i := from; i < to; i++ {
+			s = append(s, float64(i))
+		}
+		return s
+	}
+	tests := []struct {
+		name string
+		x, y []float64
+		want float64
+	}{
+		// Fully separated samples: the p-values benchstat prints for
+		// -count=3, 5 and 6 are 0.100, 0.008 and 0.002.
+		{"exact 3v3 separated", []float64{1, 2, 3}, []float64{4, 5, 6}, 0.1},
+		{"exact 5v5 separated", seq(0, 5), seq(10, 15), 2.0 / 252},
+		{"exact 6v6 separated", seq(10, 16), seq(0, 6), 2.0 / 924},
+		{"exact interleaved", []float64{1, 3, 5}, []float64{2, 4, 6}, 0.7},
+		{"normal approximation with ties", []float64{1, 2, 2, 3}, []float64{2, 3, 4, 5}, 0.13665824773814753},
+		{"normal approximation with ties across samples",
+			[]float64{10, 11, 12, 12, 13, 14}, []float64{12, 13, 15, 16, 16, 17}, 0.03539141642975822},
+		{"normal approximation above 50 samples", seq(0, 60), seq(30, 90), 1.4065275903970681e-12},
+		{"all identical", []float64{1, 1, 1}, []float64{1, 1, 1}, 1},
+		{"empty", nil, []float64{1, 2}, 1},
+	}
+	for _, tt := range tests {
+		if got := mannWhitneyU(tt.x, tt.y); !approxEqual(got, tt.want) {
+			t.Errorf("%s: mannWhitneyU = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExactMannWhitneyP(t *testing.T) {
+	tests := []struct {
+		u    float64
+		m, n int
+		want float64
+	}{
+		{0, 3, 3, 0.1},
+		{9, 3, 3, 0.1}, // U is folded onto the smaller tail
+		{3, 3, 3, 0.7},
+		{4.5, 3, 3, 1},
+		{0, 5, 5, 2.0 / 252},
+		{0, 1, 1, 1},
+	}
+	for _, tt := range tests {
+		if got := exactMannWhitneyP(tt.u, tt.m, tt.n); !approxEqual(got, tt.want) {
+			t.Errorf("exactMannWhitneyP(%v, %d, %d) = %v; want %v", tt.u, tt.m, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package dashboard
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BisectOptions names a test that passes at Good and fails at Bad.
+type BisectOptions struct {
+	Package string `json:"package"`
+	Test    string `json:"test"`
+	Good    string `json:"good"`
+	Bad     string `json:"bad"`
+	Tags    string `json:"tags,omitempty"`
+	Timeout string `json:"timeout,omitempty"` // per step, passed to go test; default 5m
+}
+
+// BisectStep is the outcome of running the test at one commit.
+type BisectStep struct {
+	Commit   string        `json:"commit"`
+	Subject  string        `json:"subject"`
+	Result   string        `json:"result"`           // "good", "bad" or "skip" (the test did not run)
+	Output   string        `json:"output,omitempty"` // the tail of the output, unless the test passed
+	Duration time.Duration `json:"duration"`
+}
+
+// BisectCommit is the commit a bisect found, with its diff stats.
+type BisectCommit struct {
+	Commit       string `json:"commit"`
+	Subject      string `json:"subject"`
+	Message      string `json:"message"`
+	Author       string `json:"author"`
+	Date         string `json:"date"`
+	FilesChanged int    `json:"files_changed"`
+	Insertions   int    `json:"insertions"`
+	Deletions    int    `json:"deletions"`
+	Stat         string `json:"stat"` // git show --stat
+}
+
+// Bisect is a git bisect of one test between a good and a bad commit. It is
+// pushed over the WebSocket as a "bisect" event after every step.
+type Bisect struct {
+	ID         string        `json:"id"`
+	Options    BisectOptions `json:"options"`
+	GoodCommit string        `json:"good_commit"`
+	BadCommit  string        `json:"bad_commit"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at,omitempty"`
+	Status     string        `json:"status"`    // "running", "finished" or "failed"
+	Remaining  int           `json:"remaining"` // revisions left to test, as git estimates it
+	Steps      []BisectStep  `json:"steps"`
+	FirstBad   *BisectCommit `json:"first_bad,omitempty"`
+	// Candidates are the commits the first bad one could be when skipped
+	// commits leave it ambiguous.
+	Candidates []string `json:"candidates,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+var (
+	bisectRemainingRe = regexp.MustCompile(`Bisecting: (\d+) revisions? left`)
+	bisectFirstBadRe  = regexp.MustCompile(`(?m)^([0-9a-f]{40}) is the first bad commit`)
+	bisectSkippedRe   = regexp.MustCompile(`(?m)^([0-9a-f]{40})$`)
+	shortstatRe       = regexp.MustCompile(`(\d+) (file|insertion|deletion)`)
+)
+
+// bisects holds the most recent bisect of each project.
+type bisects struct {
+	mu     sync.Mutex
+	latest *Bisect
+}
+
+// StartBisect checks the options and starts bisecting in the background. The
+// bisect waits for a run in progress to finish, and runs block until it is done.
+func (td *TestDashboard) StartBisect(opts BisectOptions) (*Bisect, error) {
+	if opts.Package == "" || opts.Test == "" {
+		return nil, fmt.Errorf("package and test are required")
+	}
+	packages, err := td.resolvePackages(opts.Package)
+	if err != nil {
+		return nil, err
+	}
+	opts.Package = packages[0]
+	if opts.Bad == "" {
+		opts.Bad = "HEAD"
+	}
+	runOpts := RunOptions{Tags: opts.Tags, Timeout: opts.Timeout}
+	if err := runOpts.Validate(); err != nil {
+		return nil, err
+	}
+	good, err := resolveCommit(td.ProjectPath, opts.Good)
+	if err != nil {
+		return nil, fmt.Errorf("good: %v", err)
+	}
+	bad, err := resolveCommit(td.ProjectPath, opts.Bad)
+	if err != nil {
+		return nil, fmt.Errorf("bad: %v", err)
+	}
+	if good == bad {
+		return nil, fmt.Errorf("good and bad are the same commit")
+	}
+	if _, err := git(td.ProjectPath, nil, "merge-base", "--is-ancestor", good, bad); err != nil {
+		return nil, fmt.Errorf("%s is not an ancestor of %s", opts.Good, opts.Bad)
+	}
+
+	td.bisects.mu.Lock()
+	defer td.bisects.mu.Unlock()
+	if b := td.bisects.latest; b != nil && b.Status == "running" {
+		return nil, fmt.Errorf("a bisect is already running")
+	}
+	startedAt := time.Now()
+	b := &Bisect{
+		ID:         newRunID(startedAt),
+		Options:    opts,
+		GoodCommit: good,
+		BadCommit:  bad,
+		StartedAt:  startedAt,
+		Status:     "running",
+		Steps:      []BisectStep{},
+	}
+	td.bisects.latest = b
+	go td.runBisect(b, runOpts)
+	return b.snapshot(), nil
+}
+
+// LatestBisect returns the most recent bisect, or nil.
+func (td *TestDashboard) LatestBisect() *Bisect {
+	td.bisects.mu.Lock()
+	defer td.bisects.mu.Unlock()
+	if td.bisects.latest == nil {
+		return nil
+	}
+	return td.bisects.latest.snapshot()
+}
+
+// snapshot copies b so it can be encoded while the bisect goes on. Callers
+// must hold td.bisects.mu.
+func (b *Bisect) snapshot() *Bisect {
+	c := *b
+	c.Steps = append([]BisectStep{}, b.Steps...)
+	c.Candidates = append([]string(nil), b.Candidates...)
+	return &c
+}
+
+// update changes the bisect under the lock and pushes it to the clients.
+func (td *TestDashboard) updateBisect(b *Bisect, change func()) {
+	td.bisects.mu.Lock()
+	change()
+	snapshot := b.snapshot()
+	td.bisects.mu.Unlock()
+	td.Notify("bisect", snapshot)
+}
+
+// runBisect drives git bisect in a worktree of the bad commit: it checks that
+// the test passes at the good commit and fails at the bad one, then runs it at
+// every commit git picks until the first bad commit is found.
+func (td *TestDashboard) runBisect(b *Bisect, opts RunOptions) {
+	td.lockRun("bisect")
+	defer td.runMu.Unlock()
+
+	fail := func(format string, args ...interface{}) {
+		msg := fmt.Sprintf(format, args...)
+		log.Printf("Bisect %s failed: %s", b.ID, msg)
+		td.updateBisect(b, func() {
+			b.Status = "failed"
+			b.Error = msg
+			b.FinishedAt = time.Now()
+		})
+	}
+
+	rc, leave, err := td.worktreeCtx(b.BadCommit)
+	if err != nil {
+		fail("could not create a worktree: %v", err)
+		return
+	}
+	defer leave()
+	dir := rc.root
+	log.Printf("Bisecting %s %s between %.12s and %.12s in %s", b.Options.Package, b.Options.Test, b.GoodCommit, b.BadCommit, dir)
+
+	// The endpoints are tested first: bisecting is pointless if the test
+	// already fails at good or still passes at bad.
+	for _, end := range []struct{ commit, want string }{{b.BadCommit, "bad"}, {b.GoodCommit, "good"}} {
+		step, err := td.bisectStep(rc, end.commit, b.Options, opts)
+		if err != nil {
+			fail("%v", err)
+			return
+		}
+		td.updateBisect(b, func() { b.Steps = append(b.Steps, step) })
+		if step.Result != end.want {
+			fail("expected the test to be %s at %.12s, but it was %s", end.want, end.commit, step.Result)
+			return
+		}
+	}
+
+	out, err := bisectCommand(dir, "start", b.BadCommit, b.GoodCommit)
+	if err != nil {
+		fail("%v", err)
+		return
+	}
+	defer bisectCommand(dir, "reset")
+
+	for {
+		if m := bisectFirstBadRe.FindStringSubmatch(out); m != nil {
+			commit, err := describeCommit(dir, m[1])
+			if err != nil {
+				fail("%v", err)
+				return
+			}
+			td.updateBisect(b, func() {
+				b.FirstBad = commit
+				b.Remaining = 0
+				b.Status = "finished"
+				b.FinishedAt = time.Now()
+			})
+			log.Printf("Bisect %s: first bad commit is %.12s %s", b.ID, commit.Commit, commit.Subject)
+			return
+		}
+		if strings.Contains(out, "only 'skip'ped commits left to test") {
+			candidates := bisectSkippedRe.FindAllString(out, -1)
+			td.updateBisect(b, func() {
+				b.Candidates = candidates
+				b.Remaining = 0
+				b.Status = "finished"
+				b.FinishedAt = time.Now()
+			})
+			log.Printf("Bisect %s: the first bad commit is one of %d skipped commits", b.ID, len(candidates))
+			return
+		}
+		if m := bisectRemainingRe.FindStringSubmatch(out); m != nil {
+			remaining, _ := strconv.Atoi(m[1])
+			td.updateBisect(b, func() { b.Remaining = remaining })
+		}
+
+		head, err := git(dir, nil, "rev-parse", "HEAD")
+		if err != nil {
+			fail("%v", err)
+			return
+		}
+		step, err := td.bisectStep(rc, head, b.Options, opts)
+		if err != nil {
+			fail("%v", err)
+			return
+		}
+		td.updateBisect(b, func() { b.Steps = append(b.Steps, step) })
+		if out, err = bisectCommand(dir, step.Result); err != nil {
+			fail("%v", err)
+			return
+		}
+	}
+}
+
+// bisectCommand runs a git bisect subcommand and returns its output. Running
+// out of commits to test because of skips is not an error.
+func bisectCommand(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"bisect"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil && !strings.Contains(string(out), "only 'skip'ped commits left to test") {
+		return "", fmt.Errorf("git bisect %s: %v\n%s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+// bisectStep checks commit out in the worktree of rc and runs the test there.
+// A test that doesn't run, because it doesn't exist yet or the package doesn't
+// build, makes the commit a "skip".
+func (td *TestDashboard) bisectStep(rc runCtx, commit string, b BisectOptions, opts RunOptions) (BisectStep, error) {
+	step := BisectStep{Commit: commit}
+	if _, err := git(rc.root, nil, "checkout", "--quiet", "--detach", commit); err != nil {
+		return step, err
+	}
+	step.Subject, _ = git(rc.root, nil, "log", "-1", "--format=%s", commit)
+	rc.modules = discoverModules(rc.root)
+
+	args := []string{"test", "-json", "-count=1", "-timeout=" + opts.timeout().String(), "-run", testRunPattern(b.Test)}
+	if opts.Tags != "" {
+		args = append(args, "-tags="+opts.Tags)
+	}
+	start := time.Now()
+	stream, _ := rc.goCommand(b.Package, args...).CombinedOutput()
+	step.Duration = time.Since(start)
+
+	step.Result = "skip"
+	scanTestEvents(stream, func(ev testEvent, raw []byte) {
+		if ev.Test != b.Test {
+			return
+		}
+		switch ev.Action {
+		case "pass":
+			step.Result = "good"
+		case "fail":
+			step.Result = "bad"
+		}
+	})
+	output, _ := parseTestJSON(stream)
+	if step.Result == "skip" && strings.Contains(output, "panic: test timed out") {
+		step.Result = "bad"
+	}
+	if step.Result != "good" {
+		step.Output = lastLines(output, 40)
+	}
+	log.Printf("Bisect step %.12s: %s", commit, step.Result)
+	return step, nil
+}
+
+// describeCommit returns a commit's message, author and diff stats.
+func describeCommit(dir, commit string) (*BisectCommit, error) {
+	out, err := git(dir, nil, "show", "-s", "--format=%H%x00%s%x00%an <%ae>%x00%aI%x00%B", commit)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.SplitN(out, "\x00", 5)
+	if len(fields) < 5 {
+		return nil, fmt.Errorf("unexpected git show output for %s", commit)
+	}
+	c := &BisectCommit{
+		Commit:  fields[0],
+		Subject: fields[1],
+		Author:  fields[2],
+		Date:    fields[3],
+		Message: strings.TrimSpace(fields[4]),
+	}
+	c.Stat, _ = git(dir, nil, "show", "--stat", "--format=", commit)
+	if shortstat, err := git(dir, nil, "show", "--shortstat", "--format=", commit); err == nil {
+		for _, m := range shortstatRe.FindAllStringSubmatch(shortstat, -1) {
+			n, _ := strconv.Atoi(m[1])
+			switch m[2] {
+			case "file":
+				c.FilesChanged = n
+			case "insertion":
+				c.Insertions = n
+			case "deletion":
+				c.Deletions = n
+			}
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,334 @@
+package dashboard
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// uncommittedSHA is what git blame reports for lines changed in the working copy.
+const uncommittedSHA = "0000000000000000000000000000000000000000"
+
+// BlameLine is the commit that last changed a line, according to git blame.
+type BlameLine struct {
+	Line        int       `json:"line"`
+	Commit      string    `json:"commit"`
+	Author      string    `json:"author"`
+	AuthorMail  string    `json:"author_mail,omitempty"`
+	Time        time.Time `json:"time"`
+	Summary     string    `json:"summary"`
+	Uncommitted bool      `json:"uncommitted,omitempty"`
+}
+
+// BlameBucket sums up the statements attributed to an author, a commit or an
+// age range.
+type BlameBucket struct {
+	Name       string     `json:"name"`
+	Detail     string     `json:"detail,omitempty"` // e.g. a commit's subject
+	Time       *time.Time `json:"time,omitempty"`   // of a commit
+	Statements int        `json:"statements"`
+	Uncovered  int        `json:"uncovered"`
+	Coverage   float64    `json:"coverage"`
+}
+
+// BlameReport attributes the statements of the current results to the commits
+// that last changed them. A coverage block is attributed as a whole to its most
+// recently changed line, so new code inside an old function counts as new.
+type BlameReport struct {
+	Revision   string        `json:"revision"` // "" for the working copy
+	Statements int           `json:"statements"`
+	Uncovered  int           `json:"uncovered"`
+	Authors    []BlameBucket `json:"authors"`
+	Ages       []BlameBucket `json:"ages"`
+	Commits    []BlameBucket `json:"commits"` // the commits with the most uncovered statements
+	Errors     []string      `json:"errors,omitempty"`
+}
+
+// blameAges are the age ranges of the report, youngest first.
+var blameAges = []struct {
+	name string
+	max  time.Duration
+}{
+	{"last week", 7 * 24 * time.Hour},
+	{"last month", 30 * 24 * time.Hour},
+	{"last 3 months", 91 * 24 * time.Hour},
+	{"last year", 365 * 24 * time.Hour},
+	{"older", 0},
+}
+
+// blameCache keeps the blame of files that didn't change since they were blamed.
+type blameCache struct {
+	mu    sync.Mutex
+	files map[string]blameEntry // by path and revision
+}
+
+type blameEntry struct {
+	stamp string // the file's modification time and size, for the working copy
+	lines map[int]BlameLine
+}
+
+// blameRevision is the commit a blame of the current results must look at: the
+// tested commit for runs of a ref, the working copy otherwise.
+func (td *TestDashboard) blameRevision() string {
+	if git := td.Snapshot().Git; git != nil && git.Ref != "" {
+		return git.Commit
+	}
+	return ""
+}
+
+// blame returns who last changed every line of a file on disk, at rev or in
+// the working copy.
+func (td *TestDashboard) blame(path, rev string) (map[int]BlameLine, error) {
+	key, stamp := path+"@"+rev, ""
+	if rev == "" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		stamp = fmt.Sprintf("%d.%d", info.ModTime().UnixNano(), info.Size())
+	}
+	td.blames.mu.Lock()
+	entry, ok := td.blames.files[key]
+	td.blames.mu.Unlock()
+	if ok && entry.stamp == stamp {
+		return entry.lines, nil
+	}
+
+	args := []string{"blame", "--porcelain"}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	cmd := exec.Command("git", append(args, "--", filepath.Base(path))...)
+	cmd.Dir = filepath.Dir(path)
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("git blame %s: %s", filepath.Base(path), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("git blame %s: %w", filepath.Base(path), err)
+	}
+	lines := parseBlame(string(out))
+
+	td.blames.mu.Lock()
+	if td.blames.files == nil {
+		td.blames.files = make(map[string]blameEntry)
+	}
+	td.blames.files[key] = blameEntry{stamp: stamp, lines: lines}
+	td.blames.mu.Unlock()
+	return lines, nil
+}
+
+// parseBlame reads git blame --porcelain output. The details of a commit are
+// only given the first time it appears.
+func parseBlame(out string) map[int]BlameLine {
+	lines := make(map[int]BlameLine)
+	commits := make(map[string]*BlameLine)
+	var current *BlameLine
+	var line int
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		if strings.HasPrefix(text, "\t") {
+			if current != nil {
+				l := *current
+				l.Line = line
+				lines[line] = l
+			}
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) >= 3 && len(fields[0]) == 40 {
+			if n, err := strconv.Atoi(fields[2]); err == nil {
+				line = n
+				current = commits[fields[0]]
+				if current == nil {
+					current = &BlameLine{Commit: fields[0], Uncommitted: fields[0] == uncommittedSHA}
+					commits[fields[0]] = current
+				}
+				continue
+			}
+		}
+		if current == nil {
+			continue
+		}
+		key, value, _ := strings.Cut(text, " ")
+		switch key {
+		case "author":
+			current.Author = value
+		case "author-mail":
+			current.AuthorMail = strings.Trim(value, "<>")
+		case "author-time":
+			if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+				current.Time = time.Unix(sec, 0)
+			}
+		case "summary":
+			current.Summary = value
+		}
+	}
+	return lines
+}
+
+// findCoverageFile returns a file of the current results by its profile name.
+func (td *TestDashboard) findCoverageFile(filename string) *FileCoverage {
+	for _, r := range td.Snapshot().Results {
+		for i := range r.Files {
+			if r.Files[i].Filename == filename {
+				return &r.Files[i]
+			}
+		}
+	}
+	return nil
+}
+
+// BlameCoverageFile returns the blame of the lines of a file of the current
+// results that hold statements, so the explorer can show it next to the ones
+// that aren't covered.
+func (td *TestDashboard) BlameCoverageFile(filename string) ([]BlameLine, error) {
+	file := td.findCoverageFile(filename)
+	if file == nil {
+		return nil, fmt.Errorf("file %s is not in the current results", filename)
+	}
+	lines, err := td.blame(td.projectCtx().coverageFilePath(filename), td.blameRevision())
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[int]bool)
+	for _, b := range file.Blocks {
+		for l := b.StartLine; l <= b.EndLine; l++ {
+			wanted[l] = true
+		}
+	}
+	blamed := []BlameLine{}
+	for l := range wanted {
+		if line, ok := lines[l]; ok {
+			blamed = append(blamed, line)
+		}
+	}
+	sort.Slice(blamed, func(i, j int) bool { return blamed[i].Line < blamed[j].Line })
+	return blamed, nil
+}
+
+// BlameReport attributes the covered and uncovered statements of the current
+// results to authors, commit ages and commits.
+func (td *TestDashboard) BlameReport() (*BlameReport, error) {
+	if headCommit(td.ProjectPath) == "" {
+		return nil, fmt.Errorf("the project is not in a git repository")
+	}
+	report := &BlameReport{Revision: td.blameRevision(), Authors: []BlameBucket{}, Commits: []BlameBucket{}}
+	rc := td.projectCtx()
+	now := time.Now()
+
+	authors := make(map[string]*BlameBucket)
+	commits := make(map[string]*BlameBucket)
+	ages := make([]BlameBucket, len(blameAges)+1)
+	ages[0].Name = "uncommitted"
+	for i, age := range blameAges {
+		ages[i+1].Name = age.name
+	}
+	add := func(b *BlameBucket, statements int, covered bool) {
+		b.Statements += statements
+		if !covered {
+			b.Uncovered += statements
+		}
+	}
+
+	for _, r := range td.Snapshot().Results {
+		for _, f := range r.Files {
+			lines, err := td.blame(rc.coverageFilePath(f.Filename), report.Revision)
+			if err != nil {
+				report.Errors = append(report.Errors, err.Error())
+				continue
+			}
+			for _, b := range f.Blocks {
+				statements := b.Statements
+				if statements == 0 {
+					statements = 1
+				}
+				var newest *BlameLine
+				for l := b.StartLine; l <= b.EndLine; l++ {
+					if line, ok := lines[l]; ok && (newest == nil || line.Uncommitted || (!newest.Uncommitted && line.Time.After(newest.Time))) {
+						line := line
+						newest = &line
+					}
+				}
+				if newest == nil {
+					continue
+				}
+
+				report.Statements += statements
+				if !b.Covered {
+					report.Uncovered += statements
+				}
+				author := newest.Author
+				if newest.Uncommitted {
+					author = "Not committed yet"
+				}
+				if authors[author] == nil {
+					authors[author] = &BlameBucket{Name: author}
+				}
+				add(authors[author], statements, b.Covered)
+				if commits[newest.Commit] == nil {
+					commits[newest.Commit] = &BlameBucket{Name: newest.Commit}
+					if !newest.Uncommitted {
+						commits[newest.Commit].Detail = newest.Summary
+						commits[newest.Commit].Time = &newest.Time
+					}
+				}
+				add(commits[newest.Commit], statements, b.Covered)
+
+				bucket := 0
+				if !newest.Uncommitted {
+					bucket = len(blameAges)
+					for i, age := range blameAges {
+						if age.max > 0 && now.Sub(newest.Time) < age.max {
+							bucket = i + 1
+							break
+						}
+					}
+				}
+				add(&ages[bucket], statements, b.Covered)
+			}
+		}
+	}
+
+	for _, b := range authors {
+		report.Authors = append(report.Authors, *b)
+	}
+	sortBuckets(report.Authors)
+	for _, b := range commits {
+		if b.Uncovered > 0 {
+			report.Commits = append(report.Commits, *b)
+		}
+	}
+	sortBuckets(report.Commits)
+	if len(report.Commits) > 20 {
+		report.Commits = report.Commits[:20]
+	}
+	report.Ages = ages
+	for _, list := range [][]BlameBucket{report.Authors, report.Ages, report.Commits} {
+		for i := range list {
+			if list[i].Statements > 0 {
+				list[i].Coverage = 100 * float64(list[i].Statements-list[i].Uncovered) / float64(list[i].Statements)
+			}
+		}
+	}
+	return report, nil
+}
+
+// sortBuckets orders buckets by uncovered statements, most first.
+func sortBuckets(buckets []BlameBucket) {
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Uncovered != buckets[j].Uncovered {
+			return buckets[i].Uncovered > buckets[j].Uncovered
+		}
+		return buckets[i].Name < buckets[j].Name
+	})
+}
@@ -0,0 +1,71 @@
+package dashboard
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseBlame(t *testing.T) {
+	// testdata/blame.txt is git blame --porcelain of a file with lines from
+	// two commits and one changed in the working copy. The second commit
+	// moved the original closing brace of Add to line 11.
+	out, err := os.ReadFile("testdata/blame.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := parseBlame(string(out))
+
+	ada := BlameLine{
+		Commit: "3aa76d128f5dac2950d505727f23f48fce4a3dc5", Author: "Ada Lovelace", AuthorMail: "ada@example.com",
+		Time: time.Unix(1705312800, 0), Summary: "Add calc package",
+	}
+	grace := BlameLine{
+		Commit: "d737f1c1bbb0d554816beb8b82a3c7917da91cb7", Author: "Grace Hopper", AuthorMail: "grace@example.com",
+		Time: time.Unix(1709296200, 0), Summary: "Add Sub and reorder Add",
+	}
+	uncommitted := BlameLine{
+		Commit: uncommittedSHA, Author: "Not Committed Yet", AuthorMail: "not.committed.yet",
+		Time: time.Unix(1792359956, 0), Summary: "Version of calc.go from calc.go", Uncommitted: true,
+	}
+	want := make(map[int]BlameLine)
+	for line, commit := range []BlameLine{ada, ada, ada, ada, grace, grace, grace, grace, grace, uncommitted, ada} {
+		commit.Line = line + 1
+		want[line+1] = commit
+	}
+	if !reflect.DeepEqual(got, want) {
+		for line := 1; line <= len(want) || line <= len(got); line++ {
+			if !reflect.DeepEqual(got[line], want[line]) {
+				t.Errorf("line %d:\n got %+v\nwant %+v", line, got[line], want[line])
+			}
+		}
+	}
+}
+
+func TestParseBlameEdgeCases(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want map[int]BlameLine
+	}{
+		{"empty", "", map[int]BlameLine{}},
+		{"content before any header", "\tpackage calc\n", map[int]BlameLine{}},
+		{
+			// Content lines that look like headers are still content.
+			"header-like content",
+			"3aa76d128f5dac2950d505727f23f48fce4a3dc5 1 1 1\nauthor Ada\nsummary First\n\td737f1c1bbb0d554816beb8b82a3c7917da91cb7 2 2\n",
+			map[int]BlameLine{1: {Line: 1, Commit: "3aa76d128f5dac2950d505727f23f48fce4a3dc5", Author: "Ada", Summary: "First"}},
+		},
+		{
+			"header without content",
+			"3aa76d128f5dac2950d505727f23f48fce4a3dc5 1 1 1\nauthor Ada\n",
+			map[int]BlameLine{},
+		},
+	}
+	for _, tt := range tests {
+		if got := parseBlame(tt.out); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseBlame = %+v; want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package dashboard
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultDurationThreshold is how much slower, in percent, a package or a test
+// must get to count as a duration regression when no threshold is given.
+const DefaultDurationThreshold = 20.0
+
+// minRegressionDuration keeps very fast tests out of the duration
+// regressions: a few milliseconds either way is noise.
+const minRegressionDuration = 10 * time.Millisecond
+
+// maxAlignCells bounds the table used to line up two versions of a file.
+const maxAlignCells = 4_000_000
+
+// TestChange is a test, or a package without failing tests, whose outcome
+// differs between two runs. Test is empty for a package that failed as a whole.
+type TestChange struct {
+	Package    string `json:"package"`
+	Test       string `json:"test,omitempty"`
+	BaseStatus string `json:"base_status,omitempty"` // "" if the test is not in the base run
+	HeadStatus string `json:"head_status,omitempty"` // "" if the test is not in the head run
+}
+
+// PackageCoverageDelta is the coverage of a package in two runs.
+type PackageCoverageDelta struct {
+	Package      string  `json:"package"`
+	Status       string  `json:"status,omitempty"` // "added" or "removed" if only one run has the package
+	BaseCoverage float64 `json:"base_coverage"`
+	HeadCoverage float64 `json:"head_coverage"`
+	Delta        float64 `json:"delta"`
+	BasePassed   bool    `json:"base_passed"`
+	HeadPassed   bool    `json:"head_passed"`
+}
+
+// LostLine is a line that was covered in the base run and is not in the head run.
+type LostLine struct {
+	Line     int    `json:"line"`      // in the head version of the file
+	BaseLine int    `json:"base_line"` // in the base version, if the file changed
+	Content  string `json:"content"`
+}
+
+// FileCoverageChange lists the lines of a file whose coverage changed.
+type FileCoverageChange struct {
+	Package      string     `json:"package"`
+	Filename     string     `json:"filename"`
+	BaseCoverage float64    `json:"base_coverage"`
+	HeadCoverage float64    `json:"head_coverage"`
+	Changed      bool       `json:"changed"` // the file's content differs
+	LostLines    []LostLine `json:"lost_lines"`
+	GainedLines  int        `json:"gained_lines"`
+}
+
+// DurationRegression is a package or a test that got slower than the
+// comparison's threshold allows. Test is empty for a whole package.
+type DurationRegression struct {
+	Package  string        `json:"package"`
+	Test     string        `json:"test,omitempty"`
+	Base     time.Duration `json:"base"`
+	Head     time.Duration `json:"head"`
+	Increase float64       `json:"increase"` // percent
+}
+
+// RunComparison is what changed between two stored runs.
+type RunComparison struct {
+	Base                RunSummary             `json:"base"`
+	Head                RunSummary             `json:"head"`
+	DurationThreshold   float64                `json:"duration_threshold"` // percent
+	CoverageDelta       float64                `json:"coverage_delta"`
+	NewlyFailing        []TestChange           `json:"newly_failing"`
+	NewlyPassing        []TestChange           `json:"newly_passing"`
+	Added               []TestChange           `json:"added"`
+	Removed             []TestChange           `json:"removed"`
+	Packages            []PackageCoverageDelta `json:"packages"`
+	Files               []FileCoverageChange   `json:"files"`
+	DurationRegressions []DurationRegression   `json:"duration_regressions"`
+}
+
+// CompareRuns compares two stored runs. threshold is the duration increase, in
+// percent, above which a package or a test counts as a regression.
+func (h *History) CompareRuns(baseID, headID string, threshold float64) (*RunComparison, error) {
+	base, err := h.Load(baseID)
+	if err != nil {
+		return nil, fmt.Errorf("base run: %w", err)
+	}
+	head, err := h.Load(headID)
+	if err != nil {
+		return nil, fmt.Errorf("head run: %w", err)
+	}
+	if threshold <= 0 {
+		threshold = DefaultDurationThreshold
+	}
+
+	c := &RunComparison{
+		Base:                base.Summary(),
+		Head:                head.Summary(),
+		DurationThreshold:   threshold,
+		CoverageDelta:       head.Data.OverallCoverage - base.Data.OverallCoverage,
+		NewlyFailing:        []TestChange{},
+		NewlyPassing:        []TestChange{},
+		Added:               []TestChange{},
+		Removed:             []TestChange{},
+		Packages:            []PackageCoverageDelta{},
+		Files:               []FileCoverageChange{},
+		DurationRegressions: []DurationRegression{},
+	}
+
+	baseResults := make(map[string]*TestResult)
+	for i := range base.Data.Results {
+		baseResults[base.Data.Results[i].Package] = &base.Data.Results[i]
+	}
+	seen := make(map[string]bool)
+	for i := range head.Data.Results {
+		hr := &head.Data.Results[i]
+		seen[hr.Package] = true
+		br := baseResults[hr.Package]
+		if br == nil {
+			c.Packages = append(c.Packages, PackageCoverageDelta{
+				Package:      hr.Package,
+				Status:       "added",
+				HeadCoverage: hr.Coverage,
+				Delta:        hr.Coverage,
+				HeadPassed:   hr.Passed,
+			})
+			for _, t := range hr.Tests {
+				c.Added = append(c.Added, TestChange{Package: hr.Package, Test: t.Name, HeadStatus: t.Status})
+			}
+			continue
+		}
+		c.Packages = append(c.Packages, PackageCoverageDelta{
+			Package:      hr.Package,
+			BaseCoverage: br.Coverage,
+			HeadCoverage: hr.Coverage,
+			Delta:        hr.Coverage - br.Coverage,
+			BasePassed:   br.Passed,
+			HeadPassed:   hr.Passed,
+		})
+		c.compareTests(br, hr)
+		c.compareFiles(br, hr)
+		c.compareDurations(br, hr)
+	}
+	for _, br := range base.Data.Results {
+		if seen[br.Package] {
+			continue
+		}
+		c.Packages = append(c.Packages, PackageCoverageDelta{
+			Package:      br.Package,
+			Status:       "removed",
+			BaseCoverage: br.Coverage,
+			Delta:        -br.Coverage,
+			BasePassed:   br.Passed,
+		})
+		for _, t := range br.Tests {
+			c.Removed = append(c.Removed, TestChange{Package: br.Package, Test: t.Name, BaseStatus: t.Status})
+		}
+	}
+
+	sort.SliceStable(c.Packages, func(i, j int) bool { return c.Packages[i].Delta < c.Packages[j].Delta })
+	sort.SliceStable(c.Files, func(i, j int) bool { return len(c.Files[i].LostLines) > len(c.Files[j].LostLines) })
+	sort.SliceStable(c.DurationRegressions, func(i, j int) bool {
+		return c.DurationRegressions[i].Increase > c.DurationRegressions[j].Increase
+	})
+	return c, nil
+}
+
+// compareTests sorts the tests of a package that is in both runs into newly
+// failing, newly passing, added and removed. Parents are left out when they
+// only changed because of a subtest.
+func (c *RunComparison) compareTests(br, hr *TestResult) {
+	baseStatus := make(map[string]string)
+	for _, t := range br.Tests {
+		baseStatus[t.Name] = t.Status
+	}
+	var failing, passing []string
+	headStatus := make(map[string]string)
+	for _, t := range hr.Tests {
+		headStatus[t.Name] = t.Status
+		before, ok := baseStatus[t.Name]
+		switch {
+		case !ok:
+			c.Added = append(c.Added, TestChange{Package: hr.Package, Test: t.Name, HeadStatus: t.Status})
+		case t.Status == "fail" && before != "fail":
+			failing = append(failing, t.Name)
+		case t.Status == "pass" && before == "fail":
+			passing = append(passing, t.Name)
+		}
+	}
+	for _, t := range br.Tests {
+		if _, ok := headStatus[t.Name]; !ok {
+			c.Removed = append(c.Removed, TestChange{Package: br.Package, Test: t.Name, BaseStatus: t.Status})
+		}
+	}
+
+	for _, name := range leafTests(failing) {
+		c.NewlyFailing = append(c.NewlyFailing, TestChange{Package: hr.Package, Test: name, BaseStatus: baseStatus[name], HeadStatus: "fail"})
+	}
+	for _, name := range leafTests(passing) {
+		c.NewlyPassing = append(c.NewlyPassing, TestChange{Package: hr.Package, Test: name, BaseStatus: "fail", HeadStatus: "pass"})
+	}
+
+	// A package that fails without a failing test, e.g. because it no longer
+	// builds, is reported as a whole.
+	switch {
+	case br.Passed && !hr.Passed && len(failedTests(hr.Tests)) == 0:
+		c.NewlyFailing = append(c.NewlyFailing, TestChange{Package: hr.Package, BaseStatus: "pass", HeadStatus: "fail"})
+	case !br.Passed && hr.Passed && len(failedTests(br.Tests)) == 0 && !br.NoTests:
+		c.NewlyPassing = append(c.NewlyPassing, TestChange{Package: hr.Package, BaseStatus: "fail", HeadStatus: "pass"})
+	}
+}
+
+// compareFiles finds the lines of every file in both runs that lost or gained
+// coverage. Lines of a file that changed in between are matched by content.
+func (c *RunComparison) compareFiles(br, hr *TestResult) {
+	baseFiles := make(map[string]*FileCoverage)
+	for i := range br.Files {
+		baseFiles[br.Files[i].Filename] = &br.Files[i]
+	}
+	for i := range hr.Files {
+		hf := &hr.Files[i]
+		bf := baseFiles[hf.Filename]
+		if bf == nil {
+			continue
+		}
+		baseLines, headLines := lineCoverage(bf.Blocks), lineCoverage(hf.Blocks)
+		headContent := strings.Split(hf.Content, "\n")
+		change := FileCoverageChange{
+			Package:      hr.Package,
+			Filename:     hf.Filename,
+			BaseCoverage: bf.Coverage,
+			HeadCoverage: hf.Coverage,
+			Changed:      bf.Content != hf.Content,
+			LostLines:    []LostLine{},
+		}
+		var toBase map[int]int
+		if change.Changed {
+			toBase = alignLines(strings.Split(bf.Content, "\n"), headContent)
+		}
+
+		lines := make([]int, 0, len(headLines))
+		for line := range headLines {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+		for _, line := range lines {
+			baseLine := line
+			if toBase != nil {
+				var ok bool
+				if baseLine, ok = toBase[line]; !ok {
+					continue
+				}
+			}
+			wasCovered, ok := baseLines[baseLine]
+			if !ok {
+				continue
+			}
+			covered := headLines[line]
+			switch {
+			case wasCovered && !covered:
+				lost := LostLine{Line: line}
+				if change.Changed {
+					lost.BaseLine = baseLine
+				}
+				if line-1 < len(headContent) {
+					lost.Content = headContent[line-1]
+				}
+				change.LostLines = append(change.LostLines, lost)
+			case !wasCovered && covered:
+				change.GainedLines++
+			}
+		}
+		if len(change.LostLines) > 0 || change.GainedLines > 0 {
+			c.Files = append(c.Files, change)
+		}
+	}
+}
+
+// lineCoverage tells, for every line holding statements, whether any of them ran.
+func lineCoverage(blocks []CoverageBlock) map[int]bool {
+	lines := make(map[int]bool)
+	for _, b := range blocks {
+		for l := b.StartLine; l <= b.EndLine; l++ {
+			lines[l] = lines[l] || b.Covered
+		}
+	}
+	return lines
+}
+
+// alignLines maps the 1-based line numbers of head to those of base through
+// their longest common subsequence. Files too large for that are matched on
+// the lines that appear exactly once in both.
+func alignLines(base, head []string) map[int]int {
+	toBase := make(map[int]int)
+	n, m := len(base), len(head)
+	if (n+1)*(m+1) > maxAlignCells {
+		count := make(map[string]int)
+		at := make(map[string]int)
+		for i, line := range base {
+			count[line]++
+			at[line] = i + 1
+		}
+		headCount := make(map[string]int)
+		for _, line := range head {
+			headCount[line]++
+		}
+		for j, line := range head {
+			if count[line] == 1 && headCount[line] == 1 {
+				toBase[j+1] = at[line]
+			}
+		}
+		return toBase
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of base[i:]
+	// and head[j:].
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if base[i] == head[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case base[i] == head[j]:
+			toBase[j+1] = i + 1
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return toBase
+}
+
+// compareDurations reports the package, and the tests in it, when they got
+// slower by more than the threshold.
+func (c *RunComparison) compareDurations(br, hr *TestResult) {
+	regressed := func(before, after time.Duration) (float64, bool) {
+		if before < minRegressionDuration {
+			return 0, false
+		}
+		increase := float64(after-before) / float64(before) * 100
+		return increase, increase > c.DurationThreshold
+	}
+	if increase, ok := regressed(br.Duration, hr.Duration); ok && br.Passed && hr.Passed {
+		c.DurationRegressions = append(c.DurationRegressions, DurationRegression{
+			Package: hr.Package, Base: br.Duration, Head: hr.Duration, Increase: increase,
+		})
+	}
+	baseDurations := make(map[string]time.Duration)
+	for _, t := range br.Tests {
+		if t.Status == "pass" {
+			baseDurations[t.Name] = t.Duration
+		}
+	}
+	for _, t := range hr.Tests {
+		before, ok := baseDurations[t.Name]
+		if !ok || t.Status != "pass" {
+			continue
+		}
+		if increase, ok := regressed(before, t.Duration); ok {
+			c.DurationRegressions = append(c.DurationRegressions, DurationRegression{
+				Package: hr.Package, Test: t.Name, Base: before, Head: t.Duration, Increase: increase,
+			})
+		}
+	}
+}
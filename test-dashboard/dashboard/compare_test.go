@@ -0,0 +1,200 @@
+package dashboard
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readCompareFixture(t *testing.T, name string) string {
+	t.Helper()
+	content, err := os.ReadFile("testdata/compare/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestAlignLines(t *testing.T) {
+	// calc.go.head adds an import, a variable and a function to calc.go.base
+	// and changes one line of Div.
+	base := strings.Split(readCompareFixture(t, "calc.go.base"), "\n")
+	head := strings.Split(readCompareFixture(t, "calc.go.head"), "\n")
+
+	want := map[int]int{
+		// The package clause, and the blank line after the imports.
+		1: 1, 2: 2, 7: 4,
+		// Add.
+		10: 5, 11: 6, 12: 7, 13: 8, 14: 9,
+		// Div, without its changed return.
+		20: 10, 21: 11, 22: 12, 24: 14, 25: 15, 26: 16,
+		// The empty string after the final newline.
+		27: 17,
+	}
+	if got := alignLines(base, head); !reflect.DeepEqual(got, want) {
+		t.Errorf("alignLines = %v; want %v", got, want)
+	}
+}
+
+func TestAlignLinesEdgeCases(t *testing.T) {
+	tests := []struct {
+		name       string
+		base, head []string
+		want       map[int]int
+	}{
+		{"identical", []string{"a", "b"}, []string{"a", "b"}, map[int]int{1: 1, 2: 2}},
+		{"empty base", nil, []string{"a"}, map[int]int{}},
+		{"empty head", []string{"a"}, nil, map[int]int{}},
+		{"nothing in common", []string{"a"}, []string{"b"}, map[int]int{}},
+		{"moved line keeps the longer run", []string{"x", "a", "b", "c"}, []string{"a", "b", "c", "x"}, map[int]int{1: 2, 2: 3, 3: 4}},
+		{"repeated lines", []string{"}", "a", "}"}, []string{"}", "}", "a", "}"}, map[int]int{1: 1, 3: 2, 4: 3}},
+	}
+	for _, tt := range tests {
+		if got := alignLines(tt.base, tt.head); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: alignLines = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAlignLinesLargeFiles(t *testing.T) {
+	// Too large for the table, so only lines that are unique in both files
+	// are matched.
+	var base, head []string
+	for i := 1; i <= 2100; i++ {
+		base = append(base, fmt.Sprintf("line %d", i), "}")
+	}
+	head = append([]string{"inserted"}, base...)
+	head[1] = "changed"
+
+	got := alignLines(base, head)
+	if _, ok := got[1]; ok {
+		t.Error("inserted line is matched")
+	}
+	if _, ok := got[2]; ok {
+		t.Error("changed line is matched")
+	}
+	if _, ok := got[3]; ok {
+		t.Error("repeated line is matched")
+	}
+	if got[4] != 3 || got[len(head)-1] != len(base)-1 {
+		t.Errorf("unique lines map to %d and %d; want 3 and %d", got[4], got[len(head)-1], len(base)-1)
+	}
+	if len(got) != 2099 {
+		t.Errorf("%d lines matched; want 2099", len(got))
+	}
+}
+
+func TestCompareTests(t *testing.T) {
+	// testdata/compare/{base,head}.json are `go test -json` runs of the same
+	// package before and after its tests changed.
+	_, baseTests := parseTestJSON([]byte(readCompareFixture(t, "base.json")))
+	_, headTests := parseTestJSON([]byte(readCompareFixture(t, "head.json")))
+	br := &TestResult{Package: "./cmpfix", Tests: baseTests}
+	hr := &TestResult{Package: "./cmpfix", Tests: headTests}
+
+	c := &RunComparison{}
+	c.compareTests(br, hr)
+
+	// TestRound fails only because of TestRound/down, and TestFormat passes
+	// only because of TestFormat/short, so the parents are left out.
+	want := &RunComparison{
+		NewlyFailing: []TestChange{
+			{Package: "./cmpfix", Test: "TestParse", BaseStatus: "pass", HeadStatus: "fail"},
+			{Package: "./cmpfix", Test: "TestRound/down", BaseStatus: "pass", HeadStatus: "fail"},
+		},
+		NewlyPassing: []TestChange{
+			{Package: "./cmpfix", Test: "TestFormat/short", BaseStatus: "fail", HeadStatus: "pass"},
+		},
+		Added:   []TestChange{{Package: "./cmpfix", Test: "TestSkipped", HeadStatus: "skip"}},
+		Removed: []TestChange{{Package: "./cmpfix", Test: "TestLegacy", BaseStatus: "pass"}},
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("compareTests:\n got %+v\nwant %+v", c, want)
+	}
+}
+
+func TestCompareTestsWholePackage(t *testing.T) {
+	passed := []TestCase{{Name: "TestA", Status: "pass"}}
+	tests := []struct {
+		name       string
+		base, head TestResult
+		failing    []TestChange
+		passing    []TestChange
+	}{
+		{
+			name:    "stops building",
+			base:    TestResult{Package: "./p", Passed: true, Tests: passed},
+			head:    TestResult{Package: "./p"},
+			failing: []TestChange{{Package: "./p", BaseStatus: "pass", HeadStatus: "fail"}},
+		},
+		{
+			name:    "builds again",
+			base:    TestResult{Package: "./p"},
+			head:    TestResult{Package: "./p", Passed: true, Tests: passed},
+			passing: []TestChange{{Package: "./p", BaseStatus: "fail", HeadStatus: "pass"}},
+		},
+		{
+			name: "had no tests",
+			base: TestResult{Package: "./p", NoTests: true},
+			head: TestResult{Package: "./p", Passed: true},
+		},
+		{
+			name: "failing test already reported",
+			base: TestResult{Package: "./p", Passed: true, Tests: passed},
+			head: TestResult{Package: "./p", Tests: []TestCase{{Name: "TestA", Status: "fail"}}},
+			failing: []TestChange{
+				{Package: "./p", Test: "TestA", BaseStatus: "pass", HeadStatus: "fail"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		c := &RunComparison{}
+		c.compareTests(&tt.base, &tt.head)
+		if !reflect.DeepEqual(c.NewlyFailing, tt.failing) || !reflect.DeepEqual(c.NewlyPassing, tt.passing) {
+			t.Errorf("%s: newly failing %+v, newly passing %+v; want %+v, %+v",
+				tt.name, c.NewlyFailing, c.NewlyPassing, tt.failing, tt.passing)
+		}
+	}
+}
+
+func TestCompareFilesChangedContent(t *testing.T) {
+	base := readCompareFixture(t, "calc.go.base")
+	head := readCompareFixture(t, "calc.go.head")
+	br := &TestResult{Package: "./calc", Files: []FileCoverage{{
+		Filename: "calc.go", Content: base, Coverage: 100,
+		Blocks: []CoverageBlock{
+			{StartLine: 6, EndLine: 8, Covered: true},
+			{StartLine: 11, EndLine: 12, Covered: true},
+			{StartLine: 12, EndLine: 14, Covered: true},
+			{StartLine: 15, EndLine: 15, Covered: true},
+		},
+	}}}
+	hr := &TestResult{Package: "./calc", Files: []FileCoverage{{
+		Filename: "calc.go", Content: head, Coverage: 50,
+		Blocks: []CoverageBlock{
+			{StartLine: 11, EndLine: 13, Covered: true},
+			{StartLine: 16, EndLine: 18, Covered: true}, // Sqrt is new
+			{StartLine: 21, EndLine: 22, Covered: false},
+			{StartLine: 22, EndLine: 24, Covered: false},
+			{StartLine: 25, EndLine: 25, Covered: false},
+		},
+	}}}
+
+	c := &RunComparison{}
+	c.compareFiles(br, hr)
+	// Line 23 is new, so it can't have lost coverage.
+	want := []FileCoverageChange{{
+		Package: "./calc", Filename: "calc.go", BaseCoverage: 100, HeadCoverage: 50, Changed: true,
+		LostLines: []LostLine{
+			{Line: 21, BaseLine: 11, Content: "func Div(a, b int) (int, error) {"},
+			{Line: 22, BaseLine: 12, Content: "\tif b == 0 {"},
+			{Line: 24, BaseLine: 14, Content: "\t}"},
+			{Line: 25, BaseLine: 15, Content: "\treturn a / b, nil"},
+		},
+	}}
+	if !reflect.DeepEqual(c.Files, want) {
+		t.Errorf("compareFiles:\n got %+v\nwant %+v", c.Files, want)
+	}
+}
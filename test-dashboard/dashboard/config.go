@@ -0,0 +1,41 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ProjectConfig holds the settings of a project that run commands of the
+// user's choosing. They are read from config.json in the project's data
+// directory, so only someone with access to the server can change them; no
+// HTTP request can.
+type ProjectConfig struct {
+	// Integration is a shell command run from the project root after the
+	// unit tests, with GOCOVERDIR set, e.g. a script starting a binary built
+	// with `go build -cover` and exercising it.
+	Integration string `json:"integration,omitempty"`
+}
+
+// ConfigPath is where the configuration of the project at projectPath lives.
+func ConfigPath(projectPath string) string {
+	return filepath.Join(projectDataDir(projectPath), "config.json")
+}
+
+// LoadProjectConfig reads a project's configuration. A missing file is an
+// empty configuration.
+func LoadProjectConfig(projectPath string) (ProjectConfig, error) {
+	var config ProjectConfig
+	data, err := os.ReadFile(ConfigPath(projectPath))
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("%s: %v", ConfigPath(projectPath), err)
+	}
+	return config, nil
+}
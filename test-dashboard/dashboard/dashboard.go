@@ -3,6 +3,7 @@ package dashboard
 import (
 	"bufio"
 	"bytes" // Added this import
+	"context"
 	"fmt"
 	"io/fs"
 	"log"
@@ -12,6 +13,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -20,10 +23,16 @@ import (
 // --- Data Structures (No Changes) ---
 
 type CoverageBlock struct {
-	StartLine int  `json:"start_line"`
-	EndLine   int  `json:"end_line"`
-	Count     int  `json:"count"`
-	Covered   bool `json:"covered"`
+	StartLine  int  `json:"start_line"`
+	EndLine    int  `json:"end_line"`
+	Count      int  `json:"count"`
+	Covered    bool `json:"covered"`
+	StartCol   int  `json:"start_col"`
+	EndCol     int  `json:"end_col"`
+	Statements int  `json:"statements"`
+	// IntegrationCount is how often the integration command ran the block;
+	// Count includes it, so the unit tests ran it Count-IntegrationCount times.
+	IntegrationCount int `json:"integration_count,omitempty"`
 }
 
 type FileCoverage struct {
@@ -42,39 +51,118 @@ type TestResult struct {
 	Files            []FileCoverage `json:"files"`
 	Timestamp        time.Time      `json:"timestamp"`
 	HTMLCoverageFile string         `json:"html_coverage_file,omitempty"`
+	Tests            []TestCase     `json:"tests,omitempty"`
+	Artifacts        []Artifact     `json:"artifacts,omitempty"`
+	Trace            *TraceSummary  `json:"trace,omitempty"`
+	TimedOut         bool           `json:"timed_out,omitempty"`
+	GoroutineDump    *GoroutineDump `json:"goroutine_dump,omitempty"`
+	Failures         []TestFailure  `json:"failures,omitempty"`
+	Races            []DataRace     `json:"races,omitempty"`
+	Module           string         `json:"module,omitempty"`
+	ImportPath       string         `json:"import_path,omitempty"`
+	NoTests          bool           `json:"no_tests,omitempty"` // the package has no _test.go files and was not run
+	Statements       int            `json:"statements"`         // statements in the package's non-test files
+	Covered          int            `json:"covered_statements"` // statements executed by the tests
+	// UnitCoverage is the unit tests' share of Coverage, set once integration
+	// coverage has been merged in.
+	UnitCoverage float64 `json:"unit_coverage,omitempty"`
+}
+
+// Event is a typed message pushed to WebSocket clients next to the regular
+// DashboardData snapshots, e.g. progress of a long-running benchmark or fuzz run.
+// Clients tell them apart by the presence of the "type" field.
+type Event struct {
+	Type    string      `json:"type"`
+	Payload interface{} `json:"payload"`
+}
+
+// RunFailure is pushed over the WebSocket as a "run" event when a run could not
+// start, so clients stop waiting for its results.
+type RunFailure struct {
+	Ref   string `json:"ref,omitempty"`
+	Error string `json:"error"`
 }
 
 type DashboardData struct {
-	Results         []TestResult `json:"results"`
-	OverallCoverage float64      `json:"overall_coverage"`
-	TotalTests      int          `json:"total_tests"`
-	PassedTests     int          `json:"passed_tests"`
-	LastRun         time.Time    `json:"last_run"`
-	ProjectPath     string       `json:"project_path"`
-	ProjectName     string       `json:"project_name"`
+	Results             []TestResult    `json:"results"`
+	OverallCoverage     float64         `json:"overall_coverage"`
+	TotalTests          int             `json:"total_tests"`
+	PassedTests         int             `json:"passed_tests"`
+	LastRun             time.Time       `json:"last_run"`
+	ProjectPath         string          `json:"project_path"`
+	ProjectName         string          `json:"project_name"`
+	ProjectID           string          `json:"project_id"`
+	RunID               string          `json:"run_id,omitempty"`
+	GatePassed          bool            `json:"gate_passed"`
+	QuarantinedFailures int             `json:"quarantined_failures"`
+	Modules             []ModuleSummary `json:"modules,omitempty"`
+	Integration         *IntegrationRun `json:"integration,omitempty"`
+	Git                 *GitInfo        `json:"git,omitempty"` // the code the run tested
 }
 
 // --- Core Dashboard Component (No Changes) ---
 
 type TestDashboard struct {
-	Data        DashboardData
-	Clients     map[*websocket.Conn]bool
+	ID          string        // identifies the project in the registry and in URLs
+	Data        DashboardData // the last broadcast state; read it with Snapshot
+	Clients     map[*websocket.Conn]*wsClient
 	Broadcast   chan DashboardData
+	Events      chan Event
 	Upgrader    websocket.Upgrader
 	ProjectPath string
-	HTMLFiles   map[string]time.Time
+	HTMLFiles   map[string]time.Time // guarded by htmlMu
+	History     *History
+	Quarantine  *Quarantine
+	Benchmarks  *BenchmarkHistory
+	Mutations   *MutationHistory
+	Webhooks    *Webhooks
+
+	htmlMu sync.Mutex
+
+	// mu guards Data and Clients. It is never held while writing to a client;
+	// each client has a writer goroutine of its own.
+	mu sync.Mutex
+
+	// runMu serialises full runs and re-runs so they never interleave their broadcasts.
+	runMu sync.Mutex
+	run   *RunRecord // the run currently being built or last finished; guarded by runMu
+
+	fuzzMu       sync.Mutex
+	fuzzSessions map[string]*fuzzSession
+
+	bisects bisects
+	blames  blameCache
+
+	modMu   sync.Mutex
+	modules []Module // discovered lazily; guarded by modMu
+
+	running atomic.Bool   // a full run or re-run is in progress
+	queued  atomic.Int32  // runs waiting for runMu
+	metrics *runMetrics   // counters exposed on /metrics
+	quit    chan struct{} // closed when the project leaves the registry
 }
 
-func NewTestDashboard() *TestDashboard {
-	currentDir, _ := os.Getwd()
+// NewTestDashboard returns the dashboard of the project at path. Projects are
+// normally created through the Projects registry.
+func NewTestDashboard(path string) *TestDashboard {
 	td := &TestDashboard{
-		Clients:     make(map[*websocket.Conn]bool),
+		ID:          projectID(path),
+		Clients:     make(map[*websocket.Conn]*wsClient),
 		Broadcast:   make(chan DashboardData),
-		ProjectPath: currentDir,
+		Events:      make(chan Event, 64),
+		ProjectPath: path,
 		HTMLFiles:   make(map[string]time.Time),
+		History:     NewHistory(path),
+		Quarantine:  NewQuarantine(path),
+		Benchmarks:  NewBenchmarkHistory(path),
+		Mutations:   NewMutationHistory(path),
+		Webhooks:    NewWebhooks(path),
+		metrics:     newRunMetrics(),
+		quit:        make(chan struct{}),
 		Data: DashboardData{
-			ProjectPath: currentDir,
-			ProjectName: filepath.Base(currentDir),
+			ProjectPath: path,
+			ProjectName: filepath.Base(path),
+			ProjectID:   projectID(path),
 		},
 		Upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
@@ -88,56 +176,190 @@ func NewTestDashboard() *TestDashboard {
 
 // --- Exported Methods (No Changes) ---
 
-func (td *TestDashboard) SetProjectPath(path string) error {
-	info, err := os.Stat(path)
+func (td *TestDashboard) GetProjectInfo() map[string]interface{} {
+	packages, err := td.projectCtx().listPackages("")
 	if err != nil {
-		return fmt.Errorf("path does not exist: %v", err)
-	}
-	if !info.IsDir() {
-		return fmt.Errorf("path is not a directory")
+		log.Printf("Error listing packages: %v", err)
 	}
-	if !td.isGoProject(path) {
-		return fmt.Errorf("directory does not appear to be a Go project (no go.mod or *.go files found)")
+	config, err := LoadProjectConfig(td.ProjectPath)
+	if err != nil {
+		log.Printf("Error reading the project configuration: %v", err)
 	}
-	td.ProjectPath = path
-	td.Data.ProjectPath = path
-	td.Data.ProjectName = filepath.Base(path)
-	log.Printf("Project path changed to: %s", path)
-	td.Broadcast <- td.Data
-	return nil
-}
-
-func (td *TestDashboard) GetProjectInfo() map[string]interface{} {
-	packages, _ := td.findGoPackages(td.ProjectPath)
 	return map[string]interface{}{
 		"project_path":   td.ProjectPath,
-		"project_name":   td.Data.ProjectName,
+		"project_name":   td.Snapshot().ProjectName,
 		"packages_found": len(packages),
 		"packages":       packages,
+		"config_path":    ConfigPath(td.ProjectPath),
+		"integration":    config.Integration,
 	}
 }
 
+// BroadcastUpdates sends every state and event to the connected clients until
+// the project leaves the registry, then disconnects them.
 func (td *TestDashboard) BroadcastUpdates() {
-	for data := range td.Broadcast {
-		td.Data = data
-		for client := range td.Clients {
-			err := client.WriteJSON(data)
-			if err != nil {
-				client.Close()
-				delete(td.Clients, client)
+	for {
+		var message interface{}
+		var data *DashboardData
+		select {
+		case <-td.quit:
+			td.mu.Lock()
+			for conn := range td.Clients {
+				td.dropClient(conn)
+			}
+			td.mu.Unlock()
+			return
+		case d := <-td.Broadcast:
+			data, message = &d, d
+		case event := <-td.Events:
+			message = event
+		}
+
+		td.mu.Lock()
+		if data != nil {
+			td.Data = *data
+		}
+		for conn, client := range td.Clients {
+			select {
+			case client.send <- message:
+			default:
+				log.Printf("Disconnecting a WebSocket client that fell behind")
+				td.dropClient(conn)
 			}
 		}
+		td.mu.Unlock()
 	}
 }
 
-func (td *TestDashboard) RunTests() {
+const (
+	// clientQueue is how many messages a client may fall behind before it is
+	// disconnected; it reconnects and starts over from the current state.
+	clientQueue = 256
+	// clientWriteTimeout bounds a single write to a client.
+	clientWriteTimeout = 10 * time.Second
+)
+
+// wsClient is a connected WebSocket client. Its writer goroutine is the only
+// one writing to conn, so a stalled browser holds up nobody but itself.
+type wsClient struct {
+	conn *websocket.Conn
+	send chan interface{}
+}
+
+func (c *wsClient) writeLoop() {
+	for message := range c.send {
+		c.conn.SetWriteDeadline(time.Now().Add(clientWriteTimeout))
+		if err := c.conn.WriteJSON(message); err != nil {
+			// Closing makes the handler's read fail, which removes the client.
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+// dropClient disconnects a client. Callers must hold td.mu.
+func (td *TestDashboard) dropClient(conn *websocket.Conn) {
+	client, ok := td.Clients[conn]
+	if !ok {
+		return
+	}
+	delete(td.Clients, conn)
+	close(client.send)
+	conn.Close()
+}
+
+// publish hands a new state to BroadcastUpdates. Once the project has left the
+// registry nobody receives it, so a run still in progress finishes without
+// blocking.
+func (td *TestDashboard) publish(data DashboardData) {
+	select {
+	case td.Broadcast <- data:
+	case <-td.quit:
+	}
+}
+
+// Notify queues an event for all connected clients.
+func (td *TestDashboard) Notify(eventType string, payload interface{}) {
+	select {
+	case td.Events <- Event{Type: eventType, Payload: payload}:
+	case <-td.quit:
+	}
+}
+
+// Snapshot returns the last broadcast state. Its slices are shared and must
+// not be modified.
+func (td *TestDashboard) Snapshot() DashboardData {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+	return td.Data
+}
+
+// AddClient subscribes a new WebSocket client to updates, starting with the
+// current state.
+func (td *TestDashboard) AddClient(conn *websocket.Conn) {
+	client := &wsClient{conn: conn, send: make(chan interface{}, clientQueue)}
+	td.mu.Lock()
+	client.send <- td.Data
+	td.Clients[conn] = client
+	td.mu.Unlock()
+	go client.writeLoop()
+}
+
+// RemoveClient unsubscribes and disconnects a WebSocket client.
+func (td *TestDashboard) RemoveClient(conn *websocket.Conn) {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+	td.dropClient(conn)
+}
+
+// RunTests runs every package of the project and records the run. The options
+// apply to each package, e.g. to collect a profile per package.
+func (td *TestDashboard) RunTests(opts RunOptions) {
+	td.lockRun("full")
+	defer td.runMu.Unlock()
+	td.running.Store(true)
+	defer td.running.Store(false)
+
 	log.Printf("Running tests in project: %s", td.ProjectPath)
 
-	packages, err := td.findGoPackages(td.ProjectPath)
+	config, err := LoadProjectConfig(td.ProjectPath)
+	if err != nil {
+		log.Printf("Error reading the project configuration: %v", err)
+	}
+	opts.Integration = config.Integration
+
+	startedAt := time.Now()
+	run := &RunRecord{
+		ID:        newRunID(startedAt),
+		StartedAt: startedAt,
+		Options:   opts,
+	}
+	rc := runCtx{root: td.ProjectPath, modules: td.refreshModules()}
+	if opts.Ref == "" {
+		run.setGit(gitInfo(td.ProjectPath))
+	} else {
+		commit, err := resolveCommit(td.ProjectPath, opts.Ref)
+		if err != nil {
+			td.runFailed(opts.Ref, fmt.Errorf("could not resolve %s: %w", opts.Ref, err))
+			return
+		}
+		var leave func()
+		rc, leave, err = td.worktreeCtx(commit)
+		if err != nil {
+			td.runFailed(opts.Ref, fmt.Errorf("could not check out %s: %w", opts.Ref, err))
+			return
+		}
+		defer leave()
+		log.Printf("Testing %s (%.12s) in worktree %s", opts.Ref, commit, rc.root)
+		run.setGit(refInfo(td.ProjectPath, opts.Ref, commit))
+	}
+
+	packages, err := rc.listPackages(opts.Tags)
 	if err != nil {
-		log.Printf("Error finding packages: %v", err)
+		td.runFailed(opts.Ref, fmt.Errorf("could not list packages: %w", err))
 		return
 	}
+	td.run = run
 
 	// Broadcast a "starting" state to clear the UI and show the total package count.
 	startingData := DashboardData{
@@ -147,51 +369,117 @@ func (td *TestDashboard) RunTests() {
 		PassedTests:     0,
 		LastRun:         time.Now(),
 		ProjectPath:     td.ProjectPath,
-		ProjectName:     td.Data.ProjectName,
+		ProjectName:     td.Snapshot().ProjectName,
+		ProjectID:       td.ID,
+		RunID:           td.run.ID,
+		Git:             td.run.Git(),
+		GatePassed:      true,
 	}
-	td.Broadcast <- startingData
+	td.publish(startingData)
 
 	if len(packages) == 0 {
-		log.Printf("No test packages found in %s", td.ProjectPath)
+		log.Printf("No packages found in %s", td.ProjectPath)
 		return
 	}
 
 	var results []TestResult // This will hold the results as they come in.
+	var intermediateData DashboardData
 
 	for _, pkg := range packages {
-		result := td.runPackageTests(pkg)
+		var result TestResult
+		switch {
+		case pkg.Error != nil:
+			result = rc.loadErrorResult(pkg)
+		case !pkg.HasTests():
+			result = rc.untestedResult(pkg)
+		default:
+			result = td.runPackageTests(rc, pkg.Dir, opts)
+			result.ImportPath = pkg.ImportPath
+		}
+		if result.Statements == 0 {
+			// No cover profile: untested, broken, or failed to build. Count the
+			// statements anyway so the package weighs in at 0% in the totals.
+			result.Statements = countStatements(pkg)
+		}
 		results = append(results, result) // Add the new result to our list
+		td.observePackage(result)
 
-		// Recalculate stats based on the results we have so far
-		var totalCoverage float64
-		var passedTests int
-		for _, r := range results {
-			totalCoverage += r.Coverage
-			if r.Passed {
-				passedTests++
-			}
-		}
+		// Recalculate stats based on the results we have so far and broadcast them.
+		intermediateData = td.summarize(rc, results, len(packages))
+		td.publish(intermediateData) // Send the live update
+	}
 
-		// The denominator is the number of packages completed so far, giving a running average.
-		overallCoverage := 0.0
-		if len(results) > 0 {
-			overallCoverage = totalCoverage / float64(len(results))
-		}
+	if opts.Integration != "" {
+		integration := td.runIntegration(rc, results, opts)
+		log.Printf("Integration command finished: %s", integration.summary())
+		intermediateData = td.summarize(rc, results, len(packages))
+		intermediateData.Integration = integration
+		td.publish(intermediateData)
+	}
+
+	td.storeRun(intermediateData)
+	log.Printf("Test run complete. Found %d packages, %d passed", len(packages), len(results))
+}
+
+// runFailed logs why a run could not start and tells the clients, which were
+// told the run had started. The previous run stays the current one.
+func (td *TestDashboard) runFailed(ref string, err error) {
+	log.Printf("Error running tests: %v", err)
+	td.Notify("run", RunFailure{Ref: ref, Error: err.Error()})
+}
+
+// storeRun records the final state of the current run in the history.
+func (td *TestDashboard) storeRun(data DashboardData) {
+	if td.run == nil {
+		return
+	}
+	td.run.FinishedAt = time.Now()
+	td.run.Data = data
+	if err := td.History.Save(td.run); err != nil {
+		log.Printf("Error storing run %s: %v", td.run.ID, err)
+	}
+	td.metrics.mu.Lock()
+	td.metrics.flakyStale = true
+	td.metrics.mu.Unlock()
+
+	previous, err := td.History.Previous(td.run.ID)
+	if err != nil {
+		log.Printf("Error loading the run before %s: %v", td.run.ID, err)
+	}
+	td.Webhooks.Dispatch(td.webhookPayloads(td.run, previous))
+}
 
-		// Create and broadcast the intermediate data object
-		intermediateData := DashboardData{
-			Results:         results, // Send the list of packages completed so far
-			OverallCoverage: overallCoverage,
-			TotalTests:      len(packages), // Total expected packages
-			PassedTests:     passedTests,
-			LastRun:         time.Now(),
-			ProjectPath:     td.ProjectPath,
-			ProjectName:     td.Data.ProjectName,
+// summarize builds the dashboard state for a set of (possibly partial) results.
+// The overall coverage is over the statements of the packages completed so far.
+func (td *TestDashboard) summarize(rc runCtx, results []TestResult, totalPackages int) DashboardData {
+	var passedTests int
+	for _, r := range results {
+		if r.Passed {
+			passedTests++
 		}
-		td.Broadcast <- intermediateData // Send the live update
 	}
+	overallCoverage := statementCoverage(results)
 
-	log.Printf("Test run complete. Found %d packages, %d passed", len(packages), len(results))
+	gatePassed, quarantined := td.applyQuarantine(results)
+
+	data := DashboardData{
+		Results:             results,
+		OverallCoverage:     overallCoverage,
+		TotalTests:          totalPackages,
+		PassedTests:         passedTests,
+		LastRun:             time.Now(),
+		ProjectPath:         td.ProjectPath,
+		ProjectName:         td.Snapshot().ProjectName,
+		ProjectID:           td.ID,
+		GatePassed:          gatePassed,
+		QuarantinedFailures: quarantined,
+		Modules:             summarizeModules(rc.modules, results),
+	}
+	if td.run != nil {
+		data.RunID = td.run.ID
+		data.Git = td.run.Git()
+	}
+	return data
 }
 
 // ---- NEW HELPER FUNCTION ----
@@ -220,10 +508,13 @@ func getModuleName(projectPath string) (string, error) {
 
 // --- Internal Helper Functions (with one modification) ---
 
-func (td *TestDashboard) isGoProject(path string) bool {
+func isGoProject(path string) bool {
 	if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
 		return true
 	}
+	if _, err := os.Stat(filepath.Join(path, "go.work")); err == nil {
+		return true
+	}
 	hasGoFiles := false
 	filepath.WalkDir(path, func(filePath string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -244,7 +535,7 @@ func (td *TestDashboard) isGoProject(path string) bool {
 	return hasGoFiles
 }
 
-func (td *TestDashboard) runPackageTests(pkg string) TestResult {
+func (td *TestDashboard) runPackageTests(rc runCtx, pkg string, opts RunOptions) TestResult {
 	start := time.Now()
 	coverProfile := fmt.Sprintf("coverage_%s_%d.out",
 		strings.ReplaceAll(strings.ReplaceAll(pkg, "/", "_"), string(filepath.Separator), "_"),
@@ -253,124 +544,138 @@ func (td *TestDashboard) runPackageTests(pkg string) TestResult {
 		strings.ReplaceAll(strings.ReplaceAll(pkg, "/", "_"), string(filepath.Separator), "_"),
 		time.Now().UnixNano())
 
-	defer func() {
-		os.Remove(coverProfile)
-	}()
+	// The profile path is absolute because go test runs from the package's module root.
+	coveragePath := filepath.Join(rc.root, coverProfile)
+	defer os.Remove(coveragePath)
 
-	originalDir, _ := os.Getwd()
-	defer os.Chdir(originalDir)
-	err := os.Chdir(td.ProjectPath)
-	if err != nil {
-		return TestResult{
-			Package:   pkg,
-			Passed:    false,
-			Output:    fmt.Sprintf("Error changing to project directory: %v", err),
-			Duration:  time.Since(start),
-			Timestamp: time.Now(),
-		}
-	}
+	relPkg := relPackagePath(rc.root, pkg)
 
-	relPkg, err := filepath.Rel(td.ProjectPath, pkg)
-	if err != nil {
-		relPkg = pkg
+	// The watchdog replaces go test's own timeout so a hang is caught while the
+	// binary is still alive to dump its goroutines.
+	args := []string{"test", "-json", "-timeout=0", "-coverprofile=" + coveragePath}
+	if opts.Tags != "" {
+		args = append(args, "-tags="+opts.Tags)
 	}
-	relPkg = filepath.ToSlash(relPkg)
-	if relPkg == "." {
-		relPkg = "./"
-	} else if !strings.HasPrefix(relPkg, "./") {
-		relPkg = "./" + relPkg
+	if opts.Race {
+		args = append(args, "-race")
+	}
+	var artifacts []Artifact
+	if td.run != nil {
+		dir := td.artifactDir(td.run.ID)
+		flags, expected, binary := artifactFlags(opts, relPkg, dir)
+		artifacts = expected
+		if binary != "" {
+			defer os.Remove(binary)
+		}
+		if len(flags) > 0 {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				log.Printf("Error creating artifact directory: %v", err)
+			}
+			args = append(args, flags...)
+		}
 	}
 
-	cmd := exec.Command("go", "test", "-coverprofile="+coverProfile, relPkg)
-	cmd.Dir = td.ProjectPath
-	output, testErr := cmd.CombinedOutput()
+	cmd := rc.goCommand(relPkg, args...)
+	stream, hung, testErr := td.runWatched(context.Background(), cmd, relPkg, opts.timeout())
+	output, tests := parseTestJSON(stream)
 
 	result := TestResult{
 		Package:   relPkg,
-		Passed:    testErr == nil,
-		Output:    string(output),
+		Passed:    testErr == nil && !hung,
+		Output:    output,
 		Duration:  time.Since(start),
 		Timestamp: time.Now(),
+		Tests:     tests,
+		TimedOut:  hung,
+		Races:     rc.parseRaces(output, tests),
+	}
+	if m := moduleOf(rc.modules, relPkg); m != nil {
+		result.Module = m.Path
+	}
+	if hung {
+		result.GoroutineDump = rc.parseGoroutineDump(output)
+	} else if !result.Passed {
+		result.Failures = rc.parseFailures(relPkg, output, tests)
+	}
+	for _, artifact := range artifacts {
+		// A package without tests or one that failed to build produces no profile.
+		path, err := td.ArtifactPath(td.run.ID, artifact.Name)
+		if err != nil {
+			continue
+		}
+		result.Artifacts = append(result.Artifacts, artifact)
+		if artifact.Kind == "trace" {
+			if result.Trace, err = SummarizeTrace(rc.root, path); err != nil {
+				log.Printf("Error summarizing trace of %s: %v", relPkg, err)
+			}
+		}
+	}
+
+	if opts.PerTest && td.run != nil && !hung {
+		artifact, err := rc.attributeCoverage(relPkg, tests, opts, td.artifactDir(td.run.ID))
+		if err != nil {
+			log.Printf("Error attributing coverage of %s to tests: %v", relPkg, err)
+		} else if artifact != nil {
+			result.Artifacts = append(result.Artifacts, *artifact)
+		}
 	}
 
-	coveragePath := filepath.Join(td.ProjectPath, coverProfile)
 	if fileExists(coveragePath) {
-		result.Coverage = extractCoverage(string(output))
-		result.Files = td.parseCoverageProfile(coveragePath, pkg) // This function is now fixed
+		result.Coverage = extractCoverage(output)
+		result.Statements, result.Covered = profileStatements(coveragePath)
+		result.Files = rc.parseCoverageProfile(coveragePath, pkg) // This function is now fixed
 		htmlPath := filepath.Join(td.ProjectPath, htmlCoverageFile)
-		if td.generateHTMLCoverage(coveragePath, htmlPath) {
+		if td.generateHTMLCoverage(coveragePath, htmlPath, cmd.Dir) {
 			result.HTMLCoverageFile = htmlCoverageFile
-			td.HTMLFiles[htmlCoverageFile] = time.Now().Add(1 * time.Hour)
+			td.htmlMu.Lock()
+			td.HTMLFiles[htmlCoverageFile] = time.Now().Add(htmlCoverageTTL)
+			td.htmlMu.Unlock()
 		}
 	}
 
 	return result
 }
 
-// ---- MODIFIED FUNCTION ----
-func (td *TestDashboard) parseCoverageProfile(profilePath string, _ string) []FileCoverage {
-	file, err := os.Open(profilePath)
+// relPackagePath turns a package directory into the "./sub/pkg" form go test expects.
+func relPackagePath(root, pkg string) string {
+	relPkg, err := filepath.Rel(root, pkg)
 	if err != nil {
-		log.Printf("Error opening coverage profile %s: %v", profilePath, err)
-		return nil
+		relPkg = pkg
 	}
-	defer file.Close()
-
-	// Get the module name to correctly resolve file paths
-	moduleName, modErr := getModuleName(td.ProjectPath)
+	relPkg = filepath.ToSlash(relPkg)
+	if relPkg == "." {
+		relPkg = "./"
+	} else if !strings.HasPrefix(relPkg, "./") {
+		relPkg = "./" + relPkg
+	}
+	return relPkg
+}
 
-	scanner := bufio.NewScanner(file)
-	if !scanner.Scan() { // Skip the "mode: set" line
+// ---- MODIFIED FUNCTION ----
+func (rc runCtx) parseCoverageProfile(profilePath string, _ string) []FileCoverage {
+	// The modules resolve import paths in the profile to files
+	profile, err := readCoverProfile(profilePath)
+	if err != nil {
+		log.Printf("Error reading coverage profile %s: %v", profilePath, err)
 		return nil
 	}
 
 	fileMap := make(map[string][]CoverageBlock)
-	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.Fields(line)
-		if len(parts) != 3 {
-			continue
-		}
-		locationPart := parts[0]
-		count, _ := strconv.Atoi(parts[2])
-		colonIdx := strings.LastIndex(locationPart, ":")
-		if colonIdx == -1 {
-			continue
-		}
-		filename := locationPart[:colonIdx]
-		rangeParts := strings.Split(locationPart[colonIdx+1:], ",")
-		if len(rangeParts) != 2 {
-			continue
-		}
-		startParts := strings.Split(rangeParts[0], ".")
-		endParts := strings.Split(rangeParts[1], ".")
-		if len(startParts) < 1 || len(endParts) < 1 {
-			continue
-		}
-		startLine, _ := strconv.Atoi(startParts[0])
-		endLine, _ := strconv.Atoi(endParts[0])
-		block := CoverageBlock{
-			StartLine: startLine,
-			EndLine:   endLine,
-			Count:     count,
-			Covered:   count > 0,
-		}
-		fileMap[filename] = append(fileMap[filename], block)
+	for _, b := range profile {
+		fileMap[b.File] = append(fileMap[b.File], CoverageBlock{
+			StartLine:  b.StartLine,
+			EndLine:    b.EndLine,
+			Count:      b.Count,
+			Covered:    b.Count > 0,
+			StartCol:   b.StartCol,
+			EndCol:     b.EndCol,
+			Statements: b.Statements,
+		})
 	}
 
 	var files []FileCoverage
 	for filename, blocks := range fileMap {
-		fullPath := filename
-		if !filepath.IsAbs(filename) {
-			// This is the new logic to construct the correct path
-			relativePath := filename
-			// If we found a module name and the path from the coverage file starts with it...
-			if modErr == nil && strings.HasPrefix(filename, moduleName+"/") {
-				// ...then we strip that module name prefix to get a true relative path.
-				relativePath = strings.TrimPrefix(filename, moduleName+"/")
-			}
-			fullPath = filepath.Join(td.ProjectPath, relativePath)
-		}
+		fullPath := rc.coverageFilePath(filename)
 
 		content, err := os.ReadFile(fullPath)
 		if err != nil {
@@ -391,41 +696,11 @@ func (td *TestDashboard) parseCoverageProfile(profilePath string, _ string) []Fi
 
 // --- Other helpers (No Changes) ---
 
-func (td *TestDashboard) findGoPackages(root string) ([]string, error) {
-	var packages []string
-	uniquePackages := make(map[string]bool)
-	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if d.IsDir() {
-			if d.Name() == "vendor" || d.Name() == ".git" || d.Name() == "node_modules" || d.Name() == ".vscode" || d.Name() == ".idea" {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-		if strings.HasSuffix(path, "_test.go") {
-			dir := filepath.Dir(path)
-			if !uniquePackages[dir] {
-				uniquePackages[dir] = true
-				packages = append(packages, dir)
-			}
-		}
-		return nil
-	})
-	return packages, err
-}
-
-func (td *TestDashboard) generateHTMLCoverage(profilePath, htmlPath string) bool {
-	originalDir, _ := os.Getwd()
-	defer os.Chdir(originalDir)
-	err := os.Chdir(td.ProjectPath)
-	if err != nil {
-		log.Printf("Error changing to project directory for HTML coverage: %v", err)
-		return false
-	}
-	cmd := exec.Command("go", "tool", "cover", "-html="+filepath.Base(profilePath), "-o", filepath.Base(htmlPath))
-	cmd.Dir = td.ProjectPath
+// generateHTMLCoverage renders a profile with go tool cover, run from dir, the
+// root of the module the profile belongs to, so its import paths resolve.
+func (td *TestDashboard) generateHTMLCoverage(profilePath, htmlPath, dir string) bool {
+	cmd := exec.Command("go", "tool", "cover", "-html="+profilePath, "-o", htmlPath)
+	cmd.Dir = dir
 	if err := cmd.Run(); err != nil {
 		log.Printf("Error generating HTML coverage: %v", err)
 		return false
@@ -508,19 +783,46 @@ func extractCoverage(output string) float64 {
 	return 0.0
 }
 
+// htmlCoverageTTL is how long a generated HTML coverage report is kept.
+const htmlCoverageTTL = time.Hour
+
+// shutdown stops what a removed project left behind: its fuzz sessions are
+// killed right away, and its HTML coverage files are deleted once a run in
+// progress has finished.
+func (td *TestDashboard) shutdown() {
+	td.stopFuzzSessions()
+	go func() {
+		td.runMu.Lock()
+		defer td.runMu.Unlock()
+		// Every file expires within htmlCoverageTTL, so this deletes them all.
+		td.expireHTMLFiles(time.Now().Add(htmlCoverageTTL))
+	}()
+}
+
 func (td *TestDashboard) cleanupHTMLFiles() {
 	ticker := time.NewTicker(10 * time.Minute)
 	defer ticker.Stop()
-	for range ticker.C {
-		now := time.Now()
-		for filename, expiry := range td.HTMLFiles {
-			if now.After(expiry) {
-				filePath := filepath.Join(td.ProjectPath, filename)
-				if err := os.Remove(filePath); err == nil {
-					log.Printf("Cleaned up expired HTML coverage file: %s", filename)
-				}
-				delete(td.HTMLFiles, filename)
+	for {
+		select {
+		case <-td.quit:
+			return
+		case <-ticker.C:
+		}
+		td.expireHTMLFiles(time.Now())
+	}
+}
+
+// expireHTMLFiles deletes the HTML coverage files that expired before now.
+func (td *TestDashboard) expireHTMLFiles(now time.Time) {
+	td.htmlMu.Lock()
+	defer td.htmlMu.Unlock()
+	for filename, expiry := range td.HTMLFiles {
+		if now.After(expiry) {
+			filePath := filepath.Join(td.ProjectPath, filename)
+			if err := os.Remove(filePath); err == nil {
+				log.Printf("Cleaned up expired HTML coverage file: %s", filename)
 			}
+			delete(td.HTMLFiles, filename)
 		}
 	}
 }
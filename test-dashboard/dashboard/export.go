@@ -0,0 +1,524 @@
+package dashboard
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExportedFile is a file of an exported report, by its slash-separated path
+// relative to the report's root.
+type ExportedFile struct {
+	Path string
+	Data []byte
+}
+
+var exportSlugRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// exportCSS lays out the report's pages; getCustomCoverageCSS provides the
+// dark theme and the coverage colours, as in the dashboard's cover reports.
+const exportCSS = `
+.report { max-width: 1200px; margin: 0 auto; }
+h1, h2, h3 { font-weight: 600; }
+.meta { color: #a0a0a0; margin-bottom: 20px; }
+.cards { display: flex; gap: 16px; flex-wrap: wrap; margin: 20px 0; }
+.card { background: #262626; border: 1px solid #404040; border-radius: 8px; padding: 14px 20px; min-width: 140px; }
+.card .number { font-size: 1.8em; font-weight: 600; }
+.card .label { color: #a0a0a0; font-size: 0.85em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 24px; }
+th, td { text-align: left; padding: 6px 10px; border-bottom: 1px solid #404040; }
+th { color: #a0a0a0; font-weight: 600; }
+.pass, .high { color: #4CAF50; }
+.fail, .low { color: #f44336; }
+.skip, .medium { color: #ff9800; }
+.no-tests { color: #a0a0a0; }
+.mono, pre, select { font-family: 'SF Mono', Monaco, Menlo, monospace; }
+details { margin: 6px 0; }
+summary { cursor: pointer; }
+pre.output { background: #262626 !important; padding: 10px; border-radius: 6px; overflow-x: auto; white-space: pre-wrap; }
+#topbar { display: flex; gap: 12px; align-items: center; margin: 24px 0 8px; }
+select { background: #262626; color: #e0e0e0; border: 1px solid #404040; border-radius: 4px; padding: 4px; }
+.explorer { display: flex; gap: 16px; align-items: flex-start; }
+#tree { flex: 0 0 320px; max-height: calc(100vh - 140px); overflow: auto; position: sticky; top: 10px; font-size: 0.9em; }
+#tree details { margin: 2px 0 2px 12px; }
+#tree > details { margin-left: 0; }
+#tree a { display: flex; justify-content: space-between; gap: 8px; margin-left: 12px; padding: 1px 4px; text-decoration: none; color: #e0e0e0 !important; }
+#tree a.active { background: #404040; border-radius: 4px; }
+#tree .pct { flex-shrink: 0; }
+#source-view { flex: 1; min-width: 0; }
+pre.file { margin: 0; padding: 10px 0; overflow-x: auto; border: 1px solid #404040; border-radius: 6px; }
+pre.file span.line { display: block; padding-right: 10px; }
+pre.file .num, pre.file .count { display: inline-block; padding-right: 1em; text-align: right; color: #707070; user-select: none; }
+pre.file .num { width: 4em; }
+pre.file .count { width: 5em; }
+`
+
+// exportExplorerJS is the coverage explorer of an exported report. It reads the
+// coverage coverage.html embeds, so it works without a server.
+const exportExplorerJS = `(function() {
+	var source = 'all';
+	var select = document.getElementById('source');
+	var tree = document.getElementById('tree');
+	var view = document.getElementById('source-view');
+	var byName = {};
+	var links = {};
+	var percents = []; // {el, files} to update when the source changes
+	coverage.files.forEach(function(f) { byName[f.filename] = f; });
+
+	// runs is how often a block ran under the selected coverage source.
+	function runs(block) {
+		var integration = block.integration_count || 0;
+		if (source === 'unit') return block.count - integration;
+		if (source === 'integration') return integration;
+		return block.count;
+	}
+
+	// percentage is the share of blocks that ran, as on the dashboard.
+	function percentage(files) {
+		var total = 0, ran = 0;
+		files.forEach(function(f) {
+			(f.blocks || []).forEach(function(b) {
+				total++;
+				if (runs(b) > 0) ran++;
+			});
+		});
+		return total ? ran / total * 100 : 0;
+	}
+
+	function level(v) {
+		return v >= 80 ? 'high' : v >= 60 ? 'medium' : 'low';
+	}
+
+	function percent(files) {
+		var el = document.createElement('span');
+		percents.push({ el: el, files: files });
+		return el;
+	}
+
+	function updatePercents() {
+		percents.forEach(function(p) {
+			var v = percentage(p.files);
+			p.el.textContent = v.toFixed(1) + '%';
+			p.el.className = 'pct ' + level(v);
+		});
+	}
+
+	function newDir(name) {
+		return { name: name, dirs: {}, files: [], all: [] };
+	}
+
+	var root = newDir('');
+	coverage.files.forEach(function(f) {
+		var parts = f.filename.split('/');
+		var node = root;
+		for (var i = 0; i < parts.length - 1; i++) {
+			node = node.dirs[parts[i]] = node.dirs[parts[i]] || newDir(parts[i]);
+			node.all.push(f);
+		}
+		node.files.push({ name: parts[parts.length - 1], file: f });
+	});
+
+	// A directory holding nothing but another directory is shown as one,
+	// e.g. example.com/project.
+	function collapse(dir) {
+		var names = Object.keys(dir.dirs);
+		while (names.length === 1 && dir.files.length === 0) {
+			var only = dir.dirs[names[0]];
+			dir = { name: dir.name + '/' + only.name, dirs: only.dirs, files: only.files, all: only.all };
+			names = Object.keys(dir.dirs);
+		}
+		return dir;
+	}
+
+	function renderDir(node, parent) {
+		Object.keys(node.dirs).sort().forEach(function(name) {
+			var dir = collapse(node.dirs[name]);
+			var details = document.createElement('details');
+			details.open = true;
+			var summary = document.createElement('summary');
+			summary.appendChild(document.createTextNode(dir.name + '/ '));
+			summary.appendChild(percent(dir.all));
+			details.appendChild(summary);
+			renderDir(dir, details);
+			parent.appendChild(details);
+		});
+		node.files.forEach(function(entry) {
+			var link = document.createElement('a');
+			link.href = '#' + entry.file.filename;
+			var name = document.createElement('span');
+			name.textContent = entry.name;
+			link.appendChild(name);
+			link.appendChild(percent([entry.file]));
+			links[entry.file.filename] = link;
+			parent.appendChild(link);
+		});
+	}
+
+	function describe(b) {
+		var text = b.start_line + '.' + b.start_col + '-' + b.end_line + '.' + b.end_col + ': ran ' + runs(b) + ' times';
+		if (coverage.integration && source === 'all') {
+			var integration = b.integration_count || 0;
+			text += ' (unit ' + (b.count - integration) + ', integration ' + integration + ')';
+		}
+		return text;
+	}
+
+	function cell(className, text) {
+		var el = document.createElement('span');
+		el.className = className;
+		el.textContent = text;
+		return el;
+	}
+
+	function current() {
+		return decodeURIComponent(location.hash.slice(1));
+	}
+
+	function show(name) {
+		Object.keys(links).forEach(function(key) { links[key].className = key === name ? 'active' : ''; });
+		var file = byName[name];
+		if (!file) {
+			view.innerHTML = '<p class="meta">Select a file.</p>';
+			return;
+		}
+		var onLine = {};
+		(file.blocks || []).forEach(function(b) {
+			for (var l = b.start_line; l <= b.end_line; l++) (onLine[l] = onLine[l] || []).push(b);
+		});
+		var title = document.createElement('h2');
+		title.className = 'mono';
+		var value = percentage([file]);
+		title.appendChild(document.createTextNode(name + ' '));
+		title.appendChild(cell('pct ' + level(value), value.toFixed(1) + '%'));
+		var pre = document.createElement('pre');
+		pre.className = 'file';
+		file.content.replace(/\n$/, '').split('\n').forEach(function(text, i) {
+			var n = i + 1;
+			var line = cell('line', '');
+			var blocks = onLine[n] || [];
+			var starting = blocks.filter(function(b) { return b.start_line === n; });
+			if (blocks.length) {
+				line.className += blocks.some(function(b) { return runs(b) > 0; }) ? ' cov8' : ' cov0';
+				line.title = blocks.map(describe).join('\n');
+			}
+			line.appendChild(cell('num', n));
+			line.appendChild(cell('count', starting.map(runs).join(' / ')));
+			line.appendChild(document.createTextNode(text));
+			pre.appendChild(line);
+		});
+		view.innerHTML = '';
+		view.appendChild(title);
+		view.appendChild(pre);
+	}
+
+	renderDir(root, tree);
+	if (coverage.files.length === 0) tree.textContent = 'No coverage recorded in this run.';
+	select.addEventListener('change', function() {
+		source = select.value;
+		updatePercents();
+		show(current());
+	});
+	window.addEventListener('hashchange', function() {
+		show(current());
+		window.scrollTo(0, 0);
+	});
+	if (!byName[current()] && coverage.files.length > 0) {
+		location.replace('#' + coverage.files[0].filename);
+	}
+	show(current());
+	updatePercents();
+})();
+`
+
+var exportTemplates = template.Must(template.New("export").Funcs(template.FuncMap{
+	"pct":   func(v float64) string { return fmt.Sprintf("%.1f%%", v) },
+	"dur":   roundDuration,
+	"level": coverageLevel,
+	"short": shortCommit,
+	"time":  func(t time.Time) string { return t.Format("2006-01-02 15:04:05 MST") },
+}).Parse(`
+{{define "index"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1.0">
+<title>Test report {{.Run.ID}}</title>
+<link rel="stylesheet" href="style.css">
+</head>
+<body><div class="report">
+<h1>🧪 {{.Run.Data.ProjectName}} — test report</h1>
+<div class="meta">
+Run {{.Run.ID}} · started {{time .Run.StartedAt}}{{if not .Run.FinishedAt.IsZero}}, finished {{time .Run.FinishedAt}}{{end}}
+{{if .Run.Commit}}<br>Commit <span class="mono">{{short .Run.Commit}}</span>{{if .Run.Dirty}} with local changes{{end}}{{if .Run.Branch}} on {{.Run.Branch}}{{end}}{{if .Run.Subject}} — {{.Run.Subject}}{{end}}{{end}}
+{{if .Run.Options.Ref}}<br>Tested ref {{.Run.Options.Ref}}{{end}}
+</div>
+<div class="cards">
+<div class="card"><div class="number {{level .Run.Data.OverallCoverage}}">{{pct .Run.Data.OverallCoverage}}</div><div class="label">Overall coverage</div></div>
+<div class="card"><div class="number">{{.Run.Data.TotalTests}}</div><div class="label">Packages</div></div>
+<div class="card"><div class="number pass">{{.Run.Data.PassedTests}}</div><div class="label">Passed</div></div>
+<div class="card"><div class="number fail">{{.Failed}}</div><div class="label">Failed</div></div>
+<div class="card"><div class="number {{if .Run.Data.GatePassed}}pass{{else}}fail{{end}}">{{if .Run.Data.GatePassed}}passed{{else}}failed{{end}}</div><div class="label">Quality gate</div></div>
+</div>
+<p><a href="coverage.html">📊 Coverage explorer</a> — every covered file by directory, with per-block execution counts{{if .Integration}} and the unit and integration coverage apart{{end}}</p>
+<h2>Packages</h2>
+<table>
+<tr><th>Package</th><th>Status</th><th>Tests</th><th>Coverage</th><th>Statements</th><th>Duration</th></tr>
+{{range .Packages}}<tr>
+<td><a href="{{.Page}}">{{.Result.Package}}</a></td>
+<td class="{{.Status}}">{{.Status}}</td>
+<td>{{.Passed}} / {{len .Result.Tests}}</td>
+<td class="{{level .Result.Coverage}}">{{pct .Result.Coverage}}</td>
+<td>{{.Result.Covered}} / {{.Result.Statements}}</td>
+<td>{{dur .Result.Duration}}</td>
+</tr>{{end}}
+</table>
+</div></body>
+</html>
+{{end}}
+
+{{define "coverage"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1.0">
+<title>Coverage — test report {{.Run.ID}}</title>
+<link rel="stylesheet" href="style.css">
+</head>
+<body><div class="report">
+<p><a href="index.html">← Summary</a></p>
+<h1>Coverage explorer</h1>
+<div class="meta">Run {{.Run.ID}} · overall coverage <span class="{{level .Run.Data.OverallCoverage}}">{{pct .Run.Data.OverallCoverage}}</span> · the count next to a line is how often its blocks ran</div>
+<div id="topbar">
+<select id="source"{{if not .Integration}} hidden{{end}}>
+<option value="all">All coverage</option>
+<option value="unit">Unit tests only</option>
+<option value="integration">Integration only</option>
+</select>
+<span class="cov0">not covered</span> <span class="cov8">covered</span>
+</div>
+<div class="explorer">
+<nav id="tree"></nav>
+<div id="source-view"><p class="meta">Select a file.</p></div>
+</div>
+</div>
+<script>var coverage = {{.Coverage}};</script>
+<script src="coverage.js"></script>
+</body>
+</html>
+{{end}}
+
+{{define "package"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1.0">
+<title>{{.Result.Package}} — test report {{.RunID}}</title>
+<link rel="stylesheet" href="../style.css">
+</head>
+<body><div class="report">
+<p><a href="../index.html">← All packages</a></p>
+<h1>{{.Result.Package}}</h1>
+<div class="meta">{{if .Result.ImportPath}}<span class="mono">{{.Result.ImportPath}}</span> · {{end}}<span class="{{.Status}}">{{.Status}}</span> · {{dur .Result.Duration}} · coverage <span class="{{level .Result.Coverage}}">{{pct .Result.Coverage}}</span> ({{.Result.Covered}} of {{.Result.Statements}} statements)</div>
+{{if .Result.Tests}}<h2>Tests</h2>
+<table>
+<tr><th>Test</th><th>Status</th><th>Duration</th></tr>
+{{range .Result.Tests}}<tr>
+<td class="mono">{{.Name}}</td>
+<td class="{{.Status}}">{{.Status}}{{if .Quarantined}} (quarantined){{end}}</td>
+<td>{{dur .Duration}}</td>
+</tr>{{end}}
+</table>
+{{range .Result.Tests}}{{if .Output}}<details{{if eq .Status "fail"}} open{{end}}><summary class="{{.Status}} mono">{{.Name}}</summary><pre class="output">{{.Output}}</pre></details>{{end}}{{end}}
+{{end}}
+{{if .Result.Output}}<details{{if not .Result.Tests}} open{{end}}><summary>Full output</summary><pre class="output">{{.Result.Output}}</pre></details>{{end}}
+{{if .Files}}<h2>Coverage</h2>
+<table>
+<tr><th>File</th><th>Coverage</th></tr>
+{{range .Files}}<tr>
+<td class="mono"><a href="../coverage.html#{{.Name}}">{{.Name}}</a></td>
+<td class="{{level .Coverage}}">{{pct .Coverage}}</td>
+</tr>{{end}}
+</table>
+{{else}}<p class="meta">No coverage recorded for this package.</p>{{end}}
+</div></body>
+</html>
+{{end}}
+`))
+
+type exportPackage struct {
+	Result *TestResult
+	Page   string
+	Status string
+	Passed int
+	RunID  string
+	Files  []exportFile
+}
+
+type exportFile struct {
+	Name     string
+	Coverage float64
+}
+
+// exportCoverage is the coverage the explorer page embeds.
+type exportCoverage struct {
+	Integration bool                 `json:"integration"` // blocks carry integration counts
+	Files       []exportCoverageFile `json:"files"`
+}
+
+type exportCoverageFile struct {
+	Package string `json:"package"`
+	FileCoverage
+}
+
+// exportSite renders a run as a static site: an index with the summary and the
+// packages, a page per package with its tests and their output, and a coverage
+// explorer that embeds the run's coverage and browses it by directory, with
+// per-block counts and, when the run merged integration coverage, the unit and
+// integration coverage apart. It opens offline, from the file system.
+func exportSite(run *RunRecord, css string) ([]ExportedFile, error) {
+	packages := make([]exportPackage, len(run.Data.Results))
+	used := make(map[string]bool)
+	failed := 0
+	coverage := exportCoverage{
+		Integration: run.Data.Integration != nil && run.Data.Integration.Artifact != "",
+		Files:       []exportCoverageFile{},
+	}
+	for i := range run.Data.Results {
+		r := &run.Data.Results[i]
+		slug := strings.Trim(exportSlugRe.ReplaceAllString(strings.TrimPrefix(r.Package, "./"), "-"), "-")
+		if slug == "" {
+			slug = "root"
+		}
+		page := slug
+		for n := 2; used[page]; n++ {
+			page = fmt.Sprintf("%s-%d", slug, n)
+		}
+		used[page] = true
+
+		p := exportPackage{Result: r, Page: "packages/" + page + ".html", Status: "pass", RunID: run.ID}
+		switch {
+		case r.NoTests:
+			p.Status = "no-tests"
+		case !r.Passed:
+			p.Status = "fail"
+			failed++
+		}
+		for _, t := range r.Tests {
+			if t.Status == "pass" {
+				p.Passed++
+			}
+		}
+		for _, f := range r.Files {
+			p.Files = append(p.Files, exportFile{Name: f.Filename, Coverage: f.Coverage})
+			coverage.Files = append(coverage.Files, exportCoverageFile{Package: r.Package, FileCoverage: f})
+		}
+		sort.Slice(p.Files, func(i, j int) bool { return p.Files[i].Name < p.Files[j].Name })
+		packages[i] = p
+	}
+	sort.Slice(coverage.Files, func(i, j int) bool { return coverage.Files[i].Filename < coverage.Files[j].Filename })
+
+	files := []ExportedFile{
+		{Path: "style.css", Data: []byte(css + exportCSS)},
+		{Path: "coverage.js", Data: []byte(exportExplorerJS)},
+	}
+	var buf bytes.Buffer
+	render := func(name, path string, data interface{}) error {
+		buf.Reset()
+		if err := exportTemplates.ExecuteTemplate(&buf, name, data); err != nil {
+			return err
+		}
+		files = append(files, ExportedFile{Path: path, Data: append([]byte(nil), buf.Bytes()...)})
+		return nil
+	}
+	err := render("index", "index.html", struct {
+		Run         *RunRecord
+		Failed      int
+		Packages    []exportPackage
+		Integration bool
+	}{run, failed, packages, coverage.Integration})
+	if err != nil {
+		return nil, err
+	}
+	err = render("coverage", "coverage.html", struct {
+		Run         *RunRecord
+		Integration bool
+		Coverage    exportCoverage
+	}{run, coverage.Integration, coverage})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range packages {
+		if err := render("package", p.Page, p); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// ExportRun renders a stored run, or "latest", as a static site.
+func (td *TestDashboard) ExportRun(id string) ([]ExportedFile, error) {
+	var run *RunRecord
+	var err error
+	if id == "latest" {
+		run, err = td.History.Latest()
+	} else {
+		run, err = td.History.Load(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return exportSite(run, td.getCustomCoverageCSS())
+}
+
+// WriteExportZip writes the files of a report to w as a zip archive, under a
+// directory named root.
+func WriteExportZip(w io.Writer, root string, files []ExportedFile) error {
+	zw := zip.NewWriter(w)
+	now := time.Now()
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: path.Join(root, f.Path), Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(f.Data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// ExportRunDir writes a stored run's static site to a directory and returns the paths of the files it wrote. The directory must be
+// new, empty or hold an earlier report, so nothing else gets overwritten.
+func (td *TestDashboard) ExportRunDir(id, dir string) ([]string, error) {
+	if !filepath.IsAbs(dir) {
+		return nil, fmt.Errorf("the export directory must be an absolute path")
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 && !fileExists(filepath.Join(dir, "index.html")) {
+		return nil, fmt.Errorf("%s is not empty and does not hold a report", dir)
+	}
+	files, err := td.ExportRun(id)
+	if err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, f := range files {
+		target := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(target, f.Data, 0644); err != nil {
+			return written, err
+		}
+		written = append(written, target)
+	}
+	return written, nil
+}
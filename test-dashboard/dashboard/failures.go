@@ -0,0 +1,264 @@
+package dashboard
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TestFailure is one failing test with what it reported. Failures of a package
+// are ordered like the --- FAIL hierarchy: every parent directly before its
+// subtests.
+type TestFailure struct {
+	Test    string    `json:"test"` // empty for failures outside any test, e.g. build errors
+	Depth   int       `json:"depth"`
+	Records []Failure `json:"records,omitempty"`
+}
+
+// Failure is a structured piece of failure output, pointing at the line that
+// reported it.
+type Failure struct {
+	Kind     string       `json:"kind"` // "message", "assertion", "panic" or "build"
+	Message  string       `json:"message"`
+	Messages string       `json:"messages,omitempty"` // the extra messages of a testify assertion
+	File     string       `json:"file,omitempty"`
+	Line     int          `json:"line,omitempty"`
+	Source   string       `json:"source,omitempty"` // project-relative path for the source viewer
+	Trace    []StackFrame `json:"trace,omitempty"`  // testify's Error Trace or the panicking stack
+}
+
+var (
+	// t.Error/t.Log output: "    calc_test.go:42: message", continued on more deeply indented lines.
+	testLogRe = regexp.MustCompile(`^(\s+)([^\s:]+\.go):(\d+): ?(.*)$`)
+	// Compiler and vet errors: "calc/calc.go:10:2: undefined: x".
+	buildErrorRe = regexp.MustCompile(`^(\S+\.go):(\d+)(?::\d+)?: (.*)$`)
+	// A testify field: "\tError Trace:\t/path/calc_test.go:42" or "\t   \tcontinued".
+	testifyFieldRe = regexp.MustCompile(`^\t([A-Za-z ]*?):?\s*\t(.*)$`)
+	frameLineRe    = regexp.MustCompile(`^(.+\.go):(\d+)$`)
+)
+
+// parseFailures turns the output of a package run into structured failures.
+// Test output is attributed to tests by `go test -json`, so each failing test
+// is parsed on its own; output that belongs to no test is only parsed when no
+// test failed, which is when it explains the failure (build errors, a panic in
+// TestMain or init).
+func (rc runCtx) parseFailures(relPkg, output string, tests []TestCase) []TestFailure {
+	var failing []TestCase
+	for _, tc := range tests {
+		if tc.Status == "fail" {
+			failing = append(failing, tc)
+		}
+	}
+
+	if len(failing) == 0 {
+		records := rc.parseFailureOutput(relPkg, output, true)
+		if len(records) == 0 {
+			return nil
+		}
+		return []TestFailure{{Records: records}}
+	}
+
+	// Order parents before their subtests, keeping run order among siblings.
+	children := make(map[string][]TestCase)
+	known := make(map[string]bool)
+	for _, tc := range failing {
+		known[tc.Name] = true
+	}
+	var roots []TestCase
+	for _, tc := range failing {
+		if i := strings.LastIndex(tc.Name, "/"); i != -1 && known[tc.Name[:i]] {
+			children[tc.Name[:i]] = append(children[tc.Name[:i]], tc)
+		} else {
+			roots = append(roots, tc)
+		}
+	}
+	var failures []TestFailure
+	var visit func(tc TestCase)
+	visit = func(tc TestCase) {
+		failures = append(failures, TestFailure{
+			Test:    tc.Name,
+			Depth:   strings.Count(tc.Name, "/"),
+			Records: rc.parseFailureOutput(relPkg, tc.Output, false),
+		})
+		for _, child := range children[tc.Name] {
+			visit(child)
+		}
+	}
+	for _, tc := range roots {
+		visit(tc)
+	}
+	return failures
+}
+
+// parseFailureOutput extracts failure records from the output of one test, or
+// of a whole package when packageLevel is set.
+func (rc runCtx) parseFailureOutput(relPkg, output string, packageLevel bool) []Failure {
+	lines := strings.Split(output, "\n")
+	var records []Failure
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if strings.HasPrefix(line, "panic: ") {
+			records = append(records, rc.parsePanic(line, strings.Join(lines[i+1:], "\n")))
+			break // the rest of the output is the goroutine dump
+		}
+
+		if m := testLogRe.FindStringSubmatch(line); m != nil {
+			indent := len(m[1])
+			var body []string
+			for i+1 < len(lines) && continuesLog(lines[i+1], indent) {
+				i++
+				body = append(body, strings.TrimPrefix(lines[i], strings.Repeat(" ", indent+4)))
+			}
+			lineNo, _ := strconv.Atoi(m[3])
+			record := Failure{Kind: "message", File: m[2], Line: lineNo, Source: rc.packageSource(relPkg, m[2])}
+			if !rc.parseTestifyBlock(&record, relPkg, body) {
+				record.Message = strings.TrimRight(strings.Join(append([]string{m[4]}, body...), "\n"), "\n ")
+			}
+			records = append(records, record)
+			continue
+		}
+
+		if packageLevel {
+			if m := buildErrorRe.FindStringSubmatch(line); m != nil {
+				lineNo, _ := strconv.Atoi(m[2])
+				file := strings.TrimPrefix(m[1], "./")
+				source := rc.source(file)
+				if mod := moduleOf(rc.modules, relPkg); source == "" && mod != nil {
+					// go test ran from a nested module's root, so paths are relative to it.
+					source = rc.source(path.Join(mod.Dir, file))
+				}
+				records = append(records, Failure{
+					Kind: "build", Message: m[3], File: m[1], Line: lineNo,
+					Source: source,
+				})
+			}
+		}
+	}
+	return records
+}
+
+// continuesLog reports whether line continues a t.Log message that started at
+// the given indentation; continuation lines are indented four more spaces.
+func continuesLog(line string, indent int) bool {
+	return strings.HasPrefix(line, strings.Repeat(" ", indent+4))
+}
+
+// parseTestifyBlock fills record from a testify assertion failure, whose body
+// looks like
+//
+//	\tError Trace:\t/path/calc_test.go:42
+//	\tError:      \tNot equal:
+//	\t            \texpected: 1
+//	\tMessages:   \tvalues differ
+//
+// It returns false if body is not such a block.
+func (rc runCtx) parseTestifyBlock(record *Failure, relPkg string, body []string) bool {
+	fields := make(map[string][]string)
+	var label string
+	for _, line := range body {
+		m := testifyFieldRe.FindStringSubmatch(line)
+		if m == nil {
+			if label == "" {
+				return false
+			}
+			continue
+		}
+		if name := strings.TrimSpace(m[1]); name != "" {
+			label = name
+		}
+		if label == "" {
+			return false
+		}
+		fields[label] = append(fields[label], m[2])
+	}
+	if _, ok := fields["Error Trace"]; !ok {
+		return false
+	}
+
+	record.Kind = "assertion"
+	record.Message = strings.TrimSpace(strings.Join(fields["Error"], "\n"))
+	record.Messages = strings.TrimSpace(strings.Join(fields["Messages"], "\n"))
+	for _, location := range fields["Error Trace"] {
+		m := frameLineRe.FindStringSubmatch(strings.TrimSpace(location))
+		if m == nil {
+			continue
+		}
+		lineNo, _ := strconv.Atoi(m[2])
+		frame := StackFrame{File: m[1], Line: lineNo, Source: rc.relPath(m[1])}
+		if !filepath.IsAbs(m[1]) {
+			// Some testify versions print paths relative to the package.
+			frame.Source = rc.packageSource(relPkg, m[1])
+		}
+		record.Trace = append(record.Trace, frame)
+	}
+	// Point at the innermost trace entry inside the project, usually the assertion itself.
+	for _, frame := range record.Trace {
+		if frame.Source != "" {
+			record.File, record.Line, record.Source = frame.File, frame.Line, frame.Source
+			break
+		}
+	}
+	return true
+}
+
+// parsePanic builds a record from a "panic: ..." line and the goroutine dump
+// that follows it. The location is the innermost project frame of the
+// panicking goroutine.
+func (rc runCtx) parsePanic(line, rest string) Failure {
+	message := strings.TrimPrefix(line, "panic: ")
+	if i := strings.LastIndex(message, " [recovered"); i != -1 {
+		message = message[:i]
+	}
+	record := Failure{Kind: "panic", Message: message}
+
+	dump := rc.parseGoroutineDump(rest)
+	if dump == nil || len(dump.Groups) == 0 {
+		return record
+	}
+	frames := dump.Groups[0].Frames
+	for _, g := range dump.Groups {
+		if g.State == "running" {
+			frames = g.Frames
+			break
+		}
+	}
+	// Drop the frames of testing's recover handler and the runtime's panic
+	// machinery that sit above the code that actually panicked.
+	for i, f := range frames {
+		if f.Function == "panic" || strings.HasPrefix(f.Function, "runtime.gopanic") {
+			frames = frames[i+1:]
+			break
+		}
+	}
+	record.Trace = frames
+	for _, f := range frames {
+		if f.Source != "" {
+			record.File, record.Line, record.Source = f.File, f.Line, f.Source
+			break
+		}
+	}
+	return record
+}
+
+// packageSource resolves a file name from t.Log output, which is relative to
+// the package directory, to a project-relative path.
+func (rc runCtx) packageSource(relPkg, file string) string {
+	return rc.source(path.Join(strings.TrimPrefix(relPkg, "./"), file))
+}
+
+// source returns rel if it names an existing file below the root.
+func (rc runCtx) source(rel string) string {
+	rel = path.Clean(rel)
+	if strings.HasPrefix(rel, "../") || rel == ".." {
+		return ""
+	}
+	if _, err := os.Stat(filepath.Join(rc.root, filepath.FromSlash(rel))); err != nil {
+		return ""
+	}
+	return rel
+}
@@ -0,0 +1,185 @@
+package dashboard
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readFailFixture returns a run context for the project in testdata/failfix and
+// the output and tests of one of its packages. The *.json files are the
+// `go test -json` streams of the packages, recorded with the project in
+// /tmp/failfix; that path is replaced by the fixture's own location.
+func readFailFixture(t *testing.T, name string) (runCtx, string, []TestCase) {
+	t.Helper()
+	root, err := filepath.Abs("testdata/failfix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := os.ReadFile(filepath.Join(root, name+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream = bytes.ReplaceAll(stream, []byte("/tmp/failfix"), []byte(root))
+	output, tests := parseTestJSON(stream)
+	return runCtx{root: root, modules: []Module{{Path: "example.com/failfix", Dir: "."}}}, output, tests
+}
+
+func TestParseFailures(t *testing.T) {
+	rc, output, tests := readFailFixture(t, "calc")
+	testFile := filepath.Join(rc.root, "calc/calc_test.go")
+	got := rc.parseFailures("./calc", output, tests)
+
+	want := []TestFailure{
+		{Test: "TestAdd", Records: []Failure{
+			{Kind: "message", Message: "Add(2, 2) = 5\nwant 4", File: "calc_test.go", Line: 11, Source: "calc/calc_test.go"},
+		}},
+		{Test: "TestTable"},
+		{Test: "TestTable/small", Depth: 1, Records: []Failure{{
+			Kind: "assertion", Message: "Not equal: \nexpected: 2\nactual  : 3", Messages: "adding 1 and 1",
+			File: testFile, Line: 17, Source: "calc/calc_test.go",
+			Trace: []StackFrame{{File: testFile, Line: 17, Source: "calc/calc_test.go"}},
+		}}},
+		{Test: "TestTable/zero", Depth: 1, Records: []Failure{
+			{Kind: "message", Message: "checking zero", File: "calc_test.go", Line: 20, Source: "calc/calc_test.go"},
+			{
+				Kind: "assertion", Message: "Not equal: \nexpected: 0\nactual  : 1",
+				File: testFile, Line: 21, Source: "calc/calc_test.go",
+				Trace: []StackFrame{{File: testFile, Line: 21, Source: "calc/calc_test.go"}},
+			},
+		}},
+		// A panic in a subtest is reported in its parent's output.
+		{Test: "TestDiv", Records: []Failure{{
+			Kind: "panic", Message: "runtime error: integer divide by zero",
+			File: filepath.Join(rc.root, "calc/calc.go"), Line: 10, Source: "calc/calc.go",
+			Trace: []StackFrame{
+				{Function: "example.com/failfix/calc.Div", File: filepath.Join(rc.root, "calc/calc.go"), Line: 10, Source: "calc/calc.go"},
+				{Function: "example.com/failfix/calc.TestDiv.func1", File: testFile, Line: 30, Source: "calc/calc_test.go"},
+				{Function: "testing.tRunner", File: "/usr/local/go/src/testing/testing.go", Line: 2193},
+			},
+		}}},
+		{Test: "TestDiv/by_zero", Depth: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parseFailures returned %d failures; want %d:\n%+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("failure %d:\n got %+v\nwant %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseFailuresPackageLevel(t *testing.T) {
+	tests := []struct {
+		name, pkg string
+		want      []Failure
+	}{
+		{"broken", "./broken", []Failure{
+			{Kind: "build", Message: "undefined: answer", File: "broken/broken.go", Line: 4, Source: "broken/broken.go"},
+		}},
+		// A panic during init fails the package without failing any test.
+		{"boot", "./boot", []Failure{{
+			Kind: "panic", Message: "assignment to entry in nil map",
+			File: "boot/boot.go", Line: 7, Source: "boot/boot.go",
+			Trace: []StackFrame{
+				{Function: "example.com/failfix/boot.load", File: "boot/boot.go", Line: 7, Source: "boot/boot.go"},
+				{Function: "example.com/failfix/boot.init", File: "boot/boot.go", Line: 3, Source: "boot/boot.go"},
+			},
+		}}},
+	}
+	for _, tt := range tests {
+		rc, output, cases := readFailFixture(t, tt.name)
+		for i := range tt.want {
+			// Stack traces carry absolute paths.
+			if tt.want[i].Kind == "panic" {
+				tt.want[i].File = filepath.Join(rc.root, tt.want[i].File)
+				for j := range tt.want[i].Trace {
+					tt.want[i].Trace[j].File = filepath.Join(rc.root, tt.want[i].Trace[j].File)
+				}
+			}
+		}
+		got := rc.parseFailures(tt.pkg, output, cases)
+		want := []TestFailure{{Records: tt.want}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: parseFailures:\n got %+v\nwant %+v", tt.name, got, want)
+		}
+	}
+}
+
+func TestParseTestifyBlock(t *testing.T) {
+	rc := runCtx{root: "/src/app"}
+	tests := []struct {
+		name string
+		body []string
+		ok   bool
+		want Failure
+	}{
+		{
+			name: "multi-line trace",
+			body: []string{
+				"\tError Trace:\t/usr/lib/go/src/example.com/helpers/assert.go:12",
+				"\t            \t/src/app/calc/calc_test.go:42",
+				"\tError:      \tShould be true",
+				"\tTest:       \tTestCalc",
+			},
+			ok: true,
+			want: Failure{
+				Kind: "assertion", Message: "Should be true",
+				File: "/src/app/calc/calc_test.go", Line: 42, Source: "calc/calc_test.go",
+				Trace: []StackFrame{
+					{File: "/usr/lib/go/src/example.com/helpers/assert.go", Line: 12},
+					{File: "/src/app/calc/calc_test.go", Line: 42, Source: "calc/calc_test.go"},
+				},
+			},
+		},
+		{
+			name: "plain message",
+			body: []string{"second line of a t.Error message"},
+		},
+		{
+			name: "no error trace",
+			body: []string{"\tError:      \tNot equal"},
+		},
+		{name: "empty"},
+	}
+	for _, tt := range tests {
+		var record Failure
+		ok := rc.parseTestifyBlock(&record, "./calc", tt.body)
+		if ok != tt.ok || (ok && !reflect.DeepEqual(record, tt.want)) {
+			t.Errorf("%s: parseTestifyBlock = %v, %+v; want %v, %+v", tt.name, ok, record, tt.ok, tt.want)
+		}
+	}
+}
+
+func TestParsePanic(t *testing.T) {
+	rc := runCtx{root: "/src/app"}
+	tests := []struct {
+		name, line, rest string
+		want             Failure
+	}{
+		{
+			name: "no stack",
+			line: "panic: boom",
+			want: Failure{Kind: "panic", Message: "boom"},
+		},
+		{
+			name: "recovered and repanicked",
+			line: "panic: boom [recovered, repanicked]",
+			rest: "\ngoroutine 7 [running]:\ntesting.tRunner.func1.2({0x82c6e0, 0x8a5700})\n\t/usr/local/go/src/testing/testing.go:2123 +0x232\n" +
+				"panic({0x82c6e0?, 0x8a5700?})\n\t/usr/local/go/src/runtime/panic.go:859 +0x125\n" +
+				"example.com/app.Explode()\n\t/src/app/app.go:5 +0x25\n",
+			want: Failure{
+				Kind: "panic", Message: "boom", File: "/src/app/app.go", Line: 5, Source: "app.go",
+				Trace: []StackFrame{{Function: "example.com/app.Explode", File: "/src/app/app.go", Line: 5, Source: "app.go"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		if got := rc.parsePanic(tt.line, tt.rest); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parsePanic:\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,456 @@
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FlakyTest summarises how a test behaved across the stored runs.
+// Score is the share of consecutive observations on unchanged code (same tree
+// hash, or same commit when no tree hash was recorded) that flipped between
+// pass and fail. A score of 0 means the test never flipped.
+type FlakyTest struct {
+	Package     string    `json:"package"`
+	Name        string    `json:"name"`
+	Runs        int       `json:"runs"`
+	Failures    int       `json:"failures"`
+	Flips       int       `json:"flips"`
+	Score       float64   `json:"score"`
+	LastStatus  string    `json:"last_status"`
+	LastSeen    time.Time `json:"last_seen"`
+	Quarantined bool      `json:"quarantined"`
+}
+
+// StressResult is the outcome of running one test many times with -count.
+type StressResult struct {
+	Package       string        `json:"package"`
+	Test          string        `json:"test"`
+	Count         int           `json:"count"`
+	Runs          int           `json:"runs"`
+	Failures      int           `json:"failures"`
+	FailureRate   float64       `json:"failure_rate"`
+	Duration      time.Duration `json:"duration"`
+	FailureOutput string        `json:"failure_output,omitempty"`
+	TimedOut      bool          `json:"timed_out,omitempty"` // the hang watchdog stopped the run
+	Error         string        `json:"error,omitempty"`
+}
+
+// StressProgress is pushed over the WebSocket as "stress" events while a
+// stress run is going.
+type StressProgress struct {
+	Package  string        `json:"package"`
+	Test     string        `json:"test"`
+	Count    int           `json:"count"`
+	Runs     int           `json:"runs"`
+	Failures int           `json:"failures"`
+	Elapsed  time.Duration `json:"elapsed"`
+	Status   string        `json:"status"` // "running" or "finished"
+}
+
+// QuarantineEntry marks a test whose failures are reported but do not fail the
+// quality gate. Quarantining a test also covers its subtests.
+type QuarantineEntry struct {
+	Package string    `json:"package"`
+	Test    string    `json:"test"`
+	Reason  string    `json:"reason,omitempty"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// Quarantine is the persisted list of quarantined tests for a project.
+type Quarantine struct {
+	path    string
+	mu      sync.Mutex
+	entries []QuarantineEntry
+}
+
+// NewQuarantine loads the quarantine list stored alongside the project's history.
+func NewQuarantine(projectPath string) *Quarantine {
+	q := &Quarantine{path: filepath.Join(projectDataDir(projectPath), "quarantine.json")}
+	if content, err := os.ReadFile(q.path); err == nil {
+		json.Unmarshal(content, &q.entries)
+	}
+	return q
+}
+
+// List returns a copy of the quarantined tests.
+func (q *Quarantine) List() []QuarantineEntry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]QuarantineEntry{}, q.entries...)
+}
+
+// Add quarantines a test. Adding an already quarantined test updates its reason.
+func (q *Quarantine) Add(entry QuarantineEntry) error {
+	if entry.Package == "" || entry.Test == "" {
+		return fmt.Errorf("package and test are required")
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, existing := range q.entries {
+		if existing.Package == entry.Package && existing.Test == entry.Test {
+			q.entries[i].Reason = entry.Reason
+			return q.save()
+		}
+	}
+	if entry.AddedAt.IsZero() {
+		entry.AddedAt = time.Now()
+	}
+	q.entries = append(q.entries, entry)
+	return q.save()
+}
+
+// Remove takes a test out of quarantine.
+func (q *Quarantine) Remove(pkg, test string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, existing := range q.entries {
+		if existing.Package == pkg && existing.Test == test {
+			q.entries = append(q.entries[:i], q.entries[i+1:]...)
+			return q.save()
+		}
+	}
+	return fmt.Errorf("%s %s is not quarantined", pkg, test)
+}
+
+// Contains reports whether a test, or one of its parent tests, is quarantined.
+func (q *Quarantine) Contains(pkg, test string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, entry := range q.entries {
+		if entry.Package == pkg && (entry.Test == test || strings.HasPrefix(test, entry.Test+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// save writes the list to disk. Callers must hold q.mu.
+func (q *Quarantine) save() error {
+	if err := os.MkdirAll(filepath.Dir(q.path), 0o755); err != nil {
+		return fmt.Errorf("could not create data directory: %w", err)
+	}
+	content, err := json.MarshalIndent(q.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(q.path, content, 0o644)
+}
+
+// applyQuarantine flags quarantined test cases and reports whether the results
+// pass the quality gate. A failing package only passes the gate when every leaf
+// failure (a failed test without failed subtests) is quarantined; packages that
+// failed without any test failing, such as build errors, always fail it.
+func (td *TestDashboard) applyQuarantine(results []TestResult) (gatePassed bool, quarantined int) {
+	gatePassed = true
+	for i := range results {
+		result := &results[i]
+		if result.Passed {
+			continue
+		}
+		failed := failedTests(result.Tests)
+		if len(failed) == 0 {
+			gatePassed = false
+			continue
+		}
+		tests := make([]TestCase, len(result.Tests))
+		copy(tests, result.Tests)
+		for j := range tests {
+			tests[j].Quarantined = td.Quarantine.Contains(result.Package, tests[j].Name)
+		}
+		result.Tests = tests
+
+		for _, name := range failed {
+			if hasFailedSubtest(name, failed) {
+				continue
+			}
+			if td.Quarantine.Contains(result.Package, name) {
+				quarantined++
+			} else {
+				gatePassed = false
+			}
+		}
+	}
+	return gatePassed, quarantined
+}
+
+// RefreshGate re-evaluates the quality gate for the current results, e.g. after
+// the quarantine list changed, broadcasts the updated state and stores it with
+// the current run, so badges, exports and webhooks see the same gate. It waits
+// for a run in progress.
+func (td *TestDashboard) RefreshGate() {
+	td.runMu.Lock()
+	defer td.runMu.Unlock()
+
+	data := td.Snapshot()
+	data.Results = append([]TestResult(nil), data.Results...)
+	data.GatePassed, data.QuarantinedFailures = td.applyQuarantine(data.Results)
+	td.publish(data)
+
+	// Before the first run since startup, the current run is the latest stored one.
+	run := td.run
+	if run == nil || run.FinishedAt.IsZero() {
+		latest, err := td.History.Latest()
+		if err != nil {
+			return
+		}
+		run = latest
+	}
+	run.Data.Results = append([]TestResult(nil), run.Data.Results...)
+	run.Data.GatePassed, run.Data.QuarantinedFailures = td.applyQuarantine(run.Data.Results)
+	if err := td.History.Save(run); err != nil {
+		log.Printf("Error storing run %s: %v", run.ID, err)
+	}
+}
+
+func hasFailedSubtest(name string, failed []string) bool {
+	for _, other := range failed {
+		if strings.HasPrefix(other, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// FlakyTests scores every test seen in the stored runs by how often it flipped
+// between pass and fail on unchanged code, most flaky first. Only tests that
+// flipped at least once or are quarantined are returned. A failure that passed
+// on re-run within a single run counts as a flip.
+func (td *TestDashboard) FlakyTests() ([]FlakyTest, error) {
+	runs, err := td.History.Runs()
+	if err != nil {
+		return nil, err
+	}
+
+	type observation struct {
+		version string
+		status  string
+	}
+	observations := make(map[[2]string][]observation)
+	stats := make(map[[2]string]*FlakyTest)
+
+	for _, run := range runs {
+		version := run.TreeHash
+		if version == "" {
+			version = run.Commit
+		}
+		for _, result := range run.Data.Results {
+			for _, tc := range result.Tests {
+				if tc.Status == "skip" {
+					continue
+				}
+				key := [2]string{result.Package, tc.Name}
+				ft := stats[key]
+				if ft == nil {
+					ft = &FlakyTest{Package: result.Package, Name: tc.Name}
+					stats[key] = ft
+				}
+				statuses := []string{tc.Status}
+				if tc.PossiblyFlaky || tc.Reproduced {
+					// The re-run replaced a failure; record the original outcome first.
+					statuses = []string{"fail", tc.Status}
+				}
+				for _, status := range statuses {
+					ft.Runs++
+					if status == "fail" {
+						ft.Failures++
+					}
+					if version != "" {
+						observations[key] = append(observations[key], observation{version, status})
+					}
+				}
+				ft.LastStatus = tc.Status
+				ft.LastSeen = run.StartedAt
+			}
+		}
+	}
+
+	var flaky []FlakyTest
+	for key, ft := range stats {
+		transitions := 0
+		last := make(map[string]string)
+		for _, obs := range observations[key] {
+			if prev, ok := last[obs.version]; ok {
+				transitions++
+				if prev != obs.status {
+					ft.Flips++
+				}
+			}
+			last[obs.version] = obs.status
+		}
+		if transitions > 0 {
+			ft.Score = float64(ft.Flips) / float64(transitions)
+		}
+		ft.Quarantined = td.Quarantine.Contains(ft.Package, ft.Name)
+		if ft.Flips > 0 || ft.Quarantined {
+			flaky = append(flaky, *ft)
+		}
+	}
+
+	sort.Slice(flaky, func(i, j int) bool {
+		if flaky[i].Score != flaky[j].Score {
+			return flaky[i].Score > flaky[j].Score
+		}
+		if flaky[i].Package != flaky[j].Package {
+			return flaky[i].Package < flaky[j].Package
+		}
+		return flaky[i].Name < flaky[j].Name
+	})
+	return flaky, nil
+}
+
+// StressTest runs a single test count times in one `go test -count` invocation,
+// with the race detector and build tags of the current run, and reports how
+// often it failed. Progress is pushed as "stress" events. It waits for a run
+// in progress, and stops when ctx is cancelled or the project is removed. The
+// run's hang watchdog bounds the whole invocation.
+func (td *TestDashboard) StressTest(ctx context.Context, pkg, test string, count int) StressResult {
+	result := StressResult{Package: pkg, Test: test, Count: count}
+	if count < 1 {
+		result.Error = "count must be at least 1"
+		return result
+	}
+	resolved, err := td.resolvePackages(pkg)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	pkg = resolved[0]
+	result.Package = pkg
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-td.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	td.lockRun("stress")
+	defer td.runMu.Unlock()
+	if ctx.Err() != nil {
+		result.Error = "stress run cancelled"
+		return result
+	}
+
+	opts := td.runOptions()
+	args := []string{"test", "-json", fmt.Sprintf("-count=%d", count), "-timeout=0", "-run", testRunPattern(test)}
+	if opts.Race {
+		args = append(args, "-race")
+	}
+	if opts.Tags != "" {
+		args = append(args, "-tags="+opts.Tags)
+	}
+	cmd := td.projectCtx().goCommand(pkg, args...)
+	watcher := &stressWatcher{td: td, result: &result, start: time.Now()}
+	cmd.Stdout = watcher
+	stream, hung, err := td.runWatched(ctx, cmd, pkg, opts.timeout())
+	result.Duration = time.Since(watcher.start)
+	watcher.notify("finished")
+
+	switch {
+	case hung:
+		result.TimedOut = true
+		result.Error = fmt.Sprintf("timed out after %s with %d of %d runs done", opts.timeout(), result.Runs, count)
+		output, _ := parseTestJSON(stream)
+		result.FailureOutput = output
+		return result
+	case ctx.Err() != nil:
+		result.Error = "stress run cancelled"
+		return result
+	case result.Runs == 0:
+		output, _ := parseTestJSON(stream)
+		if err != nil && output == "" {
+			output = err.Error()
+		}
+		result.Error = "test did not run: " + strings.TrimSpace(output)
+		return result
+	}
+	result.FailureRate = float64(result.Failures) / float64(result.Runs)
+	return result
+}
+
+// stressProgressInterval is how often a stress run pushes its counts.
+const stressProgressInterval = time.Second
+
+// stressWatcher counts the outcomes of a stress run from its -json stream as
+// the stream arrives, pushing them as "stress" events.
+type stressWatcher struct {
+	td       *TestDashboard
+	result   *StressResult
+	start    time.Time
+	partial  []byte
+	current  strings.Builder // output of the repetition in progress
+	notified time.Time
+}
+
+func (w *stressWatcher) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	lines := w.partial
+	for {
+		i := bytes.IndexByte(lines, '\n')
+		if i == -1 {
+			break
+		}
+		scanTestEvents(lines[:i+1], w.observe)
+		lines = lines[i+1:]
+	}
+	w.partial = append(w.partial[:0], lines...)
+	if time.Since(w.notified) >= stressProgressInterval {
+		w.notify("running")
+	}
+	return len(p), nil
+}
+
+func (w *stressWatcher) observe(ev testEvent, raw []byte) {
+	if ev.Test != w.result.Test {
+		return
+	}
+	switch ev.Action {
+	case "run":
+		w.current.Reset()
+	case "output":
+		w.current.WriteString(ev.Output)
+	case "pass", "fail":
+		w.result.Runs++
+		if ev.Action == "fail" {
+			w.result.Failures++
+			if w.result.FailureOutput == "" {
+				w.result.FailureOutput = w.current.String()
+			}
+		}
+	}
+}
+
+func (w *stressWatcher) notify(status string) {
+	w.notified = time.Now()
+	w.td.Notify("stress", StressProgress{
+		Package:  w.result.Package,
+		Test:     w.result.Test,
+		Count:    w.result.Count,
+		Runs:     w.result.Runs,
+		Failures: w.result.Failures,
+		Elapsed:  time.Since(w.start),
+		Status:   status,
+	})
+}
+
+// testRunPattern builds a -run pattern that matches exactly one (sub)test.
+func testRunPattern(name string) string {
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		parts[i] = "^" + regexp.QuoteMeta(part) + "$"
+	}
+	return strings.Join(parts, "/")
+}
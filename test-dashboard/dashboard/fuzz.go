@@ -0,0 +1,363 @@
+package dashboard
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxFuzzOutputLines is how much of a fuzz session's output is kept for display.
+const maxFuzzOutputLines = 200
+
+// maxCorpusPreview caps how much of a corpus file is returned for display.
+const maxCorpusPreview = 4096
+
+var (
+	fuzzProgressRe = regexp.MustCompile(`^fuzz: elapsed: (\S+), execs: (\d+) \((\d+)/sec\), new interesting: (\d+) \(total: (\d+)\)`)
+	fuzzCrasherRe  = regexp.MustCompile(`Failing input written to (\S+)`)
+)
+
+// FuzzTarget is a FuzzXxx function found by `go test -list`.
+type FuzzTarget struct {
+	Package    string      `json:"package"`
+	Name       string      `json:"name"`
+	SeedCorpus int         `json:"seed_corpus"`
+	Session    *FuzzStatus `json:"session,omitempty"`
+}
+
+// FuzzStatus is the live state of a fuzzing session, pushed over the WebSocket
+// as "fuzz" events.
+type FuzzStatus struct {
+	Package        string    `json:"package"`
+	Target         string    `json:"target"`
+	State          string    `json:"state"` // "running", "stopped", "finished" or "failed"
+	StartedAt      time.Time `json:"started_at"`
+	FuzzTime       string    `json:"fuzz_time"`
+	Elapsed        string    `json:"elapsed"`
+	Execs          int64     `json:"execs"`
+	ExecsPerSec    int64     `json:"execs_per_sec"`
+	NewInteresting int       `json:"new_interesting"`
+	CorpusSize     int       `json:"corpus_size"`
+	Crasher        string    `json:"crasher,omitempty"` // corpus entry name under testdata/fuzz/<target>
+	CrasherInput   string    `json:"crasher_input,omitempty"`
+	Output         string    `json:"output,omitempty"`
+}
+
+// FuzzCorpusEntry is one file of a target's seed corpus in testdata/fuzz.
+type FuzzCorpusEntry struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mod_time"`
+	Content   string    `json:"content"`
+	Truncated bool      `json:"truncated,omitempty"`
+}
+
+// FuzzCorpus lists the seed corpus of a target plus the size of the generated
+// corpus the fuzzer keeps in the Go build cache.
+type FuzzCorpus struct {
+	Package       string            `json:"package"`
+	Target        string            `json:"target"`
+	Entries       []FuzzCorpusEntry `json:"entries"`
+	CachedEntries int               `json:"cached_entries"`
+}
+
+type fuzzSession struct {
+	mu     sync.Mutex
+	status FuzzStatus
+	lines  []string
+	cmd    *exec.Cmd
+}
+
+func (s *fuzzSession) snapshot() FuzzStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	status.Output = strings.Join(s.lines, "\n")
+	return status
+}
+
+func fuzzKey(pkg, target string) string {
+	return pkg + " " + target
+}
+
+// ListFuzzTargets returns the fuzz targets of every package that has tests,
+// together with the state of any session started for them.
+func (td *TestDashboard) ListFuzzTargets() ([]FuzzTarget, error) {
+	rc := td.projectCtx()
+	packages, err := rc.listPackages("")
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []FuzzTarget
+	for _, p := range packages {
+		if p.Error != nil || !p.HasTests() {
+			continue
+		}
+		dir := p.Dir
+		pkg := relPackagePath(rc.root, dir)
+		cmd := rc.goCommand(pkg, "test", "-list", "^Fuzz")
+		out, err := cmd.Output()
+		if err != nil {
+			log.Printf("Error listing fuzz targets in %s: %v", pkg, err)
+			continue
+		}
+		for _, line := range strings.Split(string(out), "\n") {
+			name := strings.TrimSpace(line)
+			if !strings.HasPrefix(name, "Fuzz") || strings.ContainsAny(name, " \t") {
+				continue
+			}
+			target := FuzzTarget{Package: pkg, Name: name}
+			if entries, err := os.ReadDir(filepath.Join(dir, "testdata", "fuzz", name)); err == nil {
+				target.SeedCorpus = len(entries)
+			}
+			if session := td.fuzzSession(pkg, name); session != nil {
+				status := session.snapshot()
+				status.Output = ""
+				target.Session = &status
+			}
+			targets = append(targets, target)
+		}
+	}
+	return targets, nil
+}
+
+func (td *TestDashboard) fuzzSession(pkg, target string) *fuzzSession {
+	td.fuzzMu.Lock()
+	defer td.fuzzMu.Unlock()
+	return td.fuzzSessions[fuzzKey(pkg, target)]
+}
+
+// StartFuzz starts fuzzing one target for the given duration. Only one session
+// per target can run at a time; progress is pushed as "fuzz" events.
+func (td *TestDashboard) StartFuzz(pkg, target string, fuzzTime time.Duration) (FuzzStatus, error) {
+	if !strings.HasPrefix(target, "Fuzz") {
+		return FuzzStatus{}, fmt.Errorf("%q is not a fuzz target", target)
+	}
+	if fuzzTime <= 0 {
+		return FuzzStatus{}, fmt.Errorf("fuzz time must be positive")
+	}
+	resolved, err := td.resolvePackages(pkg)
+	if err != nil {
+		return FuzzStatus{}, err
+	}
+	pkg = resolved[0]
+
+	td.fuzzMu.Lock()
+	defer td.fuzzMu.Unlock()
+	key := fuzzKey(pkg, target)
+	if existing := td.fuzzSessions[key]; existing != nil && existing.snapshot().State == "running" {
+		return FuzzStatus{}, fmt.Errorf("%s is already being fuzzed", target)
+	}
+
+	cmd := td.projectCtx().goCommand(pkg, "test", "-run", "^$", "-fuzz", "^"+regexp.QuoteMeta(target)+"$",
+		"-fuzztime", fuzzTime.String())
+	setProcessGroup(cmd)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return FuzzStatus{}, err
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		return FuzzStatus{}, fmt.Errorf("could not start fuzzing: %w", err)
+	}
+
+	session := &fuzzSession{
+		cmd: cmd,
+		status: FuzzStatus{
+			Package:   pkg,
+			Target:    target,
+			State:     "running",
+			StartedAt: time.Now(),
+			FuzzTime:  fuzzTime.String(),
+		},
+	}
+	if td.fuzzSessions == nil {
+		td.fuzzSessions = make(map[string]*fuzzSession)
+	}
+	td.fuzzSessions[key] = session
+	log.Printf("Fuzzing %s in %s for %s", target, pkg, fuzzTime)
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			if session.observe(scanner.Text()) {
+				td.Notify("fuzz", session.snapshot())
+			}
+		}
+		waitErr := cmd.Wait()
+
+		session.mu.Lock()
+		switch {
+		case session.status.State == "stopped":
+		case session.status.Crasher != "" || waitErr != nil:
+			session.status.State = "failed"
+		default:
+			session.status.State = "finished"
+		}
+		if session.status.Crasher != "" {
+			path := filepath.Join(td.ProjectPath, pkg, "testdata", "fuzz", target, session.status.Crasher)
+			if content, err := os.ReadFile(path); err == nil {
+				session.status.CrasherInput = string(content)
+			}
+		}
+		state := session.status.State
+		session.mu.Unlock()
+
+		log.Printf("Fuzzing %s in %s %s", target, pkg, state)
+		td.Notify("fuzz", session.snapshot())
+	}()
+
+	return session.snapshot(), nil
+}
+
+// observe records one line of fuzzer output and reports whether it changed
+// anything worth pushing to clients.
+func (s *fuzzSession) observe(line string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lines = append(s.lines, line)
+	if len(s.lines) > maxFuzzOutputLines {
+		s.lines = s.lines[len(s.lines)-maxFuzzOutputLines:]
+	}
+
+	if m := fuzzProgressRe.FindStringSubmatch(line); m != nil {
+		s.status.Elapsed = m[1]
+		s.status.Execs, _ = strconv.ParseInt(m[2], 10, 64)
+		s.status.ExecsPerSec, _ = strconv.ParseInt(m[3], 10, 64)
+		s.status.NewInteresting, _ = strconv.Atoi(m[4])
+		s.status.CorpusSize, _ = strconv.Atoi(m[5])
+		return true
+	}
+	if m := fuzzCrasherRe.FindStringSubmatch(line); m != nil {
+		s.status.Crasher = filepath.Base(m[1])
+		return true
+	}
+	return false
+}
+
+// StopFuzz interrupts a running fuzz session. The fuzzer shuts down cleanly and
+// keeps the corpus it built so far.
+func (td *TestDashboard) StopFuzz(pkg, target string) error {
+	session := td.fuzzSession(pkg, target)
+	if session == nil {
+		return fmt.Errorf("%s is not being fuzzed", target)
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.status.State != "running" {
+		return fmt.Errorf("%s is not being fuzzed", target)
+	}
+	session.status.State = "stopped"
+	return interruptProcessGroup(session.cmd)
+}
+
+// stopFuzzSessions kills every running fuzz session of the project, test
+// binaries included. A removed project has nobody left to stop them.
+func (td *TestDashboard) stopFuzzSessions() {
+	td.fuzzMu.Lock()
+	defer td.fuzzMu.Unlock()
+	for _, session := range td.fuzzSessions {
+		session.mu.Lock()
+		if session.status.State == "running" {
+			session.status.State = "stopped"
+			if err := killProcessGroup(session.cmd); err != nil {
+				log.Printf("Error stopping fuzzing of %s: %v", session.status.Target, err)
+			}
+		}
+		session.mu.Unlock()
+	}
+}
+
+// FuzzStatusOf returns the current (or last) session of a target.
+func (td *TestDashboard) FuzzStatusOf(pkg, target string) (FuzzStatus, bool) {
+	session := td.fuzzSession(pkg, target)
+	if session == nil {
+		return FuzzStatus{}, false
+	}
+	return session.snapshot(), true
+}
+
+// GetFuzzCorpus lists the seed corpus of a target from testdata/fuzz.
+func (td *TestDashboard) GetFuzzCorpus(pkg, target string) (FuzzCorpus, error) {
+	if !strings.HasPrefix(target, "Fuzz") || strings.ContainsAny(target, `/\.`) {
+		return FuzzCorpus{}, fmt.Errorf("%q is not a fuzz target", target)
+	}
+	resolved, err := td.resolvePackages(pkg)
+	if err != nil {
+		return FuzzCorpus{}, err
+	}
+	pkg = resolved[0]
+	corpus := FuzzCorpus{Package: pkg, Target: target}
+
+	dir := filepath.Join(td.ProjectPath, filepath.FromSlash(strings.TrimPrefix(pkg, "./")), "testdata", "fuzz", target)
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return corpus, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		item := FuzzCorpusEntry{Name: entry.Name(), Size: info.Size(), ModTime: info.ModTime()}
+		if content, err := os.ReadFile(filepath.Join(dir, entry.Name())); err == nil {
+			if len(content) > maxCorpusPreview {
+				content = content[:maxCorpusPreview]
+				item.Truncated = true
+			}
+			item.Content = string(content)
+		}
+		corpus.Entries = append(corpus.Entries, item)
+	}
+	// Newest first, so a fresh crasher is on top.
+	sort.Slice(corpus.Entries, func(i, j int) bool {
+		return corpus.Entries[i].ModTime.After(corpus.Entries[j].ModTime)
+	})
+
+	corpus.CachedEntries = td.cachedCorpusSize(pkg, target)
+	return corpus, nil
+}
+
+// cachedCorpusSize counts the inputs the fuzzer generated for a target, which
+// live in $GOCACHE/fuzz/<import path>/<target>.
+func (td *TestDashboard) cachedCorpusSize(pkg, target string) int {
+	cmd := td.projectCtx().goCommand(pkg, "list", "-f", "{{.ImportPath}}")
+	importPath, err := cmd.Output()
+	if err != nil {
+		return 0
+	}
+	cmd = exec.Command("go", "env", "GOCACHE")
+	cmd.Dir = td.ProjectPath
+	cacheDir, err := cmd.Output()
+	if err != nil {
+		return 0
+	}
+	dir := filepath.Join(strings.TrimSpace(string(cacheDir)), "fuzz",
+		filepath.FromSlash(strings.TrimSpace(string(importPath))), target)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	return len(entries)
+}
+
+// RerunFuzzInput runs a target against a single corpus entry, e.g. to check
+// whether a crasher still fails after a fix. It runs like a stress run of one.
+func (td *TestDashboard) RerunFuzzInput(ctx context.Context, pkg, target, input string) StressResult {
+	return td.StressTest(ctx, pkg, target+"/"+input, 1)
+}
@@ -0,0 +1,203 @@
+package dashboard
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// git runs a git command in dir and returns its trimmed stdout.
+func git(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// headCommit returns the full SHA of HEAD, or "" outside a git repository.
+func headCommit(dir string) string {
+	sha, err := git(dir, nil, "rev-parse", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return sha
+}
+
+// workingTreeHash identifies the working copy as it is on disk, including
+// uncommitted and untracked (but not ignored) files: two runs with the same hash
+// tested byte-identical code. A clean checkout hashes to HEAD's tree. Otherwise
+// the hash covers HEAD's tree, the diff against it and the untracked files, and
+// names no git object; nothing is written to the repository.
+func workingTreeHash(dir string) string {
+	top, err := git(dir, nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return ""
+	}
+	tree, err := git(top, nil, "rev-parse", "HEAD^{tree}")
+	if err != nil {
+		return ""
+	}
+
+	// The dashboard's own coverage artifacts live in the project directory for a
+	// while after each run; leave them out so they don't change the hash.
+	pathspec := []string{"--", ".", ":(exclude,glob)**/coverage_*.out", ":(exclude,glob)**/coverage_*.html"}
+	diff, err := git(top, nil, append([]string{"diff", "HEAD", "--binary", "--no-ext-diff", "--no-textconv"}, pathspec...)...)
+	if err != nil {
+		return ""
+	}
+	untracked, err := git(top, nil, append([]string{"ls-files", "-z", "--others", "--exclude-standard"}, pathspec...)...)
+	if err != nil {
+		return ""
+	}
+	if diff == "" && untracked == "" {
+		return tree
+	}
+
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\n%s\n", tree, diff)
+	if untracked != "" {
+		paths := strings.Split(strings.TrimRight(untracked, "\x00"), "\x00")
+		// hash-object without -w only computes the blob hashes.
+		cmd := exec.Command("git", "hash-object", "--no-filters", "--stdin-paths")
+		cmd.Dir = top
+		cmd.Stdin = strings.NewReader(strings.Join(paths, "\n") + "\n")
+		blobs, err := cmd.Output()
+		if err != nil {
+			return ""
+		}
+		fmt.Fprintf(h, "%s\x00%s", strings.Join(paths, "\x00"), blobs)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// GitInfo describes the code a run tested.
+type GitInfo struct {
+	Commit   string `json:"commit"`
+	Subject  string `json:"subject,omitempty"`
+	Branch   string `json:"branch,omitempty"` // "" for a detached HEAD
+	Dirty    bool   `json:"dirty"`            // the working copy differs from Commit
+	TreeHash string `json:"tree_hash,omitempty"`
+	Ref      string `json:"ref,omitempty"` // the ref a run was asked to test, if any
+}
+
+// gitInfo describes the checkout of the project at dir, or returns nil
+// outside a git repository.
+func gitInfo(dir string) *GitInfo {
+	commit := headCommit(dir)
+	if commit == "" {
+		return nil
+	}
+	info := &GitInfo{Commit: commit, TreeHash: workingTreeHash(dir)}
+	info.Subject, _ = git(dir, nil, "log", "-1", "--format=%s", commit)
+	info.Branch, _ = git(dir, nil, "symbolic-ref", "--short", "-q", "HEAD")
+	status, err := git(dir, nil, "status", "--porcelain", "--", ".",
+		":(exclude,glob)**/coverage_*.out", ":(exclude,glob)**/coverage_*.html")
+	info.Dirty = err == nil && status != ""
+	return info
+}
+
+// refInfo describes a commit that is tested in a worktree of its own.
+func refInfo(dir, ref, commit string) *GitInfo {
+	info := &GitInfo{Commit: commit, Ref: ref}
+	info.Subject, _ = git(dir, nil, "log", "-1", "--format=%s", commit)
+	info.TreeHash, _ = git(dir, nil, "rev-parse", commit+"^{tree}")
+	if name, err := git(dir, nil, "rev-parse", "--symbolic-full-name", ref); err == nil {
+		info.Branch = strings.TrimPrefix(name, "refs/heads/")
+		if info.Branch == name {
+			info.Branch = ""
+		}
+	}
+	return info
+}
+
+// resolveCommit returns the full SHA of the commit ref names.
+func resolveCommit(dir, ref string) (string, error) {
+	if strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("invalid ref %q", ref)
+	}
+	commit, err := git(dir, nil, "rev-parse", "--verify", "--quiet", "--end-of-options", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown ref %q", ref)
+	}
+	return commit, nil
+}
+
+// addWorktree checks commit out in a temporary, detached worktree and returns
+// the project's directory inside it, leaving the user's checkout alone. The
+// returned function removes the worktree again.
+func addWorktree(dir, commit string) (string, func(), error) {
+	prefix, err := git(dir, nil, "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", nil, err
+	}
+	tmp, err := os.MkdirTemp("", "azlo-worktree-*")
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := git(dir, nil, "worktree", "add", "--detach", "--force", tmp, commit); err != nil {
+		os.RemoveAll(tmp)
+		return "", nil, err
+	}
+	remove := func() {
+		if _, err := git(dir, nil, "worktree", "remove", "--force", tmp); err != nil {
+			log.Printf("Error removing worktree %s: %v", tmp, err)
+			os.RemoveAll(tmp)
+			git(dir, nil, "worktree", "prune")
+		}
+	}
+	return filepath.Join(tmp, filepath.FromSlash(prefix)), remove, nil
+}
+
+// worktreeCtx checks commit out in a temporary worktree and returns a context
+// for working in it, until the returned function removes the worktree.
+func (td *TestDashboard) worktreeCtx(commit string) (runCtx, func(), error) {
+	dir, remove, err := addWorktree(td.ProjectPath, commit)
+	if err != nil {
+		return runCtx{}, nil, err
+	}
+	return runCtx{root: dir, modules: discoverModules(dir)}, remove, nil
+}
+
+// ResolveRef checks that ref names a commit of the project's repository.
+func (td *TestDashboard) ResolveRef(ref string) (string, error) {
+	return resolveCommit(td.ProjectPath, ref)
+}
+
+// setGit records what code the run tests.
+func (r *RunRecord) setGit(info *GitInfo) {
+	if info == nil {
+		return
+	}
+	r.Commit, r.TreeHash, r.Subject = info.Commit, info.TreeHash, info.Subject
+	r.Branch, r.Dirty = info.Branch, info.Dirty
+}
+
+// Git describes the code the run tested, or returns nil for runs outside a
+// git repository.
+func (r *RunRecord) Git() *GitInfo {
+	if r.Commit == "" {
+		return nil
+	}
+	return &GitInfo{
+		Commit:   r.Commit,
+		Subject:  r.Subject,
+		Branch:   r.Branch,
+		Dirty:    r.Dirty,
+		TreeHash: r.TreeHash,
+		Ref:      r.Options.Ref,
+	}
+}
@@ -0,0 +1,272 @@
+package dashboard
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultHangTimeout is how long a package may run before it counts as hung.
+// It is below go test's own 10 minute default so the watchdog fires first.
+const defaultHangTimeout = 5 * time.Minute
+
+// progressInterval is how often a still running package is reported.
+const progressInterval = 5 * time.Second
+
+// quitGracePeriod is how long a hung test binary gets to write its goroutine
+// dump after SIGQUIT before the whole process group is killed.
+const quitGracePeriod = 10 * time.Second
+
+// PackageProgress is pushed over the WebSocket as "package" events while a
+// package's tests are running.
+type PackageProgress struct {
+	RunID   string        `json:"run_id,omitempty"`
+	Package string        `json:"package"`
+	Elapsed time.Duration `json:"elapsed"`
+	Timeout time.Duration `json:"timeout"`
+	Status  string        `json:"status"` // "running", "hung" or "finished"
+}
+
+// GoroutineDump is a goroutine dump grouped by identical stacks.
+type GoroutineDump struct {
+	Total  int              `json:"total"`
+	Groups []GoroutineGroup `json:"groups"`
+}
+
+// GoroutineGroup is a set of goroutines blocked in the same state on the same stack.
+type GoroutineGroup struct {
+	State     string       `json:"state"`
+	Count     int          `json:"count"`
+	IDs       []int        `json:"ids"`
+	WaitTime  string       `json:"wait_time,omitempty"` // longest wait, e.g. "5 minutes"
+	Frames    []StackFrame `json:"frames"`
+	CreatedBy *StackFrame  `json:"created_by,omitempty"`
+	System    bool         `json:"system"` // only runtime frames, e.g. the GC workers
+}
+
+// StackFrame is one call in a stack trace. Source is set when the file belongs
+// to the project, so the UI can open it in the source viewer.
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Source   string `json:"source,omitempty"`
+}
+
+// timeout returns the watchdog timeout selected by the options.
+func (o RunOptions) timeout() time.Duration {
+	if d, err := time.ParseDuration(o.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return defaultHangTimeout
+}
+
+// runWatched runs a go test command for pkg under a watchdog. While it runs,
+// progress is pushed every few seconds. Once the timeout passes, the test
+// binary gets SIGQUIT so it prints every goroutine's stack before exiting; if
+// it doesn't exit, the process group is killed. hung reports whether the
+// watchdog fired. If ctx is cancelled, the process group is killed and
+// ctx.Err() returned. Output is also copied to cmd.Stdout if it is set.
+func (td *TestDashboard) runWatched(ctx context.Context, cmd *exec.Cmd, pkg string, timeout time.Duration) (output []byte, hung bool, err error) {
+	var buf bytes.Buffer
+	var out io.Writer = &buf
+	if cmd.Stdout != nil {
+		out = io.MultiWriter(&buf, cmd.Stdout)
+	}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, false, err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var runID string
+	if td.run != nil {
+		runID = td.run.ID
+	}
+	start := time.Now()
+	progress := func(status string) {
+		td.Notify("package", PackageProgress{RunID: runID, Package: pkg, Elapsed: time.Since(start), Timeout: timeout, Status: status})
+	}
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	var kill <-chan time.Time
+	cancelled := ctx.Done()
+
+	for {
+		select {
+		case err := <-done:
+			if hung || time.Since(start) >= progressInterval {
+				progress("finished")
+			}
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			return buf.Bytes(), hung, err
+		case <-cancelled:
+			cancelled = nil
+			log.Printf("Stopping %s: %v", pkg, ctx.Err())
+			killProcessGroup(cmd)
+		case <-ticker.C:
+			if !hung {
+				progress("running")
+			}
+		case <-deadline.C:
+			hung = true
+			log.Printf("Package %s still running after %s, requesting a goroutine dump", pkg, timeout)
+			progress("hung")
+			if err := quitProcessGroup(cmd); err != nil {
+				log.Printf("Error sending SIGQUIT to %s: %v", pkg, err)
+			}
+			kill = time.After(quitGracePeriod)
+		case <-kill:
+			log.Printf("Package %s did not exit after SIGQUIT, killing it", pkg)
+			killProcessGroup(cmd)
+		}
+	}
+}
+
+var (
+	goroutineHeaderRe = regexp.MustCompile(`^goroutine (\d+)(?: gp=\S+ m=\S+(?: mp=\S+)?)? \[([^\]]*)\]:$`)
+	stackFileRe       = regexp.MustCompile(`^\t(.+):(\d+)(?: \+0x[0-9a-f]+)?(?: fp=.*)?$`)
+	stackCallRe       = regexp.MustCompile(`^(.+)\(.*\)$`)
+	createdByRe       = regexp.MustCompile(`^created by (\S+)(?: in goroutine \d+)?$`)
+)
+
+// parseGoroutineDump extracts goroutine stacks from output, as printed on
+// SIGQUIT, a panic or a deadlock, and groups identical stacks. It returns nil
+// if the output holds no stacks.
+func (rc runCtx) parseGoroutineDump(output string) *GoroutineDump {
+	type goroutine struct {
+		id        int
+		state     string
+		wait      string
+		frames    []StackFrame
+		createdBy *StackFrame
+	}
+	var goroutines []*goroutine
+	var current *goroutine
+	var pending *StackFrame // a call waiting for its file:line
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := goroutineHeaderRe.FindStringSubmatch(line); m != nil {
+			id, _ := strconv.Atoi(m[1])
+			state, wait, _ := strings.Cut(m[2], ", ")
+			current = &goroutine{id: id, state: state, wait: wait}
+			goroutines = append(goroutines, current)
+			pending = nil
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			current, pending = nil, nil
+			continue
+		}
+		if m := stackFileRe.FindStringSubmatch(line); m != nil && pending != nil {
+			pending.File = m[1]
+			pending.Line, _ = strconv.Atoi(m[2])
+			pending.Source = rc.relPath(m[1])
+			pending = nil
+			continue
+		}
+		if m := createdByRe.FindStringSubmatch(line); m != nil {
+			current.createdBy = &StackFrame{Function: m[1]}
+			pending = current.createdBy
+			continue
+		}
+		if m := stackCallRe.FindStringSubmatch(line); m != nil {
+			current.frames = append(current.frames, StackFrame{Function: m[1]})
+			pending = &current.frames[len(current.frames)-1]
+		}
+	}
+	if len(goroutines) == 0 {
+		return nil
+	}
+
+	dump := &GoroutineDump{Total: len(goroutines)}
+	index := make(map[string]int)
+	for _, g := range goroutines {
+		var key strings.Builder
+		key.WriteString(g.state)
+		for _, f := range g.frames {
+			fmt.Fprintf(&key, "\n%s %s:%d", f.Function, f.File, f.Line)
+		}
+		i, ok := index[key.String()]
+		if !ok {
+			i = len(dump.Groups)
+			index[key.String()] = i
+			dump.Groups = append(dump.Groups, GoroutineGroup{
+				State:     g.state,
+				Frames:    g.frames,
+				CreatedBy: g.createdBy,
+				System:    isSystemStack(g.frames, g.createdBy),
+			})
+		}
+		group := &dump.Groups[i]
+		group.Count++
+		group.IDs = append(group.IDs, g.id)
+		if waitMinutes(g.wait) > waitMinutes(group.WaitTime) {
+			group.WaitTime = g.wait
+		}
+	}
+
+	// Biggest groups of the user's own goroutines first; runtime internals last.
+	sort.SliceStable(dump.Groups, func(i, j int) bool {
+		a, b := dump.Groups[i], dump.Groups[j]
+		if a.System != b.System {
+			return !a.System
+		}
+		return a.Count > b.Count
+	})
+	return dump
+}
+
+// isSystemStack reports whether a goroutine belongs to the runtime itself.
+func isSystemStack(frames []StackFrame, createdBy *StackFrame) bool {
+	if createdBy != nil && !isRuntimeFunction(createdBy.Function) {
+		return false
+	}
+	for _, f := range frames {
+		if !isRuntimeFunction(f.Function) {
+			return false
+		}
+	}
+	return true
+}
+
+func isRuntimeFunction(name string) bool {
+	return strings.HasPrefix(name, "runtime.") || strings.HasPrefix(name, "internal/")
+}
+
+// waitMinutes parses the wait annotation of a goroutine header, e.g.
+// "5 minutes" or "2 minutes, locked to thread".
+func waitMinutes(wait string) int {
+	for _, part := range strings.Split(wait, ", ") {
+		if n, _, ok := strings.Cut(part, " minute"); ok {
+			if v, err := strconv.Atoi(n); err == nil {
+				return v
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,166 @@
+package dashboard
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+// testdata/goroutine_dump.txt is the output of a test binary run with
+// GOTRACEBACK=system and -test.timeout 2s, built from this package in
+// /tmp/hangfix:
+//
+//	type Pool struct {
+//		mu   sync.Mutex
+//		jobs chan int
+//	}
+//
+//	func (p *Pool) worker() {
+//		for range p.jobs {
+//		}
+//	}
+//
+//	func (p *Pool) Start(n int) {
+//		for i := 0; i < n; i++ {
+//			go p.worker()
+//		}
+//	}
+//
+//	func (p *Pool) Flush() {
+//		p.mu.Lock()
+//		defer p.mu.Unlock()
+//	}
+//
+//	func TestDeadlock(t *testing.T) {
+//		p := &Pool{jobs: make(chan int)}
+//		p.Start(3)
+//		p.mu.Lock()
+//		go p.Flush()
+//		done := make(chan struct{})
+//		<-done
+//	}
+func TestParseGoroutineDump(t *testing.T) {
+	output, err := os.ReadFile("testdata/goroutine_dump.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc := runCtx{root: "/tmp/hangfix"}
+	dump := rc.parseGoroutineDump(string(output))
+	if dump == nil {
+		t.Fatal("parseGoroutineDump returned nil")
+	}
+	if dump.Total != 11 {
+		t.Errorf("total = %d; want 11", dump.Total)
+	}
+
+	type group struct {
+		State     string
+		IDs       []int
+		System    bool
+		CreatedBy string
+	}
+	var got []group
+	for _, g := range dump.Groups {
+		if g.Count != len(g.IDs) {
+			t.Errorf("group %v: count %d for %d IDs", g.IDs, g.Count, len(g.IDs))
+		}
+		createdBy := ""
+		if g.CreatedBy != nil {
+			createdBy = g.CreatedBy.Function
+		}
+		got = append(got, group{g.State, g.IDs, g.System, createdBy})
+	}
+	// The three workers share a stack and come first; the runtime's own
+	// goroutines come last.
+	want := []group{
+		{"chan receive", []int{7, 8, 9}, false, "example.com/hangfix.(*Pool).Start"},
+		{"running", []int{11}, false, "time.goFunc"},
+		{"chan receive", []int{1}, false, ""},
+		{"chan receive", []int{6}, false, "testing.(*T).Run"},
+		{"sync.Mutex.Lock", []int{10}, false, "example.com/hangfix.TestDeadlock"},
+		{"force gc (idle)", []int{2}, true, "runtime.init.7"},
+		{"GC sweep wait", []int{3}, true, "runtime.gcenable"},
+		{"GC scavenge wait", []int{4}, true, "runtime.gcenable"},
+		{"finalizer wait", []int{5}, true, "runtime.createfing"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("groups:\n got %+v\nwant %+v", got, want)
+	}
+
+	workers := dump.Groups[0]
+	wantFrames := []StackFrame{
+		{Function: "runtime.gopark", File: "/usr/local/go/src/runtime/proc.go", Line: 474},
+		{Function: "runtime.chanrecv", File: "/usr/local/go/src/runtime/chan.go", Line: 667},
+		{Function: "runtime.chanrecv2", File: "/usr/local/go/src/runtime/chan.go", Line: 514},
+		{Function: "example.com/hangfix.(*Pool).worker", File: "/tmp/hangfix/worker.go", Line: 11, Source: "worker.go"},
+		{Function: "example.com/hangfix.(*Pool).Start.gowrap1", File: "/tmp/hangfix/worker.go", Line: 17, Source: "worker.go"},
+		{Function: "runtime.goexit", File: "/usr/local/go/src/runtime/asm_amd64.s", Line: 1264},
+	}
+	if !reflect.DeepEqual(workers.Frames, wantFrames) {
+		t.Errorf("worker frames:\n got %+v\nwant %+v", workers.Frames, wantFrames)
+	}
+	wantCreatedBy := &StackFrame{Function: "example.com/hangfix.(*Pool).Start", File: "/tmp/hangfix/worker.go", Line: 17, Source: "worker.go"}
+	if !reflect.DeepEqual(workers.CreatedBy, wantCreatedBy) {
+		t.Errorf("worker created by = %+v; want %+v", workers.CreatedBy, wantCreatedBy)
+	}
+}
+
+func TestParseGoroutineDumpWaitTimes(t *testing.T) {
+	// Goroutines blocked for a minute or more carry the wait in their header;
+	// a group reports the longest one.
+	output := `goroutine 7 [chan receive, 2 minutes]:
+example.com/app.worker()
+	/src/app/worker.go:11 +0x25
+created by example.com/app.Start in goroutine 1
+	/src/app/worker.go:17 +0x1e
+
+goroutine 8 [chan receive, 6 minutes, locked to thread]:
+example.com/app.worker()
+	/src/app/worker.go:11 +0x25
+created by example.com/app.Start in goroutine 1
+	/src/app/worker.go:17 +0x1e
+
+goroutine 9 [chan receive]:
+example.com/app.worker()
+	/src/app/worker.go:11 +0x25
+created by example.com/app.Start in goroutine 1
+	/src/app/worker.go:17 +0x1e
+`
+	dump := runCtx{root: "/src/app"}.parseGoroutineDump(output)
+	if dump == nil || len(dump.Groups) != 1 {
+		t.Fatalf("parseGoroutineDump = %+v; want one group", dump)
+	}
+	if g := dump.Groups[0]; g.Count != 3 || g.WaitTime != "6 minutes, locked to thread" {
+		t.Errorf("group = %d goroutines waiting %q; want 3 waiting %q", g.Count, g.WaitTime, "6 minutes, locked to thread")
+	}
+}
+
+func TestParseGoroutineDumpWithoutStacks(t *testing.T) {
+	for _, output := range []string{
+		"",
+		"=== RUN   TestAdd\n--- PASS: TestAdd (0.00s)\nPASS\n",
+		"ok  \texample.com/app\t0.003s\n",
+	} {
+		if dump := (runCtx{root: "/src/app"}).parseGoroutineDump(output); dump != nil {
+			t.Errorf("parseGoroutineDump(%q) = %+v; want nil", output, dump)
+		}
+	}
+}
+
+func TestWaitMinutes(t *testing.T) {
+	tests := []struct {
+		wait string
+		want int
+	}{
+		{"", 0},
+		{"1 minutes", 1},
+		{"5 minutes", 5},
+		{"locked to thread", 0},
+		{"12 minutes, locked to thread", 12},
+	}
+	for _, tt := range tests {
+		if got := waitMinutes(tt.wait); got != tt.want {
+			t.Errorf("waitMinutes(%q) = %d; want %d", tt.wait, got, tt.want)
+		}
+	}
+}
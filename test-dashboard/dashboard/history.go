@@ -0,0 +1,319 @@
+package dashboard
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxStoredRuns caps how many runs are kept per project; the oldest are pruned.
+const maxStoredRuns = 100
+
+// RunRecord is a finished run as kept in the run history.
+type RunRecord struct {
+	ID         string        `json:"id"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Commit     string        `json:"commit,omitempty"`
+	TreeHash   string        `json:"tree_hash,omitempty"`
+	Subject    string        `json:"subject,omitempty"`
+	Branch     string        `json:"branch,omitempty"`
+	Dirty      bool          `json:"dirty,omitempty"`
+	Options    RunOptions    `json:"options"`
+	Data       DashboardData `json:"data"`
+}
+
+// RunSummary is the lightweight listing form of a RunRecord.
+type RunSummary struct {
+	ID              string    `json:"id"`
+	StartedAt       time.Time `json:"started_at"`
+	Commit          string    `json:"commit,omitempty"`
+	TreeHash        string    `json:"tree_hash,omitempty"`
+	Subject         string    `json:"subject,omitempty"`
+	Branch          string    `json:"branch,omitempty"`
+	Dirty           bool      `json:"dirty,omitempty"`
+	Ref             string    `json:"ref,omitempty"`
+	GatePassed      bool      `json:"gate_passed"`
+	OverallCoverage float64   `json:"overall_coverage"`
+	TotalTests      int       `json:"total_tests"`
+	PassedTests     int       `json:"passed_tests"`
+}
+
+// History stores run records as one JSON file per run in a directory.
+type History struct {
+	store recordStore
+}
+
+// NewHistory returns the history for a project, kept outside the project tree
+// so storing runs never shows up as a change in the user's working copy.
+// AZLO_DATA_DIR overrides the default location under the user cache directory.
+func NewHistory(projectPath string) *History {
+	dir := projectDataDir(projectPath)
+	return &History{store: recordStore{
+		Dir: filepath.Join(dir, "runs"),
+		OnPrune: func(id string) {
+			// Artifacts such as profiles go away together with their run.
+			os.RemoveAll(filepath.Join(dir, "artifacts", id))
+		},
+	}}
+}
+
+// dataRoot is where the dashboard keeps its data: $AZLO_DATA_DIR, or a
+// directory in the user's cache.
+func dataRoot() string {
+	if base := os.Getenv("AZLO_DATA_DIR"); base != "" {
+		return base
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+	return filepath.Join(cacheDir, "azlo-test-suite")
+}
+
+func projectDataDir(projectPath string) string {
+	sum := sha1.Sum([]byte(projectPath))
+	return filepath.Join(dataRoot(), fmt.Sprintf("%s-%x", filepath.Base(projectPath), sum[:4]))
+}
+
+// newRunID returns a sortable, URL-safe identifier for a run started at t.
+func newRunID(t time.Time) string {
+	return t.UTC().Format("20060102-150405.000")
+}
+
+// Save writes (or overwrites) a run and prunes the oldest runs beyond the cap.
+func (h *History) Save(run *RunRecord) error {
+	return h.store.save(run.ID, run)
+}
+
+// Load reads a single run by ID.
+func (h *History) Load(id string) (*RunRecord, error) {
+	var run RunRecord
+	if err := h.store.load(id, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// Runs loads every stored run, oldest first.
+func (h *History) Runs() ([]*RunRecord, error) {
+	ids, err := h.store.ids()
+	if err != nil {
+		return nil, err
+	}
+	var runs []*RunRecord
+	for _, id := range ids {
+		run, err := h.Load(id)
+		if err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// Summary returns the listing form of the run.
+func (run *RunRecord) Summary() RunSummary {
+	return RunSummary{
+		ID:              run.ID,
+		StartedAt:       run.StartedAt,
+		Commit:          run.Commit,
+		TreeHash:        run.TreeHash,
+		Subject:         run.Subject,
+		Branch:          run.Branch,
+		Dirty:           run.Dirty,
+		Ref:             run.Options.Ref,
+		GatePassed:      run.Data.GatePassed,
+		OverallCoverage: run.Data.OverallCoverage,
+		TotalTests:      run.Data.TotalTests,
+		PassedTests:     run.Data.PassedTests,
+	}
+}
+
+// List returns summaries of the stored runs, newest first.
+func (h *History) List() ([]RunSummary, error) {
+	runs, err := h.Runs()
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]RunSummary, 0, len(runs))
+	for i := len(runs) - 1; i >= 0; i-- {
+		summaries = append(summaries, runs[i].Summary())
+	}
+	return summaries, nil
+}
+
+// CommitSummary sums up the stored runs of one commit. Its figures come from
+// the latest run of the commit itself, or from the latest run with local
+// changes if the commit was never tested as is.
+type CommitSummary struct {
+	Commit          string    `json:"commit"`
+	Subject         string    `json:"subject,omitempty"`
+	Branch          string    `json:"branch,omitempty"`
+	Runs            int       `json:"runs"`
+	RunID           string    `json:"run_id"` // the run the figures come from
+	StartedAt       time.Time `json:"started_at"`
+	Dirty           bool      `json:"dirty"`
+	GatePassed      bool      `json:"gate_passed"`
+	OverallCoverage float64   `json:"overall_coverage"`
+	TotalTests      int       `json:"total_tests"`
+	PassedTests     int       `json:"passed_tests"`
+}
+
+// Commits groups the stored runs by commit, the most recently tested commit
+// first. Runs outside a git repository are left out.
+func (h *History) Commits() ([]CommitSummary, error) {
+	runs, err := h.List()
+	if err != nil {
+		return nil, err
+	}
+	commits := []CommitSummary{}
+	index := make(map[string]int)
+	for _, run := range runs {
+		if run.Commit == "" {
+			continue
+		}
+		i, ok := index[run.Commit]
+		if !ok {
+			i = len(commits)
+			index[run.Commit] = i
+			commits = append(commits, CommitSummary{Commit: run.Commit, Dirty: true})
+		}
+		c := &commits[i]
+		c.Runs++
+		if c.Dirty && (c.RunID == "" || !run.Dirty) {
+			c.RunID, c.StartedAt, c.Dirty = run.ID, run.StartedAt, run.Dirty
+			c.Subject, c.Branch = run.Subject, run.Branch
+			c.GatePassed, c.OverallCoverage = run.GatePassed, run.OverallCoverage
+			c.TotalTests, c.PassedTests = run.TotalTests, run.PassedTests
+		}
+	}
+	return commits, nil
+}
+
+// Latest returns the most recent stored run.
+func (h *History) Latest() (*RunRecord, error) {
+	ids, err := h.store.ids()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no stored runs")
+	}
+	return h.Load(ids[len(ids)-1])
+}
+
+// Previous returns the most recent stored run started before the run with the
+// given ID, or nil if there is none.
+func (h *History) Previous(id string) (*RunRecord, error) {
+	ids, err := h.store.ids()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(ids) - 1; i >= 0; i-- {
+		if ids[i] < id {
+			return h.Load(ids[i])
+		}
+	}
+	return nil, nil
+}
+
+// recordStore keeps JSON records in a directory, one file per ID. IDs sort
+// chronologically, so the oldest records are pruned first once the cap is hit.
+type recordStore struct {
+	Dir     string
+	OnPrune func(id string) // called for every record dropped by the cap
+	mu      sync.Mutex
+}
+
+func (s *recordStore) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.Contains(id, "..") {
+		return "", fmt.Errorf("invalid id %q", id)
+	}
+	return filepath.Join(s.Dir, id+".json"), nil
+}
+
+func (s *recordStore) save(id string, record interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return fmt.Errorf("could not create data directory: %w", err)
+	}
+	content, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("could not encode %s: %w", id, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o644); err != nil {
+		return fmt.Errorf("could not write %s: %w", id, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("could not store %s: %w", id, err)
+	}
+
+	ids, err := s.listIDs()
+	if err != nil {
+		return err
+	}
+	for len(ids) > maxStoredRuns {
+		os.Remove(filepath.Join(s.Dir, ids[0]+".json"))
+		if s.OnPrune != nil {
+			s.OnPrune(ids[0])
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+func (s *recordStore) load(id string, record interface{}) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%s not found: %w", id, err)
+	}
+	if err := json.Unmarshal(content, record); err != nil {
+		return fmt.Errorf("could not decode %s: %w", id, err)
+	}
+	return nil
+}
+
+// ids lists stored IDs in chronological order.
+func (s *recordStore) ids() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listIDs()
+}
+
+// listIDs is ids without locking. Callers must hold s.mu.
+func (s *recordStore) listIDs() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read data directory: %w", err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasSuffix(name, ".json") {
+			ids = append(ids, strings.TrimSuffix(name, ".json"))
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
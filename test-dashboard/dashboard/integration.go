@@ -0,0 +1,203 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// integrationProfile is the artifact the integration command's coverage is
+// converted into.
+const integrationProfile = "integration.cover.out"
+
+// IntegrationRun is the outcome of the integration command of a run.
+type IntegrationRun struct {
+	Command  string        `json:"command"`
+	Passed   bool          `json:"passed"`
+	TimedOut bool          `json:"timed_out,omitempty"`
+	Output   string        `json:"output"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`    // why no coverage could be collected
+	Artifact string        `json:"artifact,omitempty"` // the converted cover profile
+	Packages int           `json:"packages"`           // project packages it has coverage for
+	Coverage float64       `json:"coverage"`           // share of all statements it ran
+}
+
+// runIntegration runs the integration command with GOCOVERDIR pointed at a
+// directory of the run, converts what binaries built with -cover wrote there
+// into a text profile and merges it into the results.
+func (td *TestDashboard) runIntegration(rc runCtx, results []TestResult, opts RunOptions) *IntegrationRun {
+	run := &IntegrationRun{Command: opts.Integration}
+	dir := td.artifactDir(td.run.ID)
+	coverDir := filepath.Join(dir, "covdata")
+	if err := os.MkdirAll(coverDir, 0o755); err != nil {
+		run.Error = fmt.Sprintf("could not create GOCOVERDIR: %v", err)
+		return run
+	}
+	defer os.RemoveAll(coverDir)
+
+	log.Printf("Running integration command: %s", opts.Integration)
+	cmd := shellCommand(opts.Integration)
+	cmd.Dir = rc.root
+	cmd.Env = append(os.Environ(), "GOCOVERDIR="+coverDir)
+	start := time.Now()
+	output, hung, err := td.runWatched(context.Background(), cmd, "integration", opts.timeout())
+	run.Duration = time.Since(start)
+	run.Passed = err == nil && !hung
+	run.TimedOut = hung
+	run.Output = lastLines(string(output), 200)
+
+	if entries, _ := os.ReadDir(coverDir); len(entries) == 0 {
+		run.Error = "the command wrote no coverage data; build the binaries it runs with `go build -cover`"
+		return run
+	}
+	profilePath := filepath.Join(dir, integrationProfile)
+	convert := exec.Command("go", "tool", "covdata", "textfmt", "-i="+coverDir, "-o="+profilePath)
+	convert.Dir = rc.root
+	if out, err := convert.CombinedOutput(); err != nil {
+		run.Error = fmt.Sprintf("go tool covdata textfmt failed: %v\n%s", err, out)
+		return run
+	}
+	run.Artifact = integrationProfile
+
+	profile, err := readCoverProfile(profilePath)
+	if err != nil {
+		run.Error = fmt.Sprintf("could not read the integration profile: %v", err)
+		return run
+	}
+	run.Packages = rc.mergeIntegrationCoverage(results, profile)
+
+	var total, covered int
+	for _, r := range results {
+		total += r.Statements
+		for _, f := range r.Files {
+			for _, b := range f.Blocks {
+				if b.IntegrationCount > 0 {
+					covered += b.Statements
+				}
+			}
+		}
+	}
+	if total > 0 {
+		run.Coverage = 100 * float64(covered) / float64(total)
+	}
+	return run
+}
+
+// mergeIntegrationCoverage adds the blocks of an integration profile to the
+// results of the packages they belong to. Blocks the unit tests also reported
+// get their counts summed; the rest, including whole files and packages
+// without unit tests, are added. It returns how many packages were touched.
+func (rc runCtx) mergeIntegrationCoverage(results []TestResult, profile []profileBlock) int {
+	byPackage := make(map[string][]profileBlock)
+	for _, b := range profile {
+		pkg := path.Dir(b.File)
+		byPackage[pkg] = append(byPackage[pkg], b)
+	}
+
+	merged := 0
+	for i := range results {
+		r := &results[i]
+		blocks, ok := byPackage[r.ImportPath]
+		if !ok || r.ImportPath == "" {
+			continue
+		}
+		merged++
+
+		type position struct{ file, block int }
+		files := make(map[string]int)
+		index := make(map[string]position)
+		key := func(file string, startLine, startCol, endLine, endCol int) string {
+			return fmt.Sprintf("%s:%d.%d,%d.%d", file, startLine, startCol, endLine, endCol)
+		}
+		for fi, f := range r.Files {
+			files[f.Filename] = fi
+			for bi, b := range f.Blocks {
+				index[key(f.Filename, b.StartLine, b.StartCol, b.EndLine, b.EndCol)] = position{fi, bi}
+			}
+		}
+
+		for _, ib := range blocks {
+			if pos, ok := index[key(ib.File, ib.StartLine, ib.StartCol, ib.EndLine, ib.EndCol)]; ok {
+				b := &r.Files[pos.file].Blocks[pos.block]
+				b.Count += ib.Count
+				b.IntegrationCount += ib.Count
+				b.Covered = b.Count > 0
+				continue
+			}
+			fi, ok := files[ib.File]
+			if !ok {
+				content, err := os.ReadFile(rc.coverageFilePath(ib.File))
+				if err != nil {
+					log.Printf("Error reading file %s: %v", ib.File, err)
+					continue
+				}
+				r.Files = append(r.Files, FileCoverage{Filename: ib.File, Content: string(content)})
+				fi = len(r.Files) - 1
+				files[ib.File] = fi
+			}
+			r.Files[fi].Blocks = append(r.Files[fi].Blocks, CoverageBlock{
+				StartLine:        ib.StartLine,
+				EndLine:          ib.EndLine,
+				Count:            ib.Count,
+				Covered:          ib.Count > 0,
+				StartCol:         ib.StartCol,
+				EndCol:           ib.EndCol,
+				Statements:       ib.Statements,
+				IntegrationCount: ib.Count,
+			})
+		}
+
+		// Recount from the merged blocks, so untested packages trade their
+		// estimated statement count for the instrumented one.
+		r.UnitCoverage = r.Coverage
+		r.Statements, r.Covered = 0, 0
+		for fi := range r.Files {
+			f := &r.Files[fi]
+			f.Coverage = calculateFileCoverage(f.Blocks)
+			for _, b := range f.Blocks {
+				r.Statements += b.Statements
+				if b.Covered {
+					r.Covered += b.Statements
+				}
+			}
+		}
+		if r.Statements > 0 {
+			r.Coverage = 100 * float64(r.Covered) / float64(r.Statements)
+		}
+	}
+	return merged
+}
+
+// remergeIntegrationCoverage merges the current run's integration profile, if
+// it has one, into freshly re-run results.
+func (td *TestDashboard) remergeIntegrationCoverage(rc runCtx, results []TestResult) {
+	integration := td.Snapshot().Integration
+	if td.run == nil || integration == nil || integration.Artifact == "" {
+		return
+	}
+	profilePath, err := td.ArtifactPath(td.run.ID, integration.Artifact)
+	if err != nil {
+		return
+	}
+	profile, err := readCoverProfile(profilePath)
+	if err != nil {
+		log.Printf("Error reading the integration profile: %v", err)
+		return
+	}
+	rc.mergeIntegrationCoverage(results, profile)
+}
+
+// summary is a one-line description of an integration run for logs.
+func (run *IntegrationRun) summary() string {
+	if run.Error != "" {
+		return strings.SplitN(run.Error, "\n", 2)[0]
+	}
+	return fmt.Sprintf("%d packages, %.1f%% of statements", run.Packages, run.Coverage)
+}
@@ -0,0 +1,218 @@
+package dashboard
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the package duration
+// histogram.
+var durationBuckets = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// runMetrics are the counters of a project that can't be derived from its
+// current state, kept since the dashboard started.
+type runMetrics struct {
+	mu         sync.Mutex
+	runs       map[string]int        // runs started, by kind
+	durations  map[string]*histogram // package durations, by package
+	flaky      int                   // flaky tests in the history
+	flakyStale bool                  // a run was stored since flaky was counted
+}
+
+type histogram struct {
+	counts []int // per bucket, not cumulative; the last one is +Inf
+	count  int
+	sum    float64
+}
+
+func newRunMetrics() *runMetrics {
+	return &runMetrics{
+		runs:       make(map[string]int),
+		durations:  make(map[string]*histogram),
+		flakyStale: true,
+	}
+}
+
+// lockRun takes runMu for a run of the given kind ("full", "rerun",
+// "benchmark", "mutation", "bisect" or "stress"), counting it as queued while
+// it waits.
+func (td *TestDashboard) lockRun(kind string) {
+	td.queued.Add(1)
+	td.runMu.Lock()
+	td.queued.Add(-1)
+
+	td.metrics.mu.Lock()
+	td.metrics.runs[kind]++
+	td.metrics.mu.Unlock()
+}
+
+// observePackage records how long a package's tests took. Packages that were
+// not run, having no tests or failing to load, are left out.
+func (td *TestDashboard) observePackage(result TestResult) {
+	if result.Duration <= 0 || result.NoTests {
+		return
+	}
+	td.metrics.mu.Lock()
+	defer td.metrics.mu.Unlock()
+	h := td.metrics.durations[result.Package]
+	if h == nil {
+		h = &histogram{counts: make([]int, len(durationBuckets)+1)}
+		td.metrics.durations[result.Package] = h
+	}
+	seconds := result.Duration.Seconds()
+	i := sort.SearchFloat64s(durationBuckets, seconds)
+	h.counts[i]++
+	h.count++
+	h.sum += seconds
+}
+
+// flakyCount returns the number of flaky tests in the history, recounting
+// only after a new run was stored.
+func (td *TestDashboard) flakyCount() int {
+	td.metrics.mu.Lock()
+	stale := td.metrics.flakyStale
+	td.metrics.flakyStale = false
+	td.metrics.mu.Unlock()
+	if stale {
+		flaky, err := td.FlakyTests()
+		if err != nil {
+			log.Printf("Error counting flaky tests: %v", err)
+		}
+		count := 0
+		for _, ft := range flaky {
+			if ft.Flips > 0 {
+				count++
+			}
+		}
+		td.metrics.mu.Lock()
+		td.metrics.flaky = count
+		td.metrics.mu.Unlock()
+	}
+	td.metrics.mu.Lock()
+	defer td.metrics.mu.Unlock()
+	return td.metrics.flaky
+}
+
+// metricFamily collects the samples of one metric across projects, so its
+// HELP and TYPE lines are written once.
+type metricFamily struct {
+	name, help, kind string
+	samples          []string
+}
+
+func (f *metricFamily) add(name string, labels [][2]string, value float64) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, "%s=\"%s\"", l[0], escapeLabel(l[1]))
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	f.samples = append(f.samples, b.String())
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+// WriteMetrics writes the metrics of every registered project in the
+// Prometheus text exposition format.
+func (p *Projects) WriteMetrics(w io.Writer) error {
+	p.mu.Lock()
+	dashboards := make([]*TestDashboard, 0, len(p.order))
+	for _, id := range p.order {
+		dashboards = append(dashboards, p.projects[id])
+	}
+	p.mu.Unlock()
+
+	runs := &metricFamily{name: "azlo_runs_total", help: "Runs started since the dashboard started, by kind.", kind: "counter"}
+	running := &metricFamily{name: "azlo_run_in_progress", help: "Whether a full run or re-run is in progress.", kind: "gauge"}
+	queue := &metricFamily{name: "azlo_run_queue_depth", help: "Runs waiting for the project's current run to finish.", kind: "gauge"}
+	lastRun := &metricFamily{name: "azlo_last_run_timestamp_seconds", help: "When the last run's results were last updated.", kind: "gauge"}
+	passed := &metricFamily{name: "azlo_package_passed", help: "Whether the package passed in the last run (1) or failed (0).", kind: "gauge"}
+	durations := &metricFamily{name: "azlo_package_duration_seconds", help: "How long the tests of a package took.", kind: "histogram"}
+	pkgCoverage := &metricFamily{name: "azlo_package_coverage_percent", help: "Statement coverage of the package in the last run.", kind: "gauge"}
+	coverage := &metricFamily{name: "azlo_project_coverage_percent", help: "Statement coverage of the project in the last run.", kind: "gauge"}
+	gate := &metricFamily{name: "azlo_quality_gate_passed", help: "Whether the last run passed the quality gate.", kind: "gauge"}
+	flaky := &metricFamily{name: "azlo_flaky_tests", help: "Tests that flipped between pass and fail on unchanged code.", kind: "gauge"}
+	clients := &metricFamily{name: "azlo_websocket_clients", help: "Connected WebSocket clients.", kind: "gauge"}
+
+	for _, td := range dashboards {
+		project := [2]string{"project", td.ID}
+		td.mu.Lock()
+		data, connected := td.Data, len(td.Clients)
+		td.mu.Unlock()
+
+		td.metrics.mu.Lock()
+		for _, kind := range []string{"full", "rerun", "benchmark", "mutation", "bisect", "stress"} {
+			runs.add(runs.name, [][2]string{project, {"kind", kind}}, float64(td.metrics.runs[kind]))
+		}
+		packages := make([]string, 0, len(td.metrics.durations))
+		for pkg := range td.metrics.durations {
+			packages = append(packages, pkg)
+		}
+		sort.Strings(packages)
+		for _, pkg := range packages {
+			h := td.metrics.durations[pkg]
+			labels := [][2]string{project, {"package", pkg}}
+			cumulative := 0
+			for i, bound := range durationBuckets {
+				cumulative += h.counts[i]
+				durations.add(durations.name+"_bucket", append(labels, [2]string{"le", strconv.FormatFloat(bound, 'g', -1, 64)}), float64(cumulative))
+			}
+			durations.add(durations.name+"_bucket", append(labels, [2]string{"le", "+Inf"}), float64(h.count))
+			durations.add(durations.name+"_sum", labels, h.sum)
+			durations.add(durations.name+"_count", labels, float64(h.count))
+		}
+		td.metrics.mu.Unlock()
+
+		running.add(running.name, [][2]string{project}, boolMetric(td.running.Load()))
+		queue.add(queue.name, [][2]string{project}, float64(td.queued.Load()))
+		clients.add(clients.name, [][2]string{project}, float64(connected))
+		flaky.add(flaky.name, [][2]string{project}, float64(td.flakyCount()))
+
+		if len(data.Results) == 0 {
+			continue
+		}
+		lastRun.add(lastRun.name, [][2]string{project}, float64(data.LastRun.UnixNano())/float64(time.Second))
+		coverage.add(coverage.name, [][2]string{project}, data.OverallCoverage)
+		gate.add(gate.name, [][2]string{project}, boolMetric(data.GatePassed))
+		for _, r := range data.Results {
+			labels := [][2]string{project, {"package", r.Package}}
+			passed.add(passed.name, labels, boolMetric(r.Passed))
+			pkgCoverage.add(pkgCoverage.name, labels, r.Coverage)
+		}
+	}
+
+	for _, f := range []*metricFamily{runs, running, queue, lastRun, passed, durations, pkgCoverage, coverage, gate, flaky, clients} {
+		if len(f.samples) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s\n", f.name, f.help, f.name, f.kind, strings.Join(f.samples, "\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func boolMetric(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,225 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"io/fs"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Module is a Go module inside the project, found through go.work or a go.mod.
+type Module struct {
+	Path string `json:"path"` // module path, e.g. "example.com/tool"
+	Dir  string `json:"dir"`  // project-relative, slash-separated; "." for the root
+}
+
+// ModuleSummary groups the results of one module's packages.
+type ModuleSummary struct {
+	Path        string  `json:"path"`
+	Dir         string  `json:"dir"`
+	Packages    int     `json:"packages"`
+	PassedTests int     `json:"passed_tests"`
+	Coverage    float64 `json:"coverage"`
+}
+
+// runCtx is the tree a run works in: the project itself, or the project's
+// directory in a temporary worktree of a ref being tested. Runs pass it along
+// explicitly, so a worktree never leaks into anything else going on, such as
+// the source viewer or a fuzz session.
+type runCtx struct {
+	root    string
+	modules []Module // the modules below root
+}
+
+// projectCtx works in the project itself.
+func (td *TestDashboard) projectCtx() runCtx {
+	return runCtx{root: td.ProjectPath, modules: td.Modules()}
+}
+
+// Modules returns the modules of the project, discovering them on first use.
+func (td *TestDashboard) Modules() []Module {
+	td.modMu.Lock()
+	defer td.modMu.Unlock()
+	if td.modules == nil {
+		td.modules = discoverModules(td.ProjectPath)
+	}
+	return td.modules
+}
+
+// refreshModules rediscovers the modules, e.g. at the start of a run.
+func (td *TestDashboard) refreshModules() []Module {
+	td.modMu.Lock()
+	defer td.modMu.Unlock()
+	td.modules = discoverModules(td.ProjectPath)
+	return td.modules
+}
+
+// discoverModules lists the modules used by go.work, if there is one, and
+// every go.mod below root. Modules are sorted by directory, the root first.
+func discoverModules(root string) []Module {
+	dirs := make(map[string]bool)
+	if fileExists(filepath.Join(root, "go.work")) {
+		for _, dir := range workspaceModules(root) {
+			dirs[dir] = true
+		}
+	}
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			// The go command ignores these directories too.
+			if path != root && (name == "vendor" || name == "testdata" || name == "node_modules" ||
+				strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == "go.mod" {
+			dirs[filepath.Dir(path)] = true
+		}
+		return nil
+	})
+
+	modules := []Module{}
+	for dir := range dirs {
+		path, err := getModuleName(dir)
+		if err != nil {
+			log.Printf("Skipping module in %s: %v", dir, err)
+			continue
+		}
+		rel, err := filepath.Rel(root, dir)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue // a go.work may use modules outside the project
+		}
+		modules = append(modules, Module{Path: path, Dir: filepath.ToSlash(rel)})
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		if (modules[i].Dir == ".") != (modules[j].Dir == ".") {
+			return modules[i].Dir == "."
+		}
+		return modules[i].Dir < modules[j].Dir
+	})
+	return modules
+}
+
+// workspaceModules returns the absolute directories listed by go.work's use directives.
+func workspaceModules(root string) []string {
+	cmd := exec.Command("go", "work", "edit", "-json")
+	cmd.Dir = root
+	out, err := cmd.Output()
+	if err != nil {
+		log.Printf("Error reading go.work: %v", err)
+		return nil
+	}
+	var work struct {
+		Use []struct{ DiskPath string }
+	}
+	if err := json.Unmarshal(out, &work); err != nil {
+		log.Printf("Error decoding go.work: %v", err)
+		return nil
+	}
+	var dirs []string
+	for _, use := range work.Use {
+		dir := filepath.FromSlash(use.DiskPath)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+		dirs = append(dirs, filepath.Clean(dir))
+	}
+	return dirs
+}
+
+// moduleOf returns the innermost module containing a project-relative path,
+// or nil if none does.
+func moduleOf(modules []Module, rel string) *Module {
+	rel = strings.TrimPrefix(filepath.ToSlash(rel), "./")
+	var found *Module
+	depth := -1
+	for i, m := range modules {
+		d := len(m.Dir)
+		if m.Dir == "." {
+			d = 0
+		} else if rel != m.Dir && !strings.HasPrefix(rel, m.Dir+"/") {
+			continue
+		}
+		if d > depth {
+			found, depth = &modules[i], d
+		}
+	}
+	return found
+}
+
+// goCommand returns a go command for a project package, run from the root of
+// the module that contains it: outside a workspace, the go command only sees
+// packages of the module it runs in. The package, given project-relative like
+// "./sub/pkg", is appended after args in module-relative form.
+func (rc runCtx) goCommand(relPkg string, args ...string) *exec.Cmd {
+	dir, pkg := rc.root, relPkg
+	if m := moduleOf(rc.modules, relPkg); m != nil && m.Dir != "." {
+		dir = filepath.Join(rc.root, filepath.FromSlash(m.Dir))
+		pkg = "./" + strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(relPkg, "./"), m.Dir), "/")
+	}
+	cmd := exec.Command("go", append(args, pkg)...)
+	cmd.Dir = dir
+	return cmd
+}
+
+// coverageFilePath resolves a file name from a cover profile, which is an
+// import path such as "example.com/mod/pkg/file.go", to a file on disk using
+// the module whose path is the longest prefix.
+func (rc runCtx) coverageFilePath(filename string) string {
+	if filepath.IsAbs(filename) {
+		return filename
+	}
+	var best *Module
+	for i, m := range rc.modules {
+		if strings.HasPrefix(filename, m.Path+"/") && (best == nil || len(m.Path) > len(best.Path)) {
+			best = &rc.modules[i]
+		}
+	}
+	if best == nil {
+		return filepath.Join(rc.root, filepath.FromSlash(filename))
+	}
+	return filepath.Join(rc.root, filepath.FromSlash(best.Dir), filepath.FromSlash(strings.TrimPrefix(filename, best.Path+"/")))
+}
+
+// summarizeModules groups results by the module their package belongs to. It
+// returns nil for single-module projects, where grouping adds nothing.
+func summarizeModules(modules []Module, results []TestResult) []ModuleSummary {
+	if len(modules) < 2 {
+		return nil
+	}
+	var summaries []ModuleSummary
+	var grouped [][]TestResult
+	index := make(map[string]int)
+	for _, r := range results {
+		i, ok := index[r.Module]
+		if !ok {
+			i = len(summaries)
+			index[r.Module] = i
+			summary := ModuleSummary{Path: r.Module}
+			for _, m := range modules {
+				if m.Path == r.Module {
+					summary.Dir = m.Dir
+				}
+			}
+			summaries = append(summaries, summary)
+			grouped = append(grouped, nil)
+		}
+		s := &summaries[i]
+		s.Packages++
+		if r.Passed {
+			s.PassedTests++
+		}
+		grouped[i] = append(grouped[i], r)
+	}
+	for i := range summaries {
+		summaries[i].Coverage = statementCoverage(grouped[i])
+	}
+	return summaries
+}
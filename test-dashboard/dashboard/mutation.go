@@ -0,0 +1,453 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minMutantTimeout is the least time a mutant's tests get before the mutant
+// counts as killed by a timeout; mutants often turn loops infinite.
+const minMutantTimeout = 30 * time.Second
+
+// Mutant is a single small change to a package's source, tested on its own.
+type Mutant struct {
+	Package     string `json:"package"`
+	File        string `json:"file"` // project-relative
+	Line        int    `json:"line"`
+	Column      int    `json:"column"`
+	Operator    string `json:"operator"` // "conditional", "boundary", "arithmetic" or "remove-statement"
+	Original    string `json:"original"`
+	Replacement string `json:"replacement"`
+	Status      string `json:"status"` // "killed", "survived", "timeout" (counts as killed) or "invalid" (did not compile)
+	KilledBy    string `json:"killed_by,omitempty"`
+}
+
+// MutationPackage is the outcome of mutation testing one package.
+type MutationPackage struct {
+	Package  string  `json:"package"`
+	Mutants  int     `json:"mutants"`
+	Killed   int     `json:"killed"`
+	Survived int     `json:"survived"`
+	Invalid  int     `json:"invalid"`
+	Score    float64 `json:"score"`
+	Error    string  `json:"error,omitempty"` // why the package was skipped, e.g. failing tests
+}
+
+// MutationRun is a stored mutation testing run over one or more packages.
+type MutationRun struct {
+	ID         string            `json:"id"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Commit     string            `json:"commit,omitempty"`
+	TreeHash   string            `json:"tree_hash,omitempty"`
+	Packages   []MutationPackage `json:"packages"`
+	Mutants    []Mutant          `json:"mutants"`
+	Killed     int               `json:"killed"`
+	Survived   int               `json:"survived"`
+	Score      float64           `json:"score"` // killed / (killed + survived), in percent
+}
+
+// MutationOptions selects the packages a mutation run covers.
+type MutationOptions struct {
+	Packages  []string `json:"packages"`
+	Operators []string `json:"operators,omitempty"` // all operators if empty
+}
+
+// MutationProgress is pushed over the WebSocket while a mutation run is going.
+type MutationProgress struct {
+	RunID    string `json:"run_id"`
+	Package  string `json:"package,omitempty"`
+	Done     int    `json:"done"`
+	Total    int    `json:"total"`
+	Killed   int    `json:"killed"`
+	Survived int    `json:"survived"`
+	Status   string `json:"status"` // "running" or "finished"
+}
+
+// MutationHistory stores mutation runs.
+type MutationHistory struct {
+	store recordStore
+}
+
+// NewMutationHistory returns the mutation run store for a project.
+func NewMutationHistory(projectPath string) *MutationHistory {
+	return &MutationHistory{store: recordStore{Dir: filepath.Join(projectDataDir(projectPath), "mutations")}}
+}
+
+// Load reads a stored mutation run.
+func (mh *MutationHistory) Load(id string) (*MutationRun, error) {
+	var run MutationRun
+	if err := mh.store.load(id, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// List returns the stored mutation runs without their mutants, newest first.
+func (mh *MutationHistory) List() ([]MutationRun, error) {
+	ids, err := mh.store.ids()
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]MutationRun, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		run, err := mh.Load(ids[i])
+		if err != nil {
+			continue
+		}
+		run.Mutants = nil
+		runs = append(runs, *run)
+	}
+	return runs, nil
+}
+
+// mutationOperators maps each operator to the token replacements it makes.
+// Statement removal has no table; it applies to calls, assignments and ++/--.
+var mutationOperators = map[string]map[token.Token]token.Token{
+	"conditional": {
+		token.EQL: token.NEQ, token.NEQ: token.EQL,
+		token.LSS: token.GEQ, token.GEQ: token.LSS,
+		token.GTR: token.LEQ, token.LEQ: token.GTR,
+		token.LAND: token.LOR, token.LOR: token.LAND,
+	},
+	"boundary": {
+		token.LSS: token.LEQ, token.LEQ: token.LSS,
+		token.GTR: token.GEQ, token.GEQ: token.GTR,
+	},
+	"arithmetic": {
+		token.ADD: token.SUB, token.SUB: token.ADD,
+		token.MUL: token.QUO, token.QUO: token.MUL, token.REM: token.MUL,
+		token.ADD_ASSIGN: token.SUB_ASSIGN, token.SUB_ASSIGN: token.ADD_ASSIGN,
+		token.MUL_ASSIGN: token.QUO_ASSIGN, token.QUO_ASSIGN: token.MUL_ASSIGN,
+		token.INC: token.DEC, token.DEC: token.INC,
+	},
+	"remove-statement": nil,
+}
+
+// sourceEdit is a mutation as a byte range replacement in a file.
+type sourceEdit struct {
+	mutant         Mutant
+	offset, length int
+}
+
+// StartMutations validates the options, records a new mutation run and runs it
+// in the background. Like benchmarks, it waits for any test run to finish.
+func (td *TestDashboard) StartMutations(opts MutationOptions) (*MutationRun, error) {
+	if len(opts.Packages) == 0 {
+		return nil, fmt.Errorf("at least one package is required")
+	}
+	packages, err := td.resolvePackages(opts.Packages...)
+	if err != nil {
+		return nil, err
+	}
+	operators := make(map[string]bool)
+	for _, op := range opts.Operators {
+		if _, ok := mutationOperators[op]; !ok {
+			return nil, fmt.Errorf("unknown mutation operator %q", op)
+		}
+		operators[op] = true
+	}
+	if len(operators) == 0 {
+		for op := range mutationOperators {
+			operators[op] = true
+		}
+	}
+
+	startedAt := time.Now()
+	run := &MutationRun{ID: newRunID(startedAt), StartedAt: startedAt, Packages: []MutationPackage{}, Mutants: []Mutant{}}
+	started := *run
+	go td.runMutations(run, packages, operators)
+	return &started, nil
+}
+
+func (td *TestDashboard) runMutations(run *MutationRun, packages []string, operators map[string]bool) {
+	td.lockRun("mutation")
+	defer td.runMu.Unlock()
+
+	log.Printf("Mutation testing %d packages", len(packages))
+	run.Commit = headCommit(td.ProjectPath)
+	run.TreeHash = workingTreeHash(td.ProjectPath)
+
+	workDir, err := os.MkdirTemp("", "azlo-mutants-*")
+	if err != nil {
+		log.Printf("Error creating mutant directory: %v", err)
+		return
+	}
+	defer os.RemoveAll(workDir)
+
+	for _, pkg := range packages {
+		summary := MutationPackage{Package: pkg}
+		edits, timeout, err := td.mutationCandidates(pkg, operators, workDir)
+		if err != nil {
+			summary.Error = err.Error()
+			run.Packages = append(run.Packages, summary)
+			continue
+		}
+
+		progress := func(done int) {
+			td.Notify("mutation", MutationProgress{
+				RunID: run.ID, Package: pkg, Done: done, Total: len(edits),
+				Killed: summary.Killed, Survived: summary.Survived, Status: "running",
+			})
+		}
+		progress(0)
+
+		// Mutants compile and test independently; run a few at once.
+		workers := runtime.NumCPU() / 2
+		if workers < 1 {
+			workers = 1
+		}
+		mutants := make([]Mutant, len(edits))
+		next := make(chan int)
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		done := 0
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range next {
+					mutant := td.testMutant(pkg, edits[i], timeout, workDir, i)
+					mu.Lock()
+					mutants[i] = mutant
+					done++
+					switch mutant.Status {
+					case "killed", "timeout":
+						summary.Killed++
+					case "survived":
+						summary.Survived++
+					default:
+						summary.Invalid++
+					}
+					progress(done)
+					mu.Unlock()
+				}
+			}()
+		}
+		for i := range edits {
+			next <- i
+		}
+		close(next)
+		wg.Wait()
+
+		summary.Mutants = len(mutants)
+		summary.Score = mutationScore(summary.Killed, summary.Survived)
+		run.Packages = append(run.Packages, summary)
+		run.Mutants = append(run.Mutants, mutants...)
+		run.Killed += summary.Killed
+		run.Survived += summary.Survived
+	}
+	run.Score = mutationScore(run.Killed, run.Survived)
+	run.FinishedAt = time.Now()
+
+	if err := td.Mutations.store.save(run.ID, run); err != nil {
+		log.Printf("Error storing mutation run %s: %v", run.ID, err)
+	}
+	td.Notify("mutation", MutationProgress{RunID: run.ID, Done: len(run.Mutants), Total: len(run.Mutants),
+		Killed: run.Killed, Survived: run.Survived, Status: "finished"})
+	log.Printf("Mutation run %s complete: score %.1f%% (%d killed, %d survived)", run.ID, run.Score, run.Killed, run.Survived)
+}
+
+func mutationScore(killed, survived int) float64 {
+	if killed+survived == 0 {
+		return 0
+	}
+	return float64(killed) / float64(killed+survived) * 100
+}
+
+// mutationCandidates runs the package's tests once with coverage and collects
+// the mutations of every covered line. It also returns how long a mutant's
+// tests may run, derived from the unmutated run. The tests must pass unmutated.
+func (td *TestDashboard) mutationCandidates(pkg string, operators map[string]bool, workDir string) ([]sourceEdit, time.Duration, error) {
+	profile := filepath.Join(workDir, artifactBase(pkg)+".cover")
+	defer os.Remove(profile)
+	rc := td.projectCtx()
+	start := time.Now()
+	cmd := rc.goCommand(pkg, "test", "-count=1", "-coverprofile="+profile)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, 0, fmt.Errorf("tests must pass before mutating: %s", lastLines(string(out), 5))
+	}
+	timeout := 10 * time.Since(start)
+	if timeout < minMutantTimeout {
+		timeout = minMutantTimeout
+	}
+
+	blocks, err := readCoverProfile(profile)
+	if err != nil {
+		return nil, 0, fmt.Errorf("no coverage for %s: %w", pkg, err)
+	}
+	covered := make(map[string]map[int]bool) // absolute path -> covered lines
+	for _, b := range blocks {
+		path := rc.coverageFilePath(b.File)
+		if covered[path] == nil {
+			covered[path] = make(map[int]bool)
+		}
+		if b.Count > 0 {
+			for line := b.StartLine; line <= b.EndLine; line++ {
+				covered[path][line] = true
+			}
+		}
+	}
+
+	var files []string
+	for path := range covered {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+	var edits []sourceEdit
+	for _, path := range files {
+		fileEdits, err := td.fileMutations(pkg, path, covered[path], operators)
+		if err != nil {
+			log.Printf("Skipping %s for mutation: %v", path, err)
+			continue
+		}
+		edits = append(edits, fileEdits...)
+	}
+	return edits, timeout, nil
+}
+
+// fileMutations lists the mutations operators allow on the covered lines of a file.
+func (td *TestDashboard) fileMutations(pkg, path string, covered map[int]bool, operators map[string]bool) ([]sourceEdit, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	rel := td.projectCtx().relPath(path)
+
+	var edits []sourceEdit
+	add := func(operator string, pos, end token.Pos, replacement string) {
+		start := fset.Position(pos)
+		if !covered[start.Line] {
+			return
+		}
+		offset, length := start.Offset, fset.Position(end).Offset-start.Offset
+		edits = append(edits, sourceEdit{
+			mutant: Mutant{
+				Package: pkg, File: rel, Line: start.Line, Column: start.Column, Operator: operator,
+				Original: string(src[offset : offset+length]), Replacement: replacement,
+			},
+			offset: offset, length: length,
+		})
+	}
+	swap := func(op token.Token, pos token.Pos) {
+		for _, operator := range []string{"conditional", "boundary", "arithmetic"} {
+			if to, ok := mutationOperators[operator][op]; ok && operators[operator] {
+				add(operator, pos, pos+token.Pos(len(op.String())), to.String())
+			}
+		}
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BinaryExpr:
+			if n.Op == token.ADD && (isStringLit(n.X) || isStringLit(n.Y)) {
+				return true // string concatenation has no subtraction
+			}
+			swap(n.Op, n.OpPos)
+		case *ast.AssignStmt:
+			swap(n.Tok, n.TokPos)
+		case *ast.IncDecStmt:
+			swap(n.Tok, n.TokPos)
+		}
+		if stmt, ok := n.(ast.Stmt); ok && operators["remove-statement"] && isRemovable(stmt) {
+			add("remove-statement", stmt.Pos(), stmt.End(), "")
+		}
+		return true
+	})
+	return edits, nil
+}
+
+func isStringLit(expr ast.Expr) bool {
+	lit, ok := expr.(*ast.BasicLit)
+	return ok && lit.Kind == token.STRING
+}
+
+// isRemovable reports whether deleting stmt leaves a program that is likely to
+// compile: calls, plain assignments and ++/--, but not declarations.
+func isRemovable(stmt ast.Stmt) bool {
+	switch s := stmt.(type) {
+	case *ast.ExprStmt:
+		_, ok := s.X.(*ast.CallExpr)
+		return ok
+	case *ast.AssignStmt:
+		return s.Tok != token.DEFINE
+	case *ast.IncDecStmt:
+		return true
+	}
+	return false
+}
+
+// testMutant writes a mutated copy of a file, runs the package tests against
+// it through an overlay and classifies the outcome.
+func (td *TestDashboard) testMutant(pkg string, edit sourceEdit, timeout time.Duration, workDir string, n int) Mutant {
+	mutant := edit.mutant
+	original := filepath.Join(td.ProjectPath, filepath.FromSlash(mutant.File))
+	src, err := os.ReadFile(original)
+	if err != nil {
+		mutant.Status = "invalid"
+		return mutant
+	}
+	mutated := make([]byte, 0, len(src)+len(mutant.Replacement))
+	mutated = append(mutated, src[:edit.offset]...)
+	mutated = append(mutated, mutant.Replacement...)
+	mutated = append(mutated, src[edit.offset+edit.length:]...)
+
+	base := filepath.Join(workDir, fmt.Sprintf("%s-%d", artifactBase(pkg), n))
+	overlay := base + ".json"
+	mutantFile := base + ".go"
+	defer os.Remove(overlay)
+	defer os.Remove(mutantFile)
+	spec, _ := json.Marshal(map[string]map[string]string{"Replace": {original: mutantFile}})
+	if os.WriteFile(mutantFile, mutated, 0o644) != nil || os.WriteFile(overlay, spec, 0o644) != nil {
+		mutant.Status = "invalid"
+		return mutant
+	}
+
+	cmd := td.projectCtx().goCommand(pkg, "test", "-json", "-count=1", "-failfast",
+		"-timeout="+timeout.String(), "-overlay="+overlay)
+	stream, err := cmd.CombinedOutput()
+	output, tests := parseTestJSON(stream)
+	switch {
+	case err == nil:
+		mutant.Status = "survived"
+	case strings.Contains(output, "panic: test timed out after"):
+		mutant.Status = "timeout"
+	default:
+		mutant.Status = "killed"
+		for _, tc := range tests {
+			if tc.Status == "fail" {
+				mutant.KilledBy = tc.Name
+				break
+			}
+		}
+		if mutant.KilledBy == "" && (strings.Contains(output, "[build failed]") || strings.Contains(output, "[setup failed]")) {
+			mutant.Status = "invalid"
+		}
+	}
+	return mutant
+}
+
+// lastLines returns the last n non-empty lines of s.
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
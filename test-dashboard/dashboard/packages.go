@@ -0,0 +1,281 @@
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GoPackage is a package as reported by `go list -json`.
+type GoPackage struct {
+	ImportPath   string            `json:"import_path"`
+	Dir          string            `json:"dir"` // absolute
+	Name         string            `json:"name"`
+	GoFiles      []string          `json:"go_files,omitempty"`
+	TestGoFiles  []string          `json:"test_go_files,omitempty"`
+	XTestGoFiles []string          `json:"xtest_go_files,omitempty"`
+	Error        *PackageLoadError `json:"error,omitempty"`
+}
+
+// PackageLoadError is why go list could not load a package.
+type PackageLoadError struct {
+	Pos string `json:"pos,omitempty"` // "file:line:col"
+	Err string `json:"err"`
+}
+
+// goListError is an error as go list reports it; Pos is relative to the
+// directory go list ran in.
+type goListError struct {
+	ImportStack []string
+	Pos         string
+	Err         string
+}
+
+// goListPackage mirrors the fields of `go list -json` output that are used.
+type goListPackage struct {
+	ImportPath   string
+	Dir          string
+	Name         string
+	GoFiles      []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+	Error        *goListError
+	DepsErrors   []*goListError
+}
+
+// HasTests reports whether the package has any _test.go files.
+func (p GoPackage) HasTests() bool {
+	return len(p.TestGoFiles) > 0 || len(p.XTestGoFiles) > 0
+}
+
+var (
+	buildTagsRe    = regexp.MustCompile(`^[A-Za-z0-9_.,]*$`)
+	loadErrorPosRe = regexp.MustCompile(`^(.+\.go):(\d+)(?::\d+)?$`)
+)
+
+// listPackages loads every package below the root with `go list -e -json ./...`
+// from each module root, honouring build tags. Packages that fail to load are
+// kept with their Error set; if go list fails as a whole for a module, the
+// module shows up as a single package carrying the error.
+func (rc runCtx) listPackages(tags string) ([]GoPackage, error) {
+	if !buildTagsRe.MatchString(tags) {
+		return nil, fmt.Errorf("invalid build tags %q", tags)
+	}
+	roots := []string{rc.root}
+	if len(rc.modules) > 0 {
+		roots = roots[:0]
+		for _, m := range rc.modules {
+			roots = append(roots, filepath.Join(rc.root, filepath.FromSlash(m.Dir)))
+		}
+	}
+
+	var packages []GoPackage
+	seen := make(map[string]bool)
+	for _, root := range roots {
+		args := []string{"list", "-e", "-json"}
+		if tags != "" {
+			args = append(args, "-tags="+tags)
+		}
+		cmd := exec.Command("go", append(args, "./...")...)
+		cmd.Dir = root
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			packages = append(packages, GoPackage{
+				ImportPath: filepath.Base(root),
+				Dir:        root,
+				Error:      &PackageLoadError{Err: strings.TrimSpace(stderr.String())},
+			})
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(out))
+		for {
+			var p goListPackage
+			if err := dec.Decode(&p); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("could not decode go list output: %w", err)
+			}
+			// In a workspace, a module root's ./... can reach into nested modules.
+			if seen[p.Dir] || rc.relPath(p.Dir) == "" {
+				continue
+			}
+			seen[p.Dir] = true
+			pkg := GoPackage{
+				ImportPath: p.ImportPath, Dir: p.Dir, Name: p.Name,
+				GoFiles: p.GoFiles, TestGoFiles: p.TestGoFiles, XTestGoFiles: p.XTestGoFiles,
+			}
+			loadErr := p.Error
+			for _, e := range p.DepsErrors {
+				// A bad import of the package itself, rather than deeper down.
+				if loadErr == nil && len(e.ImportStack) > 0 && e.ImportStack[len(e.ImportStack)-1] == p.ImportPath {
+					loadErr = e
+				}
+			}
+			if loadErr != nil {
+				pkg.Error = &PackageLoadError{Pos: loadErr.Pos, Err: loadErr.Err}
+				if loadErr.Pos != "" && !filepath.IsAbs(loadErr.Pos) {
+					pkg.Error.Pos = filepath.Join(root, loadErr.Pos)
+				}
+			}
+			packages = append(packages, pkg)
+		}
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Dir < packages[j].Dir })
+	return packages, nil
+}
+
+// resolvePackages checks that every name is a package of the project, given
+// project-relative as in "./sub/pkg", and returns them in the "./" form used on
+// go command lines. Names end up as arguments to go, so anything else, such as
+// "-exec=...", is refused rather than passed through.
+func (td *TestDashboard) resolvePackages(names ...string) ([]string, error) {
+	packages, err := td.projectCtx().listPackages("")
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]string, len(packages))
+	for _, p := range packages {
+		rel := relPackagePath(td.ProjectPath, p.Dir)
+		known[path.Clean(strings.TrimPrefix(rel, "./"))] = rel
+	}
+	resolved := make([]string, len(names))
+	for i, name := range names {
+		rel, ok := known[path.Clean(strings.TrimPrefix(name, "./"))]
+		if strings.HasPrefix(name, "-") || !ok {
+			return nil, fmt.Errorf("%q is not a package of the project", name)
+		}
+		resolved[i] = rel
+	}
+	return resolved, nil
+}
+
+// loadErrorResult reports a package that go list could not load as a failed
+// result, so a broken package doesn't silently drop out of the run.
+func (rc runCtx) loadErrorResult(pkg GoPackage) TestResult {
+	relPkg := relPackagePath(rc.root, pkg.Dir)
+	result := TestResult{
+		Package:    relPkg,
+		ImportPath: pkg.ImportPath,
+		Passed:     false,
+		Output:     fmt.Sprintf("%s\nFAIL\t%s [setup failed]\n", pkg.Error.Err, pkg.ImportPath),
+		Timestamp:  time.Now(),
+	}
+	if m := moduleOf(rc.modules, relPkg); m != nil {
+		result.Module = m.Path
+	}
+
+	record := Failure{Kind: "build", Message: pkg.Error.Err}
+	if m := loadErrorPosRe.FindStringSubmatch(pkg.Error.Pos); m != nil {
+		record.File = m[1]
+		record.Line, _ = strconv.Atoi(m[2])
+		record.Source = rc.relPath(m[1])
+	}
+	result.Failures = []TestFailure{{Records: []Failure{record}}}
+	return result
+}
+
+// untestedResult reports a package without test files. It is not run; it
+// counts as passed with no coverage.
+func (rc runCtx) untestedResult(pkg GoPackage) TestResult {
+	relPkg := relPackagePath(rc.root, pkg.Dir)
+	result := TestResult{
+		Package:    relPkg,
+		ImportPath: pkg.ImportPath,
+		Passed:     true,
+		NoTests:    true,
+		Output:     fmt.Sprintf("?   \t%s\t[no test files]\n", pkg.ImportPath),
+		Timestamp:  time.Now(),
+	}
+	if m := moduleOf(rc.modules, relPkg); m != nil {
+		result.Module = m.Path
+	}
+	return result
+}
+
+// countStatements approximates the number of statements the cover tool would
+// instrument in a package's non-test files: every statement of a block, case
+// or select clause, plus an "else if", which cover wraps in a block of its own.
+// Files that fail to parse still count as far as the parser got.
+func countStatements(pkg GoPackage) int {
+	fset := token.NewFileSet()
+	count := 0
+	for _, name := range pkg.GoFiles {
+		file, _ := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, parser.SkipObjectResolution)
+		if file == nil {
+			continue
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.BlockStmt:
+				for _, stmt := range n.List {
+					switch stmt.(type) {
+					case *ast.CaseClause, *ast.CommClause: // counted by their bodies
+					default:
+						count++
+					}
+				}
+			case *ast.CaseClause:
+				count += len(n.Body)
+			case *ast.CommClause:
+				count += len(n.Body)
+			case *ast.IfStmt:
+				if _, ok := n.Else.(*ast.IfStmt); ok {
+					count++
+				}
+			}
+			return true
+		})
+	}
+	return count
+}
+
+// profileStatements returns the number of statements in a cover profile and
+// how many of them ran.
+func profileStatements(profilePath string) (total, covered int) {
+	blocks, err := readCoverProfile(profilePath)
+	if err != nil {
+		return 0, 0
+	}
+	for _, b := range blocks {
+		total += b.Statements
+		if b.Count > 0 {
+			covered += b.Statements
+		}
+	}
+	return total, covered
+}
+
+// statementCoverage is the percentage of the results' statements that ran, so
+// a large untested package weighs more than a small tested one. Results that
+// predate statement counts fall back to the average package coverage.
+func statementCoverage(results []TestResult) float64 {
+	var total, covered int
+	var sum float64
+	for _, r := range results {
+		total += r.Statements
+		covered += r.Covered
+		sum += r.Coverage
+	}
+	if total > 0 {
+		return 100 * float64(covered) / float64(total)
+	}
+	if len(results) > 0 {
+		return sum / float64(len(results))
+	}
+	return 0
+}
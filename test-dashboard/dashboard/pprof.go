@@ -0,0 +1,363 @@
+package dashboard
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// This file decodes the pprof profile format (profile.proto, usually gzipped)
+// with a minimal protobuf reader, so profiles can be summarised without the
+// `go tool pprof` web UI or extra dependencies. Only the fields needed for flame
+// graphs and top tables are read.
+
+// Profile is the decoded subset of a pprof profile.
+type Profile struct {
+	SampleTypes       []ValueType
+	DefaultSampleType string
+	Samples           []profileSample
+	Locations         map[uint64]profileLocation
+	Functions         map[uint64]profileFunction
+	DurationNanos     int64
+}
+
+// ValueType names one of the values recorded per sample, e.g. cpu/nanoseconds.
+type ValueType struct {
+	Type string `json:"type"`
+	Unit string `json:"unit"`
+}
+
+type profileSample struct {
+	LocationIDs []uint64
+	Values      []int64
+}
+
+type profileLocation struct {
+	Lines []profileLine // innermost (inlined callee) first
+}
+
+type profileLine struct {
+	FunctionID uint64
+	Line       int64
+}
+
+type profileFunction struct {
+	Name     string
+	Filename string
+}
+
+// FlameNode is one frame in a flame graph; Value includes all children.
+type FlameNode struct {
+	Name     string       `json:"name"`
+	File     string       `json:"file,omitempty"`
+	Value    int64        `json:"value"`
+	Children []*FlameNode `json:"children,omitempty"`
+}
+
+// TopEntry is one row of a pprof-style top table.
+type TopEntry struct {
+	Function string `json:"function"`
+	File     string `json:"file,omitempty"`
+	Flat     int64  `json:"flat"`
+	Cum      int64  `json:"cum"`
+}
+
+// ProfileSummary is what the dashboard shows for a profile artifact.
+type ProfileSummary struct {
+	SampleTypes []ValueType `json:"sample_types"`
+	SampleType  ValueType   `json:"sample_type"`
+	Total       int64       `json:"total"`
+	Flame       *FlameNode  `json:"flame"`
+	Top         []TopEntry  `json:"top"`
+}
+
+// ParseProfile decodes a pprof profile, gzipped or not.
+func ParseProfile(data []byte) (*Profile, error) {
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("could not decompress profile: %w", err)
+		}
+		data, err = io.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("could not decompress profile: %w", err)
+		}
+	}
+
+	var (
+		stringTable    []string
+		rawSampleTypes [][2]int64
+		defaultType    int64
+		rawFunctions   = make(map[uint64][2]int64)
+	)
+	p := &Profile{
+		Locations: make(map[uint64]profileLocation),
+		Functions: make(map[uint64]profileFunction),
+	}
+
+	err := walkMessage(data, func(field int, wire int, v uint64, b []byte) error {
+		switch field {
+		case 1: // sample_type
+			var vt [2]int64
+			err := walkMessage(b, func(f, _ int, v uint64, _ []byte) error {
+				if f == 1 || f == 2 {
+					vt[f-1] = int64(v)
+				}
+				return nil
+			})
+			rawSampleTypes = append(rawSampleTypes, vt)
+			return err
+		case 2: // sample
+			var s profileSample
+			err := walkMessage(b, func(f, w int, v uint64, b []byte) error {
+				switch f {
+				case 1:
+					return appendPacked(w, v, b, func(x uint64) { s.LocationIDs = append(s.LocationIDs, x) })
+				case 2:
+					return appendPacked(w, v, b, func(x uint64) { s.Values = append(s.Values, int64(x)) })
+				}
+				return nil
+			})
+			p.Samples = append(p.Samples, s)
+			return err
+		case 4: // location
+			var id uint64
+			var loc profileLocation
+			err := walkMessage(b, func(f, _ int, v uint64, b []byte) error {
+				switch f {
+				case 1:
+					id = v
+				case 4:
+					var line profileLine
+					err := walkMessage(b, func(f, _ int, v uint64, _ []byte) error {
+						switch f {
+						case 1:
+							line.FunctionID = v
+						case 2:
+							line.Line = int64(v)
+						}
+						return nil
+					})
+					loc.Lines = append(loc.Lines, line)
+					return err
+				}
+				return nil
+			})
+			p.Locations[id] = loc
+			return err
+		case 5: // function
+			var id uint64
+			var fn [2]int64 // name, filename string indexes
+			err := walkMessage(b, func(f, _ int, v uint64, _ []byte) error {
+				switch f {
+				case 1:
+					id = v
+				case 2:
+					fn[0] = int64(v)
+				case 4:
+					fn[1] = int64(v)
+				}
+				return nil
+			})
+			rawFunctions[id] = fn
+			return err
+		case 6: // string_table
+			stringTable = append(stringTable, string(b))
+		case 10: // duration_nanos
+			p.DurationNanos = int64(v)
+		case 14: // default_sample_type
+			defaultType = int64(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("malformed profile: %w", err)
+	}
+
+	str := func(i int64) string {
+		if i < 0 || int(i) >= len(stringTable) {
+			return ""
+		}
+		return stringTable[i]
+	}
+	for _, vt := range rawSampleTypes {
+		p.SampleTypes = append(p.SampleTypes, ValueType{Type: str(vt[0]), Unit: str(vt[1])})
+	}
+	p.DefaultSampleType = str(defaultType)
+	for id, fn := range rawFunctions {
+		p.Functions[id] = profileFunction{Name: str(fn[0]), Filename: str(fn[1])}
+	}
+	return p, nil
+}
+
+// walkMessage calls fn for every field of a protobuf message. For varint and
+// fixed-width fields v holds the value; for length-delimited fields b holds
+// the bytes.
+func walkMessage(data []byte, fn func(field, wire int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("bad field key")
+		}
+		data = data[n:]
+		field, wire := int(key>>3), int(key&7)
+
+		var v uint64
+		var b []byte
+		switch wire {
+		case 0:
+			v, n = binary.Uvarint(data)
+			if n <= 0 {
+				return fmt.Errorf("bad varint in field %d", field)
+			}
+			data = data[n:]
+		case 1:
+			if len(data) < 8 {
+				return fmt.Errorf("truncated field %d", field)
+			}
+			v = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case 2:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return fmt.Errorf("truncated field %d", field)
+			}
+			b = data[n : n+int(length)]
+			data = data[n+int(length):]
+		case 5:
+			if len(data) < 4 {
+				return fmt.Errorf("truncated field %d", field)
+			}
+			v = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d in field %d", wire, field)
+		}
+		if err := fn(field, wire, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendPacked handles a repeated varint field that may be packed or not.
+func appendPacked(wire int, v uint64, b []byte, add func(uint64)) error {
+	if wire != 2 {
+		add(v)
+		return nil
+	}
+	for len(b) > 0 {
+		x, n := binary.Uvarint(b)
+		if n <= 0 {
+			return fmt.Errorf("bad packed varint")
+		}
+		add(x)
+		b = b[n:]
+	}
+	return nil
+}
+
+// Summarize builds a flame graph and a top table for one sample type. An empty
+// sampleType picks the profile's default (or its last sample type, like pprof).
+// Flame graph nodes below minFraction of the total are dropped to keep the
+// payload small.
+func (p *Profile) Summarize(sampleType string, topN int, minFraction float64) (*ProfileSummary, error) {
+	if len(p.SampleTypes) == 0 {
+		return nil, fmt.Errorf("profile has no sample types")
+	}
+	if sampleType == "" {
+		sampleType = p.DefaultSampleType
+	}
+	index := len(p.SampleTypes) - 1
+	for i, vt := range p.SampleTypes {
+		if vt.Type == sampleType {
+			index = i
+		}
+	}
+
+	summary := &ProfileSummary{SampleTypes: p.SampleTypes, SampleType: p.SampleTypes[index]}
+	root := &FlameNode{Name: "root"}
+	flat := make(map[string]int64)
+	cum := make(map[string]int64)
+	files := make(map[string]string)
+
+	for _, s := range p.Samples {
+		if index >= len(s.Values) || s.Values[index] == 0 {
+			continue
+		}
+		value := s.Values[index]
+		summary.Total += value
+
+		// Expand the stack root-first, including inlined frames.
+		var frames []profileFunction
+		for i := len(s.LocationIDs) - 1; i >= 0; i-- {
+			lines := p.Locations[s.LocationIDs[i]].Lines
+			for j := len(lines) - 1; j >= 0; j-- {
+				frames = append(frames, p.Functions[lines[j].FunctionID])
+			}
+		}
+
+		root.Value += value
+		node := root
+		seen := make(map[string]bool)
+		for _, fn := range frames {
+			node = node.child(fn)
+			node.Value += value
+			files[fn.Name] = fn.Filename
+			if !seen[fn.Name] {
+				seen[fn.Name] = true
+				cum[fn.Name] += value
+			}
+		}
+		if len(frames) > 0 {
+			flat[frames[len(frames)-1].Name] += value
+		}
+	}
+
+	root.prune(int64(float64(summary.Total) * minFraction))
+	summary.Flame = root
+
+	for name, c := range cum {
+		summary.Top = append(summary.Top, TopEntry{Function: name, File: files[name], Flat: flat[name], Cum: c})
+	}
+	sort.Slice(summary.Top, func(i, j int) bool {
+		if summary.Top[i].Flat != summary.Top[j].Flat {
+			return summary.Top[i].Flat > summary.Top[j].Flat
+		}
+		if summary.Top[i].Cum != summary.Top[j].Cum {
+			return summary.Top[i].Cum > summary.Top[j].Cum
+		}
+		return summary.Top[i].Function < summary.Top[j].Function
+	})
+	if topN > 0 && len(summary.Top) > topN {
+		summary.Top = summary.Top[:topN]
+	}
+	return summary, nil
+}
+
+func (n *FlameNode) child(fn profileFunction) *FlameNode {
+	for _, c := range n.Children {
+		if c.Name == fn.Name {
+			return c
+		}
+	}
+	c := &FlameNode{Name: fn.Name, File: fn.Filename}
+	n.Children = append(n.Children, c)
+	return c
+}
+
+// prune drops subtrees below min and orders children by value.
+func (n *FlameNode) prune(min int64) {
+	kept := n.Children[:0]
+	for _, c := range n.Children {
+		if c.Value >= min && c.Value > 0 {
+			c.prune(min)
+			kept = append(kept, c)
+		}
+	}
+	n.Children = kept
+	sort.Slice(n.Children, func(i, j int) bool { return n.Children[i].Value > n.Children[j].Value })
+}
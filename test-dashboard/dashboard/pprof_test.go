@@ -0,0 +1,226 @@
+package dashboard
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// testdata/cpu.pprof is a gzipped CPU profile of 60ms in which main.helper is
+// inlined into main.work. go tool pprof -top reports for it:
+//
+//	flat  flat%   sum%        cum   cum%
+//	30ms 50.00% 50.00%       40ms 66.67%  main.helper (inline)
+//	20ms 33.33% 83.33%       20ms 33.33%  main.other
+//	10ms 16.67%   100%       10ms 16.67%  runtime.memmove
+//	   0     0%   100%       60ms   100%  main.main
+//	   0     0%   100%       40ms 66.67%  main.work
+func readTestProfile(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/cpu.pprof")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseProfile(t *testing.T) {
+	gzipped := readTestProfile(t)
+	zr, err := gzip.NewReader(bytes.NewReader(gzipped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{"gzipped": gzipped, "uncompressed": raw} {
+		p, err := ParseProfile(data)
+		if err != nil {
+			t.Fatalf("%s: ParseProfile: %v", name, err)
+		}
+		wantTypes := []ValueType{{"samples", "count"}, {"cpu", "nanoseconds"}}
+		if !reflect.DeepEqual(p.SampleTypes, wantTypes) {
+			t.Errorf("%s: sample types = %v; want %v", name, p.SampleTypes, wantTypes)
+		}
+		if p.DurationNanos != 1500000000 {
+			t.Errorf("%s: duration = %d; want 1500000000", name, p.DurationNanos)
+		}
+		if len(p.Samples) != 5 {
+			t.Errorf("%s: %d samples; want 5", name, len(p.Samples))
+		}
+		wantLines := []profileLine{{FunctionID: 3, Line: 20}, {FunctionID: 2, Line: 31}}
+		if got := p.Locations[2].Lines; !reflect.DeepEqual(got, wantLines) {
+			t.Errorf("%s: inlined location lines = %v; want %v", name, got, wantLines)
+		}
+		wantFn := profileFunction{Name: "main.helper", Filename: "/src/app/work.go"}
+		if got := p.Functions[3]; got != wantFn {
+			t.Errorf("%s: function 3 = %v; want %v", name, got, wantFn)
+		}
+	}
+}
+
+func TestParseProfileMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"bad gzip", []byte{0x1f, 0x8b, 0x00}},
+		{"truncated string", []byte{0x32, 0x05, 'a', 'b'}},
+		{"bad nested sample", []byte{0x12, 0x02, 0x08, 0x80}},
+	}
+	for _, tt := range tests {
+		if _, err := ParseProfile(tt.data); err == nil {
+			t.Errorf("%s: ParseProfile succeeded; want an error", tt.name)
+		}
+	}
+}
+
+func TestWalkMessage(t *testing.T) {
+	type field struct {
+		Field, Wire int
+		V           uint64
+		B           string
+	}
+	tests := []struct {
+		name    string
+		data    []byte
+		want    []field
+		wantErr bool
+	}{
+		{"varint", []byte{0x08, 0x96, 0x01}, []field{{1, 0, 150, ""}}, false},
+		{"fixed64", []byte{0x11, 1, 0, 0, 0, 0, 0, 0, 0}, []field{{2, 1, 1, ""}}, false},
+		{"bytes", []byte{0x1a, 0x03, 'a', 'b', 'c'}, []field{{3, 2, 0, "abc"}}, false},
+		{"fixed32", []byte{0x25, 2, 0, 0, 0}, []field{{4, 5, 2, ""}}, false},
+		{"several fields", []byte{0x08, 0x01, 0x72, 0x00, 0x08, 0x02}, []field{{1, 0, 1, ""}, {14, 2, 0, ""}, {1, 0, 2, ""}}, false},
+		{"empty", nil, nil, false},
+		{"truncated varint", []byte{0x08, 0x80}, nil, true},
+		{"truncated fixed64", []byte{0x11, 1, 0}, nil, true},
+		{"truncated bytes", []byte{0x1a, 0x05, 'a'}, nil, true},
+		{"truncated fixed32", []byte{0x25, 1}, nil, true},
+		{"group wire type", []byte{0x0b}, nil, true},
+	}
+	for _, tt := range tests {
+		var got []field
+		err := walkMessage(tt.data, func(f, w int, v uint64, b []byte) error {
+			got = append(got, field{f, w, v, string(b)})
+			return nil
+		})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: walkMessage error = %v; want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: walkMessage fields = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAppendPacked(t *testing.T) {
+	tests := []struct {
+		name    string
+		wire    int
+		v       uint64
+		b       []byte
+		want    []uint64
+		wantErr bool
+	}{
+		{"unpacked", 0, 7, nil, []uint64{7}, false},
+		{"packed", 2, 0, []byte{0x01, 0x96, 0x01, 0x03}, []uint64{1, 150, 3}, false},
+		{"bad packed varint", 2, 0, []byte{0x01, 0x80}, []uint64{1}, true},
+	}
+	for _, tt := range tests {
+		var got []uint64
+		err := appendPacked(tt.wire, tt.v, tt.b, func(x uint64) { got = append(got, x) })
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: appendPacked = %v, %v; want %v, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	p, err := ParseProfile(readTestProfile(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const ms = 1000000
+	s, err := p.Summarize("", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.SampleType != (ValueType{"cpu", "nanoseconds"}) {
+		t.Errorf("default sample type = %v; want cpu/nanoseconds", s.SampleType)
+	}
+	if s.Total != 60*ms {
+		t.Errorf("total = %d; want %d", s.Total, 60*ms)
+	}
+	wantTop := []TopEntry{
+		{Function: "main.helper", File: "/src/app/work.go", Flat: 30 * ms, Cum: 40 * ms},
+		{Function: "main.other", File: "/src/app/other.go", Flat: 20 * ms, Cum: 20 * ms},
+		{Function: "runtime.memmove", File: "/usr/local/go/src/runtime/memmove_amd64.s", Flat: 10 * ms, Cum: 10 * ms},
+		{Function: "main.main", File: "/src/app/main.go", Flat: 0, Cum: 60 * ms},
+		{Function: "main.work", File: "/src/app/work.go", Flat: 0, Cum: 40 * ms},
+	}
+	if !reflect.DeepEqual(s.Top, wantTop) {
+		t.Errorf("top:\n got %+v\nwant %+v", s.Top, wantTop)
+	}
+
+	// The inlined main.helper is its own frame below main.work, and the
+	// recursive main.other counts once in the top table but twice in the
+	// flame graph.
+	const wantFlame = "root(60000000 main.main(60000000 main.work(40000000 main.helper(40000000 runtime.memmove(10000000))) main.other(20000000 main.other(10000000))))"
+	if got := flameString(s.Flame); got != wantFlame {
+		t.Errorf("flame graph:\n got %s\nwant %s", got, wantFlame)
+	} else if helper := s.Flame.Children[0].Children[0].Children[0]; helper.File != "/src/app/work.go" {
+		t.Errorf("inlined frame file = %q; want /src/app/work.go", helper.File)
+	}
+}
+
+func TestSummarizeOptions(t *testing.T) {
+	p, err := ParseProfile(readTestProfile(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := p.Summarize("samples", 2, 0.4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Total != 6 {
+		t.Errorf("samples total = %d; want 6", s.Total)
+	}
+	if len(s.Top) != 2 || s.Top[0].Function != "main.helper" || s.Top[1].Function != "main.other" {
+		t.Errorf("top 2 = %+v; want main.helper and main.other", s.Top)
+	}
+	// 40% of 6 samples rounds down to 2, so the frames with a single sample
+	// (runtime.memmove and the recursive main.other call) are pruned.
+	if got := flameString(s.Flame); got != "root(6 main.main(6 main.work(4 main.helper(4)) main.other(2)))" {
+		t.Errorf("pruned flame graph = %s", got)
+	}
+
+	if _, err := (&Profile{}).Summarize("", 0, 0); err == nil {
+		t.Error("Summarize of a profile without sample types succeeded; want an error")
+	}
+}
+
+// flameString renders a flame graph as name(value children...).
+func flameString(n *FlameNode) string {
+	var buf bytes.Buffer
+	var write func(n *FlameNode)
+	write = func(n *FlameNode) {
+		fmt.Fprintf(&buf, "%s(%d", n.Name, n.Value)
+		for _, c := range n.Children {
+			buf.WriteString(" ")
+			write(c)
+		}
+		buf.WriteString(")")
+	}
+	write(n)
+	return buf.String()
+}
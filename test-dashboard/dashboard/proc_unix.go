@@ -0,0 +1,45 @@
+//go:build !windows
+
+package dashboard
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts cmd in its own process group so that signals reach
+// the test binary `go test` spawns, not just the go command itself.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// interruptProcessGroup asks the whole process group of cmd to stop, the same
+// as pressing Ctrl-C in a terminal.
+func interruptProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGINT)
+}
+
+// quitProcessGroup sends SIGQUIT to the process group of cmd. The go command
+// ignores it while the test binary dumps all goroutine stacks and exits.
+func quitProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGQUIT)
+}
+
+// killProcessGroup kills cmd and everything it started.
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// shellCommand runs a user-supplied command line through the shell.
+func shellCommand(line string) *exec.Cmd {
+	return exec.Command("sh", "-c", line)
+}
@@ -0,0 +1,36 @@
+//go:build windows
+
+package dashboard
+
+import "os/exec"
+
+// setProcessGroup is a no-op on Windows.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// interruptProcessGroup kills the go command; Windows has no process-group
+// signals, so a test binary it started may linger until it exits by itself.
+func interruptProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+// quitProcessGroup kills the go command; Windows has no SIGQUIT, so no
+// goroutine dump is captured there.
+func quitProcessGroup(cmd *exec.Cmd) error {
+	return killProcessGroup(cmd)
+}
+
+// killProcessGroup kills the go command.
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+// shellCommand runs a user-supplied command line through cmd.exe.
+func shellCommand(line string) *exec.Cmd {
+	return exec.Command("cmd", "/C", line)
+}
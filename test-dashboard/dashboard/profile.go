@@ -0,0 +1,132 @@
+package dashboard
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RunOptions tweaks how `go test` is invoked for every package of a run.
+type RunOptions struct {
+	Profile string `json:"profile,omitempty"`  // "cpu", "mem", "block" or "mutex"
+	Trace   bool   `json:"trace,omitempty"`    // record an execution trace per package
+	Timeout string `json:"timeout,omitempty"`  // hang watchdog per package, e.g. "90s"; default 5m
+	Race    bool   `json:"race,omitempty"`     // build with the race detector
+	PerTest bool   `json:"per_test,omitempty"` // attribute coverage to each top-level test
+	Tags    string `json:"tags,omitempty"`     // comma-separated build tags for go list and go test
+	// Integration is the project's configured integration command (see
+	// ProjectConfig), recorded with the run. Its coverage is merged into the
+	// run. It is never taken from a request.
+	Integration string `json:"integration,omitempty"`
+	// Ref is a git ref, e.g. a branch, tag or SHA, to test in a temporary
+	// worktree instead of the working copy.
+	Ref string `json:"ref,omitempty"`
+}
+
+// Artifact is a file produced while testing a package, such as a profile. It
+// lives in the run's artifact directory and is removed with the run.
+type Artifact struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// profileFlags maps a profile kind to the go test flag that records it.
+var profileFlags = map[string]string{
+	"cpu":   "-cpuprofile",
+	"mem":   "-memprofile",
+	"block": "-blockprofile",
+	"mutex": "-mutexprofile",
+}
+
+// Validate rejects unknown options before a run starts.
+func (o RunOptions) Validate() error {
+	if o.Profile != "" {
+		if _, ok := profileFlags[o.Profile]; !ok {
+			return fmt.Errorf("unknown profile kind %q", o.Profile)
+		}
+	}
+	if !buildTagsRe.MatchString(o.Tags) {
+		return fmt.Errorf("invalid build tags %q", o.Tags)
+	}
+	if strings.HasPrefix(o.Ref, "-") || strings.ContainsAny(o.Ref, " \t\n") {
+		return fmt.Errorf("invalid ref %q", o.Ref)
+	}
+	if o.Timeout != "" {
+		if d, err := time.ParseDuration(o.Timeout); err != nil || d <= 0 {
+			return fmt.Errorf("invalid timeout %q", o.Timeout)
+		}
+	}
+	return nil
+}
+
+// artifactDir is where files produced by a run are kept.
+func (td *TestDashboard) artifactDir(runID string) string {
+	return filepath.Join(projectDataDir(td.ProjectPath), "artifacts", runID)
+}
+
+// artifactFlags returns the extra go test flags that make a package run write
+// its artifacts into dir, the artifacts to expect afterwards and the test
+// binary go test leaves behind, if any.
+func artifactFlags(opts RunOptions, relPkg, dir string) (flags []string, artifacts []Artifact, binary string) {
+	base := artifactBase(relPkg)
+
+	if flag, ok := profileFlags[opts.Profile]; ok {
+		name := fmt.Sprintf("%s-%s.pprof", base, opts.Profile)
+		flags = append(flags, flag+"="+filepath.Join(dir, name))
+		artifacts = append(artifacts, Artifact{Kind: opts.Profile, Name: name})
+	}
+	if opts.Trace {
+		name := base + ".trace"
+		flags = append(flags, "-trace="+filepath.Join(dir, name))
+		artifacts = append(artifacts, Artifact{Kind: "trace", Name: name})
+	}
+
+	if len(flags) > 0 {
+		// Profiling makes go test keep the test binary; keep it out of the
+		// project. Go profiles are already symbolized, so it is deleted afterwards.
+		binary = filepath.Join(dir, base+".test")
+		flags = append(flags, "-o", binary)
+	}
+	return flags, artifacts, binary
+}
+
+// artifactBase names the artifacts of a package after its directory.
+func artifactBase(relPkg string) string {
+	base := strings.Trim(strings.ReplaceAll(strings.TrimPrefix(relPkg, "./"), "/", "_"), "_")
+	if base == "" {
+		base = "root"
+	}
+	return base
+}
+
+// ArtifactPath resolves an artifact of a stored run to a file on disk.
+func (td *TestDashboard) ArtifactPath(runID, name string) (string, error) {
+	if strings.ContainsAny(runID+name, `/\`) || strings.Contains(runID+name, "..") || name == "" {
+		return "", fmt.Errorf("invalid artifact %s/%s", runID, name)
+	}
+	path := filepath.Join(td.artifactDir(runID), name)
+	if !fileExists(path) {
+		return "", fmt.Errorf("artifact %s/%s not found", runID, name)
+	}
+	return path, nil
+}
+
+// ProfileArtifact parses a stored profile artifact and summarises it for the
+// flame graph and top table.
+func (td *TestDashboard) ProfileArtifact(runID, name, sampleType string, topN int) (*ProfileSummary, error) {
+	path, err := td.ArtifactPath(runID, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read profile: %w", err)
+	}
+	profile, err := ParseProfile(data)
+	if err != nil {
+		return nil, err
+	}
+	return profile.Summarize(sampleType, topN, 0.001)
+}
@@ -0,0 +1,202 @@
+package dashboard
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// ProjectInfo is a project of the registry as the project switcher lists it.
+type ProjectInfo struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	Path            string    `json:"path"`
+	Default         bool      `json:"default"`
+	Running         bool      `json:"running"`
+	LastRun         time.Time `json:"last_run"`
+	OverallCoverage float64   `json:"overall_coverage"`
+	TotalTests      int       `json:"total_tests"`
+	PassedTests     int       `json:"passed_tests"`
+}
+
+// Projects is the registry of projects the dashboard manages. Every project has
+// its own TestDashboard, and with it its own runner, history and WebSocket
+// clients, so several projects can run tests at the same time.
+type Projects struct {
+	mu        sync.Mutex
+	projects  map[string]*TestDashboard
+	order     []string // IDs in the order they were added
+	defaultID string   // the project served by the routes without a /projects/{id} prefix
+	file      string   // where the registered paths are saved
+}
+
+var unsafeIDChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// projectID derives a stable, URL-safe ID from a project's path.
+func projectID(path string) string {
+	sum := sha1.Sum([]byte(path))
+	name := unsafeIDChars.ReplaceAllString(filepath.Base(path), "_")
+	return fmt.Sprintf("%s-%x", name, sum[:4])
+}
+
+// NewProjects loads the saved registry. The working directory becomes the
+// default project, as it was before projects could be registered.
+func NewProjects(workDir string) *Projects {
+	p := &Projects{
+		projects: make(map[string]*TestDashboard),
+		file:     filepath.Join(dataRoot(), "projects.json"),
+	}
+	var paths []string
+	if content, err := os.ReadFile(p.file); err == nil {
+		if err := json.Unmarshal(content, &paths); err != nil {
+			log.Printf("Error reading project registry %s: %v", p.file, err)
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.add(workDir)
+	p.defaultID = projectID(workDir)
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			log.Printf("Skipping registered project %s: %v", path, err)
+			continue
+		}
+		p.add(path)
+	}
+	return p
+}
+
+// add registers path, unless it already is, and starts its broadcaster.
+// Callers must hold p.mu.
+func (p *Projects) add(path string) *TestDashboard {
+	id := projectID(path)
+	if td, ok := p.projects[id]; ok {
+		return td
+	}
+	td := NewTestDashboard(path)
+	go td.BroadcastUpdates()
+	p.projects[id] = td
+	p.order = append(p.order, id)
+	return td
+}
+
+// Add registers the Go project at path and saves the registry. Adding a
+// project twice returns the existing one.
+func (p *Projects) Add(path string) (*TestDashboard, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("path does not exist: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("path is not a directory")
+	}
+	if !isGoProject(path) {
+		return nil, fmt.Errorf("directory does not appear to be a Go project (no go.mod or *.go files found)")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	td := p.add(path)
+	log.Printf("Project %s registered: %s", td.ID, path)
+	return td, p.save()
+}
+
+// Remove unregisters a project, disconnects its clients and stops its fuzz
+// sessions. A run in progress finishes in the background, after which the
+// project's HTML coverage files are deleted. The default project can't be
+// removed.
+func (p *Projects) Remove(id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	td, ok := p.projects[id]
+	if !ok {
+		return fmt.Errorf("project %s not found", id)
+	}
+	if id == p.defaultID {
+		return fmt.Errorf("the default project can't be removed")
+	}
+	delete(p.projects, id)
+	for i, other := range p.order {
+		if other == id {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+	close(td.quit)
+	td.shutdown()
+	return p.save()
+}
+
+// Get returns the project with the given ID, or nil.
+func (p *Projects) Get(id string) *TestDashboard {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.projects[id]
+}
+
+// Default returns the project served by the unprefixed routes.
+func (p *Projects) Default() *TestDashboard {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.projects[p.defaultID]
+}
+
+// SetDefault makes a registered project the default one.
+func (p *Projects) SetDefault(id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.projects[id]; !ok {
+		return fmt.Errorf("project %s not found", id)
+	}
+	p.defaultID = id
+	return nil
+}
+
+// List describes every registered project, in the order they were added.
+func (p *Projects) List() []ProjectInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	list := make([]ProjectInfo, 0, len(p.order))
+	for _, id := range p.order {
+		td := p.projects[id]
+		data := td.Snapshot()
+		list = append(list, ProjectInfo{
+			ID:              id,
+			Name:            filepath.Base(td.ProjectPath),
+			Path:            td.ProjectPath,
+			Default:         id == p.defaultID,
+			Running:         td.running.Load(),
+			LastRun:         data.LastRun,
+			OverallCoverage: data.OverallCoverage,
+			TotalTests:      data.TotalTests,
+			PassedTests:     data.PassedTests,
+		})
+	}
+	return list
+}
+
+// save writes the paths of the registered projects. Callers must hold p.mu.
+func (p *Projects) save() error {
+	paths := []string{}
+	for _, id := range p.order {
+		paths = append(paths, p.projects[id].ProjectPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(p.file), 0o755); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(paths, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p.file, content, 0o644)
+}
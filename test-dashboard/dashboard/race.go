@@ -0,0 +1,221 @@
+package dashboard
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DataRace is one report of the race detector. Reports whose accesses happen
+// at the same places are merged, so a race hit by several tests shows once.
+type DataRace struct {
+	Key      string       `json:"key"`
+	Accesses []RaceAccess `json:"accesses"` // the current access first, then the previous one
+	Tests    []string     `json:"tests,omitempty"`
+	Count    int          `json:"count"`
+}
+
+// RaceAccess is one of the two conflicting memory accesses of a data race.
+type RaceAccess struct {
+	Operation string       `json:"operation"` // e.g. "Read", "Previous write"
+	Goroutine int          `json:"goroutine"`
+	State     string       `json:"state,omitempty"` // "running" or "finished" when the report says so
+	Stack     []StackFrame `json:"stack"`
+	CreatedAt []StackFrame `json:"created_at,omitempty"` // where the goroutine was started
+}
+
+// RaceRunCount is the number of distinct races found by one stored run.
+type RaceRunCount struct {
+	RunID     string    `json:"run_id"`
+	StartedAt time.Time `json:"started_at"`
+	Commit    string    `json:"commit,omitempty"`
+	Races     int       `json:"races"`
+}
+
+// RaceOccurrence tracks a distinct race across stored runs.
+type RaceOccurrence struct {
+	Race      DataRace  `json:"race"`
+	Runs      int       `json:"runs"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// RaceHistory is the race detector's view of the run history.
+type RaceHistory struct {
+	Runs  []RaceRunCount   `json:"runs"` // oldest first; only runs made with -race
+	Races []RaceOccurrence `json:"races"`
+}
+
+var (
+	raceAccessRe  = regexp.MustCompile(`^((?:Previous )?(?:[Rr]ead|[Ww]rite|[Aa]tomic read|[Aa]tomic write))(?: at 0x[0-9a-f]+)? by (?:goroutine (\d+)|main goroutine):$`)
+	raceCreatedRe = regexp.MustCompile(`^Goroutine (\d+) \((\w+)\) created at:$`)
+	raceFuncRe    = regexp.MustCompile(`^  (\S.*)\(\)$`)
+	raceFileRe    = regexp.MustCompile(`^      (.+\.go):(\d+)(?: \+0x[0-9a-f]+)?$`)
+)
+
+const raceSeparator = "=================="
+
+// parseRaces extracts the race reports of a package run, attributing each one
+// to the test whose output contains it.
+func (rc runCtx) parseRaces(output string, tests []TestCase) []DataRace {
+	var races []DataRace
+	index := make(map[string]int)
+	add := func(test string, race DataRace) {
+		i, ok := index[race.Key]
+		if !ok {
+			i = len(races)
+			index[race.Key] = i
+			races = append(races, race)
+		}
+		races[i].Count++
+		if test != "" && !containsString(races[i].Tests, test) {
+			races[i].Tests = append(races[i].Tests, test)
+		}
+	}
+
+	found := false
+	for _, tc := range tests {
+		for _, race := range rc.parseRaceReports(tc.Output) {
+			add(tc.Name, race)
+			found = true
+		}
+	}
+	if !found {
+		// Races outside any test, e.g. in TestMain or after a test returned.
+		for _, race := range rc.parseRaceReports(output) {
+			add("", race)
+		}
+	}
+	return races
+}
+
+// parseRaceReports parses every "WARNING: DATA RACE" block in output. A report
+// looks like
+//
+//	Read at 0x00c0000182a8 by goroutine 8:
+//	  example.com/pkg.(*counter).inc()
+//	      /src/pkg/counter.go:10 +0x7e
+//
+//	Previous write at 0x00c0000182a8 by goroutine 9:
+//	  ...
+//
+//	Goroutine 8 (running) created at:
+//	  ...
+func (rc runCtx) parseRaceReports(output string) []DataRace {
+	var races []DataRace
+	var race *DataRace
+	var stack *[]StackFrame
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "WARNING: DATA RACE":
+			races = append(races, DataRace{})
+			race = &races[len(races)-1]
+			stack = nil
+		case race == nil:
+		case line == raceSeparator:
+			race, stack = nil, nil
+		default:
+			if m := raceAccessRe.FindStringSubmatch(line); m != nil {
+				goroutine, _ := strconv.Atoi(m[2]) // 0 for the main goroutine
+				race.Accesses = append(race.Accesses, RaceAccess{Operation: m[1], Goroutine: goroutine})
+				stack = &race.Accesses[len(race.Accesses)-1].Stack
+			} else if m := raceCreatedRe.FindStringSubmatch(line); m != nil {
+				goroutine, _ := strconv.Atoi(m[1])
+				stack = nil
+				for i := range race.Accesses {
+					if race.Accesses[i].Goroutine == goroutine && race.Accesses[i].CreatedAt == nil {
+						race.Accesses[i].State = m[2]
+						stack = &race.Accesses[i].CreatedAt
+						break
+					}
+				}
+			} else if m := raceFuncRe.FindStringSubmatch(line); m != nil && stack != nil {
+				*stack = append(*stack, StackFrame{Function: m[1]})
+			} else if m := raceFileRe.FindStringSubmatch(line); m != nil && stack != nil && len(*stack) > 0 {
+				frame := &(*stack)[len(*stack)-1]
+				frame.File = m[1]
+				frame.Line, _ = strconv.Atoi(m[2])
+				frame.Source = rc.relPath(m[1])
+			}
+		}
+	}
+
+	for i := range races {
+		races[i].Key = raceKey(races[i])
+	}
+	return races
+}
+
+// raceKey identifies a race by its operations and the top frame of each access.
+// Addresses and goroutine IDs differ between reports and are left out.
+func raceKey(race DataRace) string {
+	var parts []string
+	for _, access := range race.Accesses {
+		part := strings.TrimPrefix(strings.ToLower(access.Operation), "previous ")
+		if len(access.Stack) > 0 {
+			top := access.Stack[0]
+			part += fmt.Sprintf(" %s %s:%d", top.Function, top.File, top.Line)
+		}
+		parts = append(parts, part)
+	}
+	sort.Strings(parts)
+	sum := sha1.Sum([]byte(strings.Join(parts, "\n")))
+	return fmt.Sprintf("%x", sum[:6])
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// RaceHistory counts races across the stored runs made with the race detector.
+func (td *TestDashboard) RaceHistory() (*RaceHistory, error) {
+	runs, err := td.History.Runs()
+	if err != nil {
+		return nil, err
+	}
+
+	history := &RaceHistory{Runs: []RaceRunCount{}, Races: []RaceOccurrence{}}
+	index := make(map[string]int)
+	for _, run := range runs {
+		if !run.Options.Race {
+			continue
+		}
+		count := RaceRunCount{RunID: run.ID, StartedAt: run.StartedAt, Commit: run.Commit}
+		for _, result := range run.Data.Results {
+			for _, race := range result.Races {
+				count.Races++
+				i, ok := index[race.Key]
+				if !ok {
+					i = len(history.Races)
+					index[race.Key] = i
+					history.Races = append(history.Races, RaceOccurrence{Race: race, FirstSeen: run.StartedAt})
+				}
+				occurrence := &history.Races[i]
+				if occurrence.LastSeen != run.StartedAt {
+					occurrence.Runs++
+				}
+				occurrence.LastSeen = run.StartedAt
+				occurrence.Race = race // keep the latest report
+			}
+		}
+		history.Runs = append(history.Runs, count)
+	}
+	sort.SliceStable(history.Races, func(i, j int) bool {
+		return history.Races[i].LastSeen.After(history.Races[j].LastSeen)
+	})
+	return history, nil
+}
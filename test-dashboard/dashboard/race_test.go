@@ -0,0 +1,191 @@
+package dashboard
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testdata/race.json is the `go test -race -json` stream of a package in
+// /tmp/racefix whose Counter.Inc and Counter.Value access a field without
+// locking. TestInc and TestValue each hit a race; TestIncAgain hits the same
+// race as TestInc, which the race detector reports only once per process.
+func readRaceFixture(t *testing.T) (string, []TestCase) {
+	t.Helper()
+	stream, err := os.ReadFile("testdata/race.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parseTestJSON(stream)
+}
+
+func TestParseRaceReports(t *testing.T) {
+	_, tests := readRaceFixture(t)
+	rc := runCtx{root: "/tmp/racefix"}
+
+	frame := func(function, file string, line int) StackFrame {
+		f := StackFrame{Function: function, File: file, Line: line}
+		if strings.HasPrefix(file, "/tmp/racefix/") {
+			f.Source = strings.TrimPrefix(file, "/tmp/racefix/")
+		}
+		return f
+	}
+	testingFrames := []StackFrame{
+		frame("testing.tRunner", "/usr/local/go/src/testing/testing.go", 2193),
+		frame("testing.(*T).Run.gowrap1", "/usr/local/go/src/testing/testing.go", 2258),
+	}
+	incStack := []StackFrame{
+		frame("example.com/racefix.(*Counter).Inc", "/tmp/racefix/counter.go", 9),
+		frame("example.com/racefix.incTwice.func1", "/tmp/racefix/counter_test.go", 14),
+	}
+	incCreated := append([]StackFrame{
+		frame("example.com/racefix.incTwice", "/tmp/racefix/counter_test.go", 12),
+		frame("example.com/racefix.TestInc", "/tmp/racefix/counter_test.go", 21),
+	}, testingFrames...)
+
+	cases := []struct {
+		test string
+		want []RaceAccess
+	}{
+		{"TestInc", []RaceAccess{
+			{Operation: "Read", Goroutine: 8, State: "running", Stack: incStack, CreatedAt: incCreated},
+			{Operation: "Previous write", Goroutine: 9, State: "finished", Stack: incStack, CreatedAt: incCreated},
+		}},
+		{"TestIncAgain", nil},
+		{"TestValue", []RaceAccess{
+			{
+				Operation: "Write", Goroutine: 14, State: "running",
+				Stack: []StackFrame{
+					frame("example.com/racefix.(*Counter).Inc", "/tmp/racefix/counter.go", 9),
+					frame("example.com/racefix.TestValue.func1", "/tmp/racefix/counter_test.go", 32),
+				},
+				CreatedAt: append([]StackFrame{frame("example.com/racefix.TestValue", "/tmp/racefix/counter_test.go", 31)}, testingFrames...),
+			},
+			{
+				Operation: "Previous read", Goroutine: 13, State: "running",
+				Stack: append([]StackFrame{
+					frame("example.com/racefix.(*Counter).Value", "/tmp/racefix/counter.go", 13),
+					frame("example.com/racefix.TestValue", "/tmp/racefix/counter_test.go", 35),
+				}, testingFrames...),
+				CreatedAt: []StackFrame{
+					frame("testing.(*T).Run", "/usr/local/go/src/testing/testing.go", 2258),
+					frame("testing.runTests.func1", "/usr/local/go/src/testing/testing.go", 2742),
+					frame("testing.tRunner", "/usr/local/go/src/testing/testing.go", 2193),
+					frame("testing.runTests", "/usr/local/go/src/testing/testing.go", 2740),
+					frame("testing.(*M).Run", "/usr/local/go/src/testing/testing.go", 2600),
+					frame("main.main", "_testmain.go", 50),
+				},
+			},
+		}},
+	}
+	if len(tests) != len(cases) {
+		t.Fatalf("fixture has %d tests; want %d", len(tests), len(cases))
+	}
+	for i, tt := range cases {
+		races := rc.parseRaceReports(tests[i].Output)
+		if tt.want == nil {
+			if len(races) != 0 {
+				t.Errorf("%s: parsed %d races; want none", tt.test, len(races))
+			}
+			continue
+		}
+		if len(races) != 1 {
+			t.Errorf("%s: parsed %d races; want 1", tt.test, len(races))
+			continue
+		}
+		if !reflect.DeepEqual(races[0].Accesses, tt.want) {
+			t.Errorf("%s: accesses:\n got %+v\nwant %+v", tt.test, races[0].Accesses, tt.want)
+		}
+		if races[0].Key == "" {
+			t.Errorf("%s: race has no key", tt.test)
+		}
+	}
+}
+
+func TestParseRaces(t *testing.T) {
+	output, tests := readRaceFixture(t)
+	rc := runCtx{root: "/tmp/racefix"}
+
+	races := rc.parseRaces(output, tests)
+	if len(races) != 2 {
+		t.Fatalf("parseRaces found %d races; want 2", len(races))
+	}
+	if got := races[0].Tests; !reflect.DeepEqual(got, []string{"TestInc"}) || races[0].Count != 1 {
+		t.Errorf("first race: tests %v, count %d; want [TestInc], 1", got, races[0].Count)
+	}
+	if got := races[1].Tests; !reflect.DeepEqual(got, []string{"TestValue"}) || races[1].Count != 1 {
+		t.Errorf("second race: tests %v, count %d; want [TestValue], 1", got, races[1].Count)
+	}
+	if races[0].Key == races[1].Key {
+		t.Error("different races share a key")
+	}
+
+	// The same race reported by another test binary run differs only in its
+	// addresses and goroutine IDs, and is merged with the first report.
+	again := strings.NewReplacer("0x00c0000182b8", "0x00c00011a0f8", "goroutine 8", "goroutine 21", "goroutine 9", "goroutine 22",
+		"Goroutine 8 ", "Goroutine 21 ", "Goroutine 9 ", "Goroutine 22 ").Replace(tests[0].Output)
+	if again == tests[0].Output {
+		t.Fatal("fixture does not contain the expected addresses and goroutine IDs")
+	}
+	merged := rc.parseRaces(output, append(tests, TestCase{Name: "TestIncAgain", Status: "fail", Output: again}))
+	if len(merged) != 2 {
+		t.Fatalf("parseRaces found %d races; want 2", len(merged))
+	}
+	if got := merged[0].Tests; !reflect.DeepEqual(got, []string{"TestInc", "TestIncAgain"}) || merged[0].Count != 2 {
+		t.Errorf("merged race: tests %v, count %d; want [TestInc TestIncAgain], 2", got, merged[0].Count)
+	}
+
+	// Without per-test output, e.g. a race in TestMain, the package output is
+	// parsed and the races belong to no test.
+	outside := rc.parseRaces(output, nil)
+	if len(outside) != 2 || outside[0].Tests != nil || outside[0].Key != races[0].Key {
+		t.Errorf("races outside tests = %+v; want the same 2 races without tests", outside)
+	}
+}
+
+func TestParseRaceReportsMainGoroutine(t *testing.T) {
+	output := `==================
+WARNING: DATA RACE
+Write at 0x00c000012345 by main goroutine:
+  main.main()
+      /src/app/main.go:12 +0x4c
+
+Previous read at 0x00c000012345 by goroutine 6:
+  main.main.func1()
+      /src/app/main.go:9 +0x3a
+
+Goroutine 6 (finished) created at:
+  main.main()
+      /src/app/main.go:8 +0x2e
+==================
+Found 1 data race(s)
+`
+	races := runCtx{root: "/src/app"}.parseRaceReports(output)
+	if len(races) != 1 || len(races[0].Accesses) != 2 {
+		t.Fatalf("parseRaceReports = %+v; want one race with two accesses", races)
+	}
+	current, previous := races[0].Accesses[0], races[0].Accesses[1]
+	if current.Operation != "Write" || current.Goroutine != 0 || current.CreatedAt != nil {
+		t.Errorf("main goroutine access = %+v", current)
+	}
+	if previous.Goroutine != 6 || previous.State != "finished" || len(previous.CreatedAt) != 1 || previous.CreatedAt[0].Source != "main.go" {
+		t.Errorf("previous access = %+v", previous)
+	}
+}
+
+func TestRaceKey(t *testing.T) {
+	access := func(op, fn string, line int) RaceAccess {
+		return RaceAccess{Operation: op, Stack: []StackFrame{{Function: fn, File: "/src/app/c.go", Line: line}}}
+	}
+	a := DataRace{Accesses: []RaceAccess{access("Read", "app.get", 10), access("Previous write", "app.set", 20)}}
+	swapped := DataRace{Accesses: []RaceAccess{access("Write", "app.set", 20), access("Previous read", "app.get", 10)}}
+	moved := DataRace{Accesses: []RaceAccess{access("Read", "app.get", 11), access("Previous write", "app.set", 20)}}
+
+	if raceKey(a) != raceKey(swapped) {
+		t.Error("the order of the accesses changes the key")
+	}
+	if raceKey(a) == raceKey(moved) {
+		t.Error("accesses on different lines share a key")
+	}
+}
@@ -0,0 +1,156 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RerunFailed re-runs only what failed in the previous run. Packages with failing
+// tests re-run just those top-level tests; packages that failed without any test
+// failing (build errors, a broken TestMain, ...) are re-run in full. Results are
+// updated in place so the UI can show which failures reproduced. A run of a
+// git ref is re-run in a fresh worktree of the same commit.
+func (td *TestDashboard) RerunFailed() {
+	td.lockRun("rerun")
+	defer td.runMu.Unlock()
+	td.running.Store(true)
+	defer td.running.Store(false)
+
+	rc := td.projectCtx()
+	if td.run != nil && td.run.Options.Ref != "" {
+		var leave func()
+		var err error
+		rc, leave, err = td.worktreeCtx(td.run.Commit)
+		if err != nil {
+			log.Printf("Error checking out %.12s for the re-run: %v", td.run.Commit, err)
+			return
+		}
+		defer leave()
+	}
+
+	current := td.Snapshot()
+	results := make([]TestResult, len(current.Results))
+	copy(results, current.Results)
+	total := current.TotalTests
+
+	rerunCount := 0
+	data := current
+	for i, result := range results {
+		if result.Passed {
+			continue
+		}
+		rerunCount++
+
+		failed := failedTests(result.Tests)
+		if len(failed) == 0 {
+			log.Printf("Re-running package %s", result.Package)
+			results[i] = td.runPackageTests(rc, filepath.Join(rc.root, result.Package), td.runOptions())
+			results[i].ImportPath = result.ImportPath
+			if results[i].Statements == 0 {
+				results[i].Statements = result.Statements
+			}
+			td.remergeIntegrationCoverage(rc, results[i:i+1])
+		} else {
+			log.Printf("Re-running %d failed tests in %s", len(failed), result.Package)
+			results[i] = td.rerunPackageTests(rc, result, failed)
+		}
+		td.observePackage(results[i])
+
+		data = td.summarize(rc, results, total)
+		data.Integration = current.Integration
+		td.publish(data)
+	}
+
+	if rerunCount > 0 {
+		td.storeRun(data)
+	}
+	log.Printf("Re-run complete. %d packages re-run", rerunCount)
+}
+
+// runOptions returns the options of the current run. Callers must hold td.runMu.
+func (td *TestDashboard) runOptions() RunOptions {
+	if td.run == nil {
+		return RunOptions{}
+	}
+	return td.run.Options
+}
+
+// rerunPackageTests runs the top-level tests owning the given failures and merges
+// the fresh outcomes into a copy of result.
+func (td *TestDashboard) rerunPackageTests(rc runCtx, result TestResult, failed []string) TestResult {
+	start := time.Now()
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range failed {
+		top := topLevelTest(name)
+		if !seen[top] {
+			seen[top] = true
+			names = append(names, regexp.QuoteMeta(top))
+		}
+	}
+	pattern := "^(" + strings.Join(names, "|") + ")$"
+
+	args := []string{"test", "-json", "-count=1", "-timeout=0", "-run", pattern}
+	if td.runOptions().Race {
+		args = append(args, "-race")
+	}
+	if tags := td.runOptions().Tags; tags != "" {
+		args = append(args, "-tags="+tags)
+	}
+	cmd := rc.goCommand(result.Package, args...)
+	stream, hung, testErr := td.runWatched(context.Background(), cmd, result.Package, td.runOptions().timeout())
+	output, fresh := parseTestJSON(stream)
+
+	outcomes := make(map[string]TestCase, len(fresh))
+	for _, tc := range fresh {
+		outcomes[tc.Name] = tc
+	}
+
+	updated := result
+	updated.Tests = make([]TestCase, len(result.Tests))
+	for i, tc := range result.Tests {
+		again, ok := outcomes[tc.Name]
+		if !ok || !seen[topLevelTest(tc.Name)] {
+			updated.Tests[i] = tc
+			continue
+		}
+		again.Rerun = true
+		if tc.Status == "fail" {
+			again.Reproduced = again.Status == "fail"
+			again.PossiblyFlaky = again.Status == "pass"
+		}
+		updated.Tests[i] = again
+	}
+
+	updated.Passed = testErr == nil && !hung
+	updated.TimedOut = hung
+	updated.Races = rc.parseRaces(output, fresh)
+	updated.GoroutineDump = nil
+	updated.Failures = nil
+	if hung {
+		updated.GoroutineDump = rc.parseGoroutineDump(output)
+	} else if !updated.Passed {
+		updated.Failures = rc.parseFailures(result.Package, output, updated.Tests)
+	}
+	updated.Output = fmt.Sprintf("%s\n=== RE-RUN (%s) ===\n%s", result.Output, pattern, output)
+	updated.Duration = time.Since(start)
+	updated.Timestamp = time.Now()
+	return updated
+}
+
+// failedTests returns the names of the tests that ended in failure.
+func failedTests(tests []TestCase) []string {
+	var names []string
+	for _, tc := range tests {
+		if tc.Status == "fail" {
+			names = append(names, tc.Name)
+		}
+	}
+	return names
+}
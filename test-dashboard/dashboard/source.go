@@ -0,0 +1,56 @@
+package dashboard
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxSourceSize caps how large a file the source viewer will return.
+const maxSourceSize = 2 << 20
+
+// SourceFile is a project file as shown by the source viewer.
+type SourceFile struct {
+	Path    string `json:"path"` // relative to the project, slash-separated
+	Content string `json:"content"`
+}
+
+// relPath returns file relative to the root, or "" if the file lies outside
+// of it. Stack traces and test output use absolute paths.
+func (rc runCtx) relPath(file string) string {
+	if !filepath.IsAbs(file) {
+		return ""
+	}
+	rel, err := filepath.Rel(rc.root, filepath.Clean(file))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// ReadSource returns a Go file of the project for the source viewer. Paths are
+// relative to the project root; anything outside of it is refused.
+func (td *TestDashboard) ReadSource(path string) (*SourceFile, error) {
+	if path == "" || filepath.IsAbs(path) || !strings.HasSuffix(path, ".go") {
+		return nil, fmt.Errorf("invalid source path %q", path)
+	}
+	full := filepath.Join(td.ProjectPath, filepath.FromSlash(path))
+	rel := td.projectCtx().relPath(full)
+	if rel == "" {
+		return nil, fmt.Errorf("invalid source path %q", path)
+	}
+
+	info, err := os.Stat(full)
+	if err != nil {
+		return nil, fmt.Errorf("source file %s not found", rel)
+	}
+	if info.Size() > maxSourceSize {
+		return nil, fmt.Errorf("source file %s is too large", rel)
+	}
+	content, err := os.ReadFile(full)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", rel, err)
+	}
+	return &SourceFile{Path: rel, Content: string(content)}, nil
+}
@@ -0,0 +1,393 @@
+package dashboard
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultSummaryTop is how many uncovered changed lines, slowest packages and
+// tests of each kind a Markdown summary lists.
+const defaultSummaryTop = 10
+
+// SummaryOptions selects what a Markdown summary of a run covers.
+type SummaryOptions struct {
+	// Compare is the ID of a run to compare against, or "previous" for the
+	// run stored before this one.
+	Compare string
+	// DiffBase is the git ref that diff coverage is measured against. When
+	// comparing, it defaults to the commit of the compared run.
+	DiffBase string
+	// Top bounds the lists of the summary.
+	Top int
+	// Threshold is the duration increase, in percent, that counts as a
+	// regression when comparing.
+	Threshold float64
+}
+
+// DiffCoverage is the coverage of the lines changed since a git ref.
+type DiffCoverage struct {
+	Base      string       `json:"base"`
+	Lines     int          `json:"lines"` // changed lines that hold statements
+	Covered   int          `json:"covered"`
+	Coverage  float64      `json:"coverage"`
+	Uncovered []ChangedRow `json:"uncovered"`
+}
+
+// ChangedRow is a changed line that no test executed.
+type ChangedRow struct {
+	Path    string `json:"path"` // project-relative
+	Line    int    `json:"line"`
+	Content string `json:"content"`
+}
+
+// diffHead returns what a run's changes are diffed to: its commit if it
+// tested a clean checkout, else its tree if that is a git object, as it is for
+// runs stored before the tree hash stopped being written. "" means the working
+// tree, which only still holds what the latest run tested if nothing changed.
+func (td *TestDashboard) diffHead(run *RunRecord) (string, error) {
+	if run.Commit == "" {
+		return "", fmt.Errorf("run %s did not test a git checkout", run.ID)
+	}
+	if !run.Dirty {
+		return run.Commit, nil
+	}
+	if run.TreeHash != "" {
+		if _, err := git(td.ProjectPath, nil, "cat-file", "-e", run.TreeHash+"^{tree}"); err == nil {
+			return run.TreeHash, nil
+		}
+	}
+	if latest, err := td.History.Latest(); err == nil && latest.ID == run.ID &&
+		run.TreeHash != "" && workingTreeHash(td.ProjectPath) == run.TreeHash {
+		return "", nil
+	}
+	return "", fmt.Errorf("run %s tested uncommitted changes that are no longer in the working tree", run.ID)
+}
+
+// diffCoverage measures how many of the lines changed since base that hold
+// statements the run executed, on the code the run tested (see diffHead).
+func (td *TestDashboard) diffCoverage(run *RunRecord, base string) (*DiffCoverage, error) {
+	head, err := td.diffHead(run)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := td.changedLines(base, head)
+	if err != nil {
+		return nil, err
+	}
+	dc := &DiffCoverage{Base: base, Uncovered: []ChangedRow{}}
+	rc := td.projectCtx()
+	for _, r := range run.Data.Results {
+		for _, f := range r.Files {
+			rel, err := filepath.Rel(rc.root, rc.coverageFilePath(f.Filename))
+			if err != nil {
+				continue
+			}
+			lines := changed[filepath.ToSlash(rel)]
+			if len(lines) == 0 {
+				continue
+			}
+			content := strings.Split(f.Content, "\n")
+			for line, covered := range lineCoverage(f.Blocks) {
+				if !lines[line] {
+					continue
+				}
+				dc.Lines++
+				if covered {
+					dc.Covered++
+					continue
+				}
+				row := ChangedRow{Path: filepath.ToSlash(rel), Line: line}
+				if line-1 < len(content) {
+					row.Content = strings.TrimSpace(content[line-1])
+				}
+				dc.Uncovered = append(dc.Uncovered, row)
+			}
+		}
+	}
+	if dc.Lines > 0 {
+		dc.Coverage = 100 * float64(dc.Covered) / float64(dc.Lines)
+	}
+	sort.Slice(dc.Uncovered, func(i, j int) bool {
+		if dc.Uncovered[i].Path != dc.Uncovered[j].Path {
+			return dc.Uncovered[i].Path < dc.Uncovered[j].Path
+		}
+		return dc.Uncovered[i].Line < dc.Uncovered[j].Line
+	})
+	return dc, nil
+}
+
+// SummaryMarkdown renders a stored run, or "latest", as Markdown for a pull
+// request comment: the outcome of every package, the failing tests, coverage,
+// diff coverage and the slowest packages, plus what changed since another
+// run when opts.Compare is set.
+func (td *TestDashboard) SummaryMarkdown(id string, opts SummaryOptions) (string, error) {
+	var run *RunRecord
+	var err error
+	if id == "latest" {
+		run, err = td.History.Latest()
+	} else {
+		run, err = td.History.Load(id)
+	}
+	if err != nil {
+		return "", err
+	}
+	if opts.Top <= 0 {
+		opts.Top = defaultSummaryTop
+	}
+
+	var comparison *RunComparison
+	if opts.Compare != "" {
+		baseID := opts.Compare
+		if baseID == "previous" {
+			previous, err := td.History.Previous(run.ID)
+			if err != nil {
+				return "", err
+			}
+			if previous == nil {
+				return "", fmt.Errorf("run %s is the oldest stored run", run.ID)
+			}
+			baseID = previous.ID
+		}
+		if comparison, err = td.History.CompareRuns(baseID, run.ID, opts.Threshold); err != nil {
+			return "", err
+		}
+		if opts.DiffBase == "" {
+			opts.DiffBase = comparison.Base.Commit
+		}
+	}
+	var diff *DiffCoverage
+	var diffErr error
+	if opts.DiffBase != "" {
+		diff, diffErr = td.diffCoverage(run, opts.DiffBase)
+	}
+
+	var b strings.Builder
+	data := run.Data
+	failed := data.TotalTests - data.PassedTests
+	if failed == 0 {
+		fmt.Fprintf(&b, "## ✅ All %d packages passed\n\n", data.TotalTests)
+	} else {
+		fmt.Fprintf(&b, "## ❌ %d of %d packages failed\n\n", failed, data.TotalTests)
+	}
+	meta := []string{"Run `" + run.ID + "`"}
+	if run.Commit != "" {
+		commit := "commit `" + shortCommit(run.Commit) + "`"
+		if run.Dirty {
+			commit += " with local changes"
+		}
+		meta = append(meta, commit)
+	}
+	if run.Branch != "" {
+		meta = append(meta, "branch `"+run.Branch+"`")
+	}
+	if data.GatePassed {
+		meta = append(meta, "quality gate passed")
+	} else {
+		meta = append(meta, "**quality gate failed**")
+	}
+	b.WriteString(strings.Join(meta, " · ") + "\n\n")
+
+	coverage := fmt.Sprintf("**Coverage:** %.1f%%", data.OverallCoverage)
+	if comparison != nil {
+		coverage += fmt.Sprintf(" (%s vs `%s`)", signedPercent(comparison.CoverageDelta), comparison.Base.ID)
+	}
+	if diff != nil {
+		if diff.Lines > 0 {
+			coverage += fmt.Sprintf(" · **Diff coverage:** %.1f%% of %d changed lines since `%s`", diff.Coverage, diff.Lines, shortCommit(diff.Base))
+		} else {
+			coverage += fmt.Sprintf(" · no changed statements since `%s`", shortCommit(diff.Base))
+		}
+	} else if diffErr != nil {
+		coverage += fmt.Sprintf(" · diff coverage unavailable: %s", diffErr)
+	}
+	b.WriteString(coverage + "\n\n")
+
+	// Packages, failing ones first
+	results := append([]TestResult(nil), data.Results...)
+	sort.SliceStable(results, func(i, j int) bool { return !results[i].Passed && results[j].Passed })
+	deltas := make(map[string]float64)
+	if comparison != nil {
+		for _, p := range comparison.Packages {
+			if p.Status == "" {
+				deltas[p.Package] = p.Delta
+			}
+		}
+	}
+	b.WriteString("| | Package | Tests | Coverage |")
+	if comparison != nil {
+		b.WriteString(" Δ |")
+	}
+	b.WriteString("\n|---|---|---|---|")
+	if comparison != nil {
+		b.WriteString("---|")
+	}
+	b.WriteString("\n")
+	for _, r := range results {
+		status := "✅"
+		switch {
+		case r.NoTests:
+			status = "➖"
+		case !r.Passed:
+			status = "❌"
+		}
+		passed := 0
+		for _, t := range r.Tests {
+			if t.Status == "pass" {
+				passed++
+			}
+		}
+		fmt.Fprintf(&b, "| %s | %s | %d/%d | %.1f%% |", status, mdCode(r.Package), passed, len(r.Tests), r.Coverage)
+		if comparison != nil {
+			if delta, ok := deltas[r.Package]; ok {
+				fmt.Fprintf(&b, " %s |", signedPercent(delta))
+			} else {
+				b.WriteString(" new |")
+			}
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	// Failing tests
+	newlyFailing := make(map[string]bool)
+	if comparison != nil {
+		for _, t := range comparison.NewlyFailing {
+			newlyFailing[t.Package+" "+t.Test] = true
+		}
+	}
+	var failing []string
+	for _, r := range data.Results {
+		if r.Passed {
+			continue
+		}
+		names := leafTests(failedTests(r.Tests))
+		if len(names) == 0 {
+			names = []string{""}
+		}
+		for _, name := range names {
+			line := "- " + mdCode(r.Package)
+			if name != "" {
+				line += " › " + mdCode(name)
+			} else {
+				line += " (no test failed; the package did not build or exited early)"
+			}
+			if newlyFailing[r.Package+" "+name] {
+				line += " 🆕"
+			}
+			failing = append(failing, line)
+		}
+	}
+	if len(failing) > 0 {
+		b.WriteString("### Failing tests\n\n")
+		writeCapped(&b, failing, opts.Top)
+	}
+
+	if comparison != nil {
+		fmt.Fprintf(&b, "### Since `%s`", comparison.Base.ID)
+		if comparison.Base.Commit != "" {
+			fmt.Fprintf(&b, " (`%s`)", shortCommit(comparison.Base.Commit))
+		}
+		b.WriteString("\n\n")
+		fmt.Fprintf(&b, "Tests: %d newly failing · %d newly passing · %d added · %d removed · %d slower by more than %.0f%%\n\n",
+			len(comparison.NewlyFailing), len(comparison.NewlyPassing), len(comparison.Added), len(comparison.Removed),
+			len(comparison.DurationRegressions), comparison.DurationThreshold)
+		if len(comparison.NewlyPassing) > 0 {
+			b.WriteString("**Newly passing**\n\n")
+			writeCapped(&b, testChangeLines(comparison.NewlyPassing), opts.Top)
+		}
+		if len(comparison.DurationRegressions) > 0 {
+			b.WriteString("**Slower**\n\n| Package | Test | Before | After | Change |\n|---|---|---|---|---|\n")
+			for i, d := range comparison.DurationRegressions {
+				if i == opts.Top {
+					fmt.Fprintf(&b, "\n…and %d more\n", len(comparison.DurationRegressions)-opts.Top)
+					break
+				}
+				test := mdCode(d.Test)
+				if test == "" {
+					test = "(whole package)"
+				}
+				fmt.Fprintf(&b, "| %s | %s | %s | %s | +%.0f%% |\n", mdCode(d.Package), test, roundDuration(d.Base), roundDuration(d.Head), d.Increase)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if diff != nil && len(diff.Uncovered) > 0 {
+		fmt.Fprintf(&b, "### Uncovered changed lines (%d)\n\n| File | Line | Code |\n|---|---|---|\n", len(diff.Uncovered))
+		for i, row := range diff.Uncovered {
+			if i == opts.Top {
+				fmt.Fprintf(&b, "\n…and %d more\n", len(diff.Uncovered)-opts.Top)
+				break
+			}
+			fmt.Fprintf(&b, "| %s | %d | %s |\n", mdCode(row.Path), row.Line, mdCode(row.Content))
+		}
+		b.WriteString("\n")
+	}
+
+	slowest := append([]TestResult(nil), data.Results...)
+	sort.SliceStable(slowest, func(i, j int) bool { return slowest[i].Duration > slowest[j].Duration })
+	b.WriteString("### Slowest packages\n\n| Package | Duration |\n|---|---|\n")
+	for i, r := range slowest {
+		if i == opts.Top || r.Duration == 0 {
+			break
+		}
+		fmt.Fprintf(&b, "| %s | %s |\n", mdCode(r.Package), roundDuration(r.Duration))
+	}
+	return b.String(), nil
+}
+
+// writeCapped writes a Markdown list of at most max lines.
+func writeCapped(b *strings.Builder, lines []string, max int) {
+	for i, line := range lines {
+		if i == max {
+			fmt.Fprintf(b, "- …and %d more\n", len(lines)-max)
+			break
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n")
+}
+
+func testChangeLines(changes []TestChange) []string {
+	lines := make([]string, len(changes))
+	for i, t := range changes {
+		lines[i] = "- " + mdCode(t.Package)
+		if t.Test != "" {
+			lines[i] += " › " + mdCode(t.Test)
+		}
+	}
+	return lines
+}
+
+// mdCode formats s as inline code that is safe inside a table cell.
+func mdCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	s = strings.ReplaceAll(s, "|", `\|`)
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+	return "`" + s + "`"
+}
+
+func signedPercent(v float64) string {
+	return fmt.Sprintf("%+.1f%%", v)
+}
+
+func shortCommit(commit string) string {
+	if len(commit) == 40 {
+		return commit[:12]
+	}
+	return commit
+}
+
+func roundDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(10 * time.Millisecond).String()
+}
@@ -0,0 +1,468 @@
+package dashboard
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TestCoverage maps the statements of a package to the top-level tests that
+// execute them. It is built by running every top-level test on its own with
+// its own cover profile, and stored as a "testcoverage" artifact of the run.
+type TestCoverage struct {
+	Package string                 `json:"package"`
+	Tests   []string               `json:"tests"`
+	Files   map[string][]TestBlock `json:"files"` // keyed by project-relative path
+}
+
+// TestBlock is a block of a cover profile together with the tests covering it.
+type TestBlock struct {
+	StartLine  int   `json:"start_line"`
+	StartCol   int   `json:"start_col"`
+	EndLine    int   `json:"end_line"`
+	EndCol     int   `json:"end_col"`
+	Statements int   `json:"statements"`
+	Tests      []int `json:"tests"` // indexes into TestCoverage.Tests; empty if no test covers it
+}
+
+// CoveringTest is a test that executes a given line.
+type CoveringTest struct {
+	Package string `json:"package"`
+	Test    string `json:"test"`
+}
+
+// LineTests answers "which tests cover this line".
+type LineTests struct {
+	File      string         `json:"file"`
+	Line      int            `json:"line"`
+	Statement bool           `json:"statement"` // false if the line holds no statement
+	Tests     []CoveringTest `json:"tests"`
+}
+
+// ImpactReport lists the tests that execute code changed since a git ref.
+type ImpactReport struct {
+	RunID        string            `json:"run_id"`
+	Base         string            `json:"base"`
+	ChangedLines int               `json:"changed_lines"`
+	CoveredLines int               `json:"covered_lines"` // changed lines executed by at least one test
+	Files        []ChangedFile     `json:"files"`
+	Packages     []ImpactedPackage `json:"packages"`
+}
+
+// ChangedFile is a Go file with lines changed since the base ref.
+type ChangedFile struct {
+	Path    string `json:"path"`
+	Changed int    `json:"changed"`
+	Covered int    `json:"covered"`
+}
+
+// ImpactedPackage groups the impacted tests of a package with the command
+// that re-runs just them.
+type ImpactedPackage struct {
+	Package string         `json:"package"`
+	Tests   []ImpactedTest `json:"tests"`
+	Command string         `json:"command"`
+}
+
+// ImpactedTest is a test that executes changed lines, or whose own code changed.
+type ImpactedTest struct {
+	Name    string `json:"name"`
+	Lines   int    `json:"lines"`             // changed lines it executes
+	Changed bool   `json:"changed,omitempty"` // the test function itself was edited
+}
+
+// profileBlock is one line of a cover profile.
+type profileBlock struct {
+	File                                 string
+	StartLine, StartCol, EndLine, EndCol int
+	Statements, Count                    int
+}
+
+var profileLineRe = regexp.MustCompile(`^(.+):(\d+)\.(\d+),(\d+)\.(\d+) (\d+) (\d+)$`)
+
+// readCoverProfile parses a cover profile written by -coverprofile.
+func readCoverProfile(profilePath string) ([]profileBlock, error) {
+	file, err := os.Open(profilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var blocks []profileBlock
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		m := profileLineRe.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue // the "mode:" header
+		}
+		n := make([]int, 6)
+		for i := range n {
+			n[i], _ = strconv.Atoi(m[i+2])
+		}
+		blocks = append(blocks, profileBlock{
+			File: m[1], StartLine: n[0], StartCol: n[1], EndLine: n[2], EndCol: n[3],
+			Statements: n[4], Count: n[5],
+		})
+	}
+	return blocks, scanner.Err()
+}
+
+// attributeCoverage runs each top-level test of a package alone and records
+// which blocks it covers. The package is compiled once with -cover and the
+// test binary is run per test, which is much faster than a go test per test.
+func (rc runCtx) attributeCoverage(relPkg string, tests []TestCase, opts RunOptions, dir string) (*Artifact, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, tc := range tests {
+		top := topLevelTest(tc.Name)
+		if !seen[top] {
+			seen[top] = true
+			names = append(names, top)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	base := artifactBase(relPkg)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create artifact directory: %w", err)
+	}
+	binary := filepath.Join(dir, base+".cover.test")
+	defer os.Remove(binary)
+	build := rc.goCommand(relPkg, "test", "-c", "-cover", "-tags="+opts.Tags, "-o", binary)
+	if out, err := build.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("could not build %s: %v\n%s", relPkg, err, out)
+	}
+
+	coverage := &TestCoverage{Package: relPkg, Tests: names, Files: make(map[string][]TestBlock)}
+	type blockKey struct {
+		file                                 string
+		startLine, startCol, endLine, endCol int
+	}
+	index := make(map[blockKey]int)
+
+	for i, name := range names {
+		profile := filepath.Join(dir, fmt.Sprintf("%s-%d.cover", base, i))
+		cmd := exec.Command(binary,
+			"-test.run=^"+regexp.QuoteMeta(name)+"$",
+			"-test.coverprofile="+profile,
+			"-test.timeout="+opts.timeout().String())
+		// go test runs a test binary in its package directory; so do we.
+		cmd.Dir = filepath.Join(rc.root, filepath.FromSlash(relPkg))
+		if err := cmd.Run(); err != nil {
+			// A failing test still writes a profile worth attributing.
+			log.Printf("Per-test coverage: %s in %s: %v", name, relPkg, err)
+		}
+		blocks, err := readCoverProfile(profile)
+		os.Remove(profile)
+		if err != nil {
+			log.Printf("Per-test coverage: no profile for %s in %s: %v", name, relPkg, err)
+			continue
+		}
+
+		for _, b := range blocks {
+			file := rc.relPath(rc.coverageFilePath(b.File))
+			if file == "" {
+				continue
+			}
+			key := blockKey{file, b.StartLine, b.StartCol, b.EndLine, b.EndCol}
+			j, ok := index[key]
+			if !ok {
+				j = len(coverage.Files[file])
+				index[key] = j
+				coverage.Files[file] = append(coverage.Files[file], TestBlock{
+					StartLine: b.StartLine, StartCol: b.StartCol, EndLine: b.EndLine, EndCol: b.EndCol,
+					Statements: b.Statements, Tests: []int{},
+				})
+			}
+			if b.Count > 0 {
+				block := &coverage.Files[file][j]
+				block.Tests = append(block.Tests, i)
+			}
+		}
+	}
+
+	name := base + ".testcov.json"
+	content, err := json.Marshal(coverage)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+		return nil, fmt.Errorf("could not store per-test coverage: %w", err)
+	}
+	return &Artifact{Kind: "testcoverage", Name: name}, nil
+}
+
+// loadTestCoverage reads the per-test coverage of every package of a stored run.
+func (td *TestDashboard) loadTestCoverage(runID string) ([]*TestCoverage, error) {
+	run, err := td.History.Load(runID)
+	if err != nil {
+		return nil, err
+	}
+	var indexes []*TestCoverage
+	for _, result := range run.Data.Results {
+		for _, artifact := range result.Artifacts {
+			if artifact.Kind != "testcoverage" {
+				continue
+			}
+			path, err := td.ArtifactPath(runID, artifact.Name)
+			if err != nil {
+				continue
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			var coverage TestCoverage
+			if err := json.Unmarshal(content, &coverage); err != nil {
+				log.Printf("Error decoding %s: %v", path, err)
+				continue
+			}
+			indexes = append(indexes, &coverage)
+		}
+	}
+	if len(indexes) == 0 {
+		return nil, fmt.Errorf("run %s has no per-test coverage; run the tests with per-test coverage enabled", runID)
+	}
+	return indexes, nil
+}
+
+// sourceRelPath normalises a file name as shown in the UI, either an import
+// path from a cover profile or a project-relative path, to the latter.
+func (td *TestDashboard) sourceRelPath(file string) string {
+	rc := td.projectCtx()
+	if filepath.IsAbs(file) {
+		return rc.relPath(file)
+	}
+	for _, m := range rc.modules {
+		if strings.HasPrefix(file, m.Path+"/") {
+			return rc.relPath(rc.coverageFilePath(file))
+		}
+	}
+	return path.Clean(file)
+}
+
+// TestsCoveringLine lists the tests of a stored run that execute a line.
+func (td *TestDashboard) TestsCoveringLine(runID, file string, line int) (*LineTests, error) {
+	indexes, err := td.loadTestCoverage(runID)
+	if err != nil {
+		return nil, err
+	}
+	rel := td.sourceRelPath(file)
+	result := &LineTests{File: rel, Line: line, Tests: []CoveringTest{}}
+	for _, coverage := range indexes {
+		covering := make(map[int]bool)
+		for _, block := range coverage.Files[rel] {
+			if line < block.StartLine || line > block.EndLine {
+				continue
+			}
+			result.Statement = true
+			for _, t := range block.Tests {
+				covering[t] = true
+			}
+		}
+		for i, name := range coverage.Tests {
+			if covering[i] {
+				result.Tests = append(result.Tests, CoveringTest{Package: coverage.Package, Test: name})
+			}
+		}
+	}
+	return result, nil
+}
+
+var diffHunkRe = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// changedLines returns the lines of Go files changed since base, in the
+// working tree or, if head is set, in that commit or tree, keyed by
+// project-relative path. For pure deletions the lines around the removed code
+// count as changed.
+func (td *TestDashboard) changedLines(base, head string) (map[string]map[int]bool, error) {
+	if base == "" || strings.HasPrefix(base, "-") {
+		return nil, fmt.Errorf("invalid base ref %q", base)
+	}
+	if _, err := git(td.ProjectPath, nil, "rev-parse", "--verify", "--quiet", base+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("unknown base ref %q", base)
+	}
+	// Explicit prefixes, as diff.noprefix or diff.mnemonicPrefix would change them.
+	args := []string{"diff", "-U0", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/", "--relative", base}
+	if head != "" {
+		args = append(args, head)
+	}
+	diff, err := git(td.ProjectPath, nil, append(args, "--", "*.go")...)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]map[int]bool)
+	var current map[int]bool
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			current = nil
+			if name := strings.TrimPrefix(line, "+++ "); strings.HasPrefix(name, "b/") {
+				current = make(map[int]bool)
+				changed[strings.TrimPrefix(name, "b/")] = current
+			}
+		case current != nil && strings.HasPrefix(line, "@@"):
+			m := diffHunkRe.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			start, _ := strconv.Atoi(m[1])
+			count := 1
+			if m[2] != "" {
+				count, _ = strconv.Atoi(m[2])
+			}
+			if count == 0 {
+				// A deletion after line start: its neighbours are what changed behaviour.
+				current[start] = true
+				current[start+1] = true
+				continue
+			}
+			for l := start; l < start+count; l++ {
+				current[l] = true
+			}
+		}
+	}
+	return changed, nil
+}
+
+// ImpactedTests lists the tests of a stored run that execute lines changed
+// since base, plus tests whose own code changed, grouped by package.
+func (td *TestDashboard) ImpactedTests(runID, base string) (*ImpactReport, error) {
+	indexes, err := td.loadTestCoverage(runID)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := td.changedLines(base, "")
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImpactReport{RunID: runID, Base: base, Files: []ChangedFile{}, Packages: []ImpactedPackage{}}
+	hits := make(map[string]map[string]*ImpactedTest) // package -> test -> hit
+	hit := func(pkg, test string) *ImpactedTest {
+		if hits[pkg] == nil {
+			hits[pkg] = make(map[string]*ImpactedTest)
+		}
+		if hits[pkg][test] == nil {
+			hits[pkg][test] = &ImpactedTest{Name: test}
+		}
+		return hits[pkg][test]
+	}
+
+	var files []string
+	for file := range changed {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		lines := changed[file]
+		entry := ChangedFile{Path: file, Changed: len(lines)}
+		if strings.HasSuffix(file, "_test.go") {
+			for _, test := range td.editedTests(file, lines) {
+				hit(relPackagePath(td.ProjectPath, filepath.Join(td.ProjectPath, filepath.Dir(file))), test).Changed = true
+			}
+		}
+		for line := range lines {
+			covered := false
+			for _, coverage := range indexes {
+				tests := make(map[int]bool)
+				for _, block := range coverage.Files[file] {
+					if line >= block.StartLine && line <= block.EndLine {
+						for _, t := range block.Tests {
+							tests[t] = true
+						}
+					}
+				}
+				for t := range tests {
+					hit(coverage.Package, coverage.Tests[t]).Lines++
+					covered = true
+				}
+			}
+			if covered {
+				entry.Covered++
+			}
+		}
+		report.ChangedLines += entry.Changed
+		report.CoveredLines += entry.Covered
+		report.Files = append(report.Files, entry)
+	}
+
+	var packages []string
+	for pkg := range hits {
+		packages = append(packages, pkg)
+	}
+	sort.Strings(packages)
+	for _, pkg := range packages {
+		impacted := ImpactedPackage{Package: pkg}
+		var patterns []string
+		for _, t := range hits[pkg] {
+			impacted.Tests = append(impacted.Tests, *t)
+			patterns = append(patterns, regexp.QuoteMeta(t.Name))
+		}
+		sort.Slice(impacted.Tests, func(i, j int) bool {
+			if impacted.Tests[i].Lines != impacted.Tests[j].Lines {
+				return impacted.Tests[i].Lines > impacted.Tests[j].Lines
+			}
+			return impacted.Tests[i].Name < impacted.Tests[j].Name
+		})
+		sort.Strings(patterns)
+		impacted.Command = fmt.Sprintf("go test -run '^(%s)$' %s", strings.Join(patterns, "|"), pkg)
+		report.Packages = append(report.Packages, impacted)
+	}
+	return report, nil
+}
+
+// editedTests returns the top-level test functions of a test file that
+// contain one of the given lines.
+func (td *TestDashboard) editedTests(file string, lines map[int]bool) []string {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, filepath.Join(td.ProjectPath, filepath.FromSlash(file)), nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	var tests []string
+	for _, decl := range parsed.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || !isTestFunc(fn.Name.Name) {
+			continue
+		}
+		start, end := fset.Position(fn.Pos()).Line, fset.Position(fn.End()).Line
+		for line := range lines {
+			if line >= start && line <= end {
+				tests = append(tests, fn.Name.Name)
+				break
+			}
+		}
+	}
+	return tests
+}
+
+// isTestFunc reports whether name is run by go test as a top-level test.
+func isTestFunc(name string) bool {
+	if name == "TestMain" {
+		return false
+	}
+	for _, prefix := range []string{"Test", "Fuzz", "Example"} {
+		if rest, ok := strings.CutPrefix(name, prefix); ok {
+			// TestFoo and Test_foo are tests, Testing is not.
+			return rest == "" || !unicode.IsLower(rune(rest[0]))
+		}
+	}
+	return false
+}
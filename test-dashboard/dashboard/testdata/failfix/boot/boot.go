@@ -0,0 +1,14 @@
+package boot
+
+var config = load()
+
+func load() map[string]string {
+	var m map[string]string
+	m["env"] = "test"
+	return m
+}
+
+// Env returns the configured environment.
+func Env() string {
+	return config["env"]
+}
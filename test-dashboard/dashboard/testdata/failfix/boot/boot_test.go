@@ -0,0 +1,9 @@
+package boot
+
+import "testing"
+
+func TestEnv(t *testing.T) {
+	if Env() != "test" {
+		t.Fail()
+	}
+}
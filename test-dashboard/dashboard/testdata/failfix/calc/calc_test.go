@@ -0,0 +1,32 @@
+package calc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdd(t *testing.T) {
+	if got := Add(2, 2); got != 4 {
+		t.Errorf("Add(2, 2) = %d\nwant 4", got)
+	}
+}
+
+func TestTable(t *testing.T) {
+	t.Run("small", func(t *testing.T) {
+		assert.Equal(t, 2, Add(1, 1), "adding %d and %d", 1, 1)
+	})
+	t.Run("zero", func(t *testing.T) {
+		t.Log("checking zero")
+		assert.Equal(t, 0, Add(0, 0))
+	})
+}
+
+func TestDiv(t *testing.T) {
+	if Div(4, 2) != 2 {
+		t.Fatal("4 / 2 != 2")
+	}
+	t.Run("by zero", func(t *testing.T) {
+		Div(1, 0)
+	})
+}
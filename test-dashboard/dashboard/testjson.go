@@ -0,0 +1,107 @@
+package dashboard
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// TestCase is the outcome of a single test (or subtest) inside a package run.
+type TestCase struct {
+	Name          string        `json:"name"`
+	Status        string        `json:"status"` // "pass", "fail" or "skip"
+	Duration      time.Duration `json:"duration"`
+	Output        string        `json:"output,omitempty"`
+	Rerun         bool          `json:"rerun,omitempty"`
+	Reproduced    bool          `json:"reproduced,omitempty"`
+	PossiblyFlaky bool          `json:"possibly_flaky,omitempty"`
+	Quarantined   bool          `json:"quarantined,omitempty"`
+}
+
+// testEvent mirrors the records emitted by `go test -json` (see `go doc test2json`).
+type testEvent struct {
+	Time    time.Time `json:"Time"`
+	Action  string    `json:"Action"`
+	Package string    `json:"Package"`
+	Test    string    `json:"Test"`
+	Elapsed float64   `json:"Elapsed"`
+	Output  string    `json:"Output"`
+}
+
+// parseTestJSON turns the stream produced by `go test -json` back into the plain
+// text output a normal `go test` run would have printed, plus one TestCase per
+// test that reached a final pass/fail/skip state. Lines that are not JSON (for
+// example build errors from older toolchains) are kept verbatim in the output.
+func parseTestJSON(stream []byte) (string, []TestCase) {
+	var output strings.Builder
+	var tests []TestCase
+	index := make(map[string]int)
+	pending := make(map[string]*strings.Builder)
+
+	scanTestEvents(stream, func(ev testEvent, raw []byte) {
+		if raw != nil {
+			output.Write(raw)
+			output.WriteByte('\n')
+			return
+		}
+
+		switch ev.Action {
+		case "output", "build-output":
+			output.WriteString(ev.Output)
+			if ev.Test != "" {
+				if pending[ev.Test] == nil {
+					pending[ev.Test] = &strings.Builder{}
+				}
+				pending[ev.Test].WriteString(ev.Output)
+			}
+		case "pass", "fail", "skip":
+			if ev.Test == "" {
+				return
+			}
+			tc := TestCase{
+				Name:     ev.Test,
+				Status:   ev.Action,
+				Duration: time.Duration(ev.Elapsed * float64(time.Second)),
+			}
+			if buf := pending[ev.Test]; buf != nil {
+				tc.Output = buf.String()
+				delete(pending, ev.Test)
+			}
+			// With -count > 1 the same test reports several times; keep the latest.
+			if i, ok := index[ev.Test]; ok {
+				tests[i] = tc
+			} else {
+				index[ev.Test] = len(tests)
+				tests = append(tests, tc)
+			}
+		}
+	})
+	return output.String(), tests
+}
+
+// scanTestEvents calls fn for every line of a `go test -json` stream. Decoded
+// events are passed with a nil raw slice; lines that are not JSON are passed
+// as raw instead.
+func scanTestEvents(stream []byte, fn func(ev testEvent, raw []byte)) {
+	scanner := bufio.NewScanner(bytes.NewReader(stream))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var ev testEvent
+		if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &ev) != nil {
+			fn(testEvent{}, append([]byte{}, line...))
+			continue
+		}
+		fn(ev, nil)
+	}
+}
+
+// topLevelTest returns the name of the top-level test that owns a (sub)test.
+func topLevelTest(name string) string {
+	if i := strings.Index(name, "/"); i != -1 {
+		return name[:i]
+	}
+	return name
+}
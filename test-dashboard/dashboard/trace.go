@@ -0,0 +1,342 @@
+package dashboard
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxTraceSamples caps the number of points in the goroutine timeline.
+const maxTraceSamples = 200
+
+// TraceSummary condenses a `go test -trace` execution trace into what matters
+// when a test is slow for reasons a CPU profile can't show: goroutines piling
+// up, time spent blocked, GC pauses and waiting for a P.
+type TraceSummary struct {
+	Duration      time.Duration     `json:"duration"`
+	Goroutines    []GoroutineSample `json:"goroutines"`
+	MaxGoroutines int               `json:"max_goroutines"`
+	Blocking      []BlockingReason  `json:"blocking"`
+	GC            GCSummary         `json:"gc"`
+	SchedLatency  LatencySummary    `json:"sched_latency"`
+}
+
+// GoroutineSample counts live goroutines by state at an offset into the trace.
+type GoroutineSample struct {
+	Offset   time.Duration `json:"offset"`
+	Running  int           `json:"running"`
+	Runnable int           `json:"runnable"`
+	Waiting  int           `json:"waiting"`
+	Syscall  int           `json:"syscall"`
+}
+
+func (s GoroutineSample) total() int {
+	return s.Running + s.Runnable + s.Waiting + s.Syscall
+}
+
+// BlockingReason sums the time goroutines spent blocked for one wait reason.
+type BlockingReason struct {
+	Reason string        `json:"reason"`
+	Count  int           `json:"count"`
+	Total  time.Duration `json:"total"`
+	Max    time.Duration `json:"max"`
+}
+
+// GCSummary describes garbage collection during the trace.
+type GCSummary struct {
+	Cycles     int           `json:"cycles"`
+	Pauses     int           `json:"pauses"`
+	PauseTotal time.Duration `json:"pause_total"`
+	PauseMax   time.Duration `json:"pause_max"`
+	MarkAssist time.Duration `json:"mark_assist"` // time user goroutines spent helping the GC
+}
+
+// LatencySummary describes how long runnable goroutines waited to be scheduled.
+type LatencySummary struct {
+	Count int           `json:"count"`
+	Mean  time.Duration `json:"mean"`
+	P50   time.Duration `json:"p50"`
+	P90   time.Duration `json:"p90"`
+	P99   time.Duration `json:"p99"`
+	Max   time.Duration `json:"max"`
+}
+
+// runtimeWaitReasons are waits of the runtime's own background goroutines,
+// which are idle most of the time and would drown out the test's blocking.
+var runtimeWaitReasons = map[string]bool{
+	"system goroutine wait":      true,
+	"GC background sweeper wait": true,
+	"GC scavenge wait":           true,
+	"GC worker (idle)":           true,
+	"GC worker (active)":         true,
+	"finalizer wait":             true,
+	"force gc (idle)":            true,
+	"trace reader (blocked)":     true,
+	"wait for debug call":        true,
+	"cleanup wait":               true,
+}
+
+var (
+	traceTimeRe       = regexp.MustCompile(`\bTime=(\d+)`)
+	traceTransitionRe = regexp.MustCompile(`\bGoID=(\d+) (\w+)->(\w+) Reason="([^"]*)"`)
+	traceRangeRe      = regexp.MustCompile(`^(RangeBegin|RangeEnd) Time=\d+ Name="([^"]*)" Scope=(\S+)`)
+)
+
+// SummarizeTrace parses a trace with `go tool trace -d=parsed`, which prints one
+// line per event in a stable text form, and aggregates it. The trace format
+// itself changes between Go releases, so the toolchain of the project is left
+// to decode it.
+func SummarizeTrace(projectPath, tracePath string) (*TraceSummary, error) {
+	cmd := exec.Command("go", "tool", "trace", "-d=parsed", tracePath)
+	cmd.Dir = projectPath
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("could not run go tool trace: %w", err)
+	}
+
+	agg := newTraceAggregator()
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		agg.observe(scanner.Text())
+	}
+	scanErr := scanner.Err()
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("go tool trace failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if scanErr != nil {
+		return nil, fmt.Errorf("could not read trace: %w", scanErr)
+	}
+	return agg.summary(), nil
+}
+
+type waitStart struct {
+	at     int64
+	reason string
+}
+
+type traceAggregator struct {
+	start, end int64
+
+	states        map[uint64]string
+	counts        GoroutineSample
+	points        []GoroutineSample // Offset holds the absolute time until summary()
+	runnableSince map[uint64]int64
+	blockedSince  map[uint64]waitStart
+	blocking      map[string]*BlockingReason
+	latencies     []int64
+
+	openRanges map[string]int64
+	gc         GCSummary
+}
+
+func newTraceAggregator() *traceAggregator {
+	return &traceAggregator{
+		states:        make(map[uint64]string),
+		runnableSince: make(map[uint64]int64),
+		blockedSince:  make(map[uint64]waitStart),
+		blocking:      make(map[string]*BlockingReason),
+		openRanges:    make(map[string]int64),
+	}
+}
+
+// observe handles one line of `go tool trace -d=parsed` output. Event lines
+// look like
+//
+//	M=11822 P=0 G=1 StateTransition Time=1649475971328 GoID=1 Running->Waiting Reason="chan receive"
+//
+// and are followed by indented stack lines, which are skipped.
+func (a *traceAggregator) observe(line string) {
+	if !strings.HasPrefix(line, "M=") {
+		return
+	}
+	m := traceTimeRe.FindStringSubmatch(line)
+	if m == nil {
+		return
+	}
+	t, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return
+	}
+	if a.start == 0 || t < a.start {
+		a.start = t
+	}
+	if t > a.end {
+		a.end = t
+	}
+
+	fields := strings.SplitN(line, " ", 4) // M=, P=, G=, rest
+	if len(fields) < 4 {
+		return
+	}
+	event := fields[3]
+	switch {
+	case strings.HasPrefix(event, "StateTransition"):
+		if m := traceTransitionRe.FindStringSubmatch(event); m != nil {
+			id, _ := strconv.ParseUint(m[1], 10, 64)
+			a.transition(t, id, m[2], m[3], m[4])
+		}
+	case strings.HasPrefix(event, "Range"):
+		if m := traceRangeRe.FindStringSubmatch(event); m != nil {
+			a.rangeEvent(t, m[1] == "RangeBegin", m[2], m[3])
+		}
+	}
+}
+
+func (a *traceAggregator) transition(t int64, id uint64, from, to, reason string) {
+	if from == "Undetermined" {
+		// Goroutines that already existed when a trace generation started; only
+		// their state is known, not since when.
+		if _, known := a.states[id]; known {
+			return
+		}
+		from = "NotExist"
+	}
+	a.count(from, -1)
+	a.count(to, 1)
+	if to == "NotExist" {
+		delete(a.states, id)
+	} else {
+		a.states[id] = to
+	}
+	a.points = append(a.points, GoroutineSample{
+		Offset: time.Duration(t), Running: a.counts.Running, Runnable: a.counts.Runnable,
+		Waiting: a.counts.Waiting, Syscall: a.counts.Syscall,
+	})
+
+	switch {
+	case to == "Runnable":
+		a.runnableSince[id] = t
+	case from == "Runnable" && to == "Running":
+		if since, ok := a.runnableSince[id]; ok {
+			a.latencies = append(a.latencies, t-since)
+			delete(a.runnableSince, id)
+		}
+	}
+
+	switch {
+	case from == "Running" && to == "Waiting" && !runtimeWaitReasons[reason]:
+		if reason == "" {
+			reason = "unknown"
+		}
+		a.blockedSince[id] = waitStart{at: t, reason: reason}
+	case to == "Syscall":
+		a.blockedSince[id] = waitStart{at: t, reason: "syscall"}
+	case from == "Waiting" || from == "Syscall":
+		if w, ok := a.blockedSince[id]; ok {
+			a.block(w.reason, time.Duration(t-w.at))
+			delete(a.blockedSince, id)
+		}
+	}
+}
+
+func (a *traceAggregator) count(state string, delta int) {
+	switch state {
+	case "Running":
+		a.counts.Running += delta
+	case "Runnable":
+		a.counts.Runnable += delta
+	case "Waiting":
+		a.counts.Waiting += delta
+	case "Syscall":
+		a.counts.Syscall += delta
+	}
+}
+
+func (a *traceAggregator) block(reason string, d time.Duration) {
+	b := a.blocking[reason]
+	if b == nil {
+		b = &BlockingReason{Reason: reason}
+		a.blocking[reason] = b
+	}
+	b.Count++
+	b.Total += d
+	if d > b.Max {
+		b.Max = d
+	}
+}
+
+func (a *traceAggregator) rangeEvent(t int64, begin bool, name, scope string) {
+	key := name + " " + scope
+	if begin {
+		a.openRanges[key] = t
+		if name == "GC concurrent mark phase" {
+			a.gc.Cycles++
+		}
+		return
+	}
+	started, ok := a.openRanges[key]
+	if !ok {
+		return
+	}
+	delete(a.openRanges, key)
+	d := time.Duration(t - started)
+	switch {
+	case strings.HasPrefix(name, "stop-the-world") && strings.Contains(name, "GC"):
+		a.gc.Pauses++
+		a.gc.PauseTotal += d
+		if d > a.gc.PauseMax {
+			a.gc.PauseMax = d
+		}
+	case name == "GC mark assist":
+		a.gc.MarkAssist += d
+	}
+}
+
+func (a *traceAggregator) summary() *TraceSummary {
+	s := &TraceSummary{Duration: time.Duration(a.end - a.start), GC: a.gc}
+
+	// Downsample the timeline, keeping the busiest point of every bucket so
+	// short spikes stay visible.
+	width := (a.end - a.start) / maxTraceSamples
+	if width < 1 {
+		width = 1
+	}
+	bucket := int64(-1)
+	for _, p := range a.points {
+		if total := p.total(); total > s.MaxGoroutines {
+			s.MaxGoroutines = total
+		}
+		at := int64(p.Offset)
+		p.Offset = time.Duration(at - a.start)
+		if b := (at - a.start) / width; b != bucket || len(s.Goroutines) == 0 {
+			bucket = b
+			s.Goroutines = append(s.Goroutines, p)
+		} else if last := &s.Goroutines[len(s.Goroutines)-1]; p.total() >= last.total() {
+			*last = p
+		}
+	}
+
+	for _, b := range a.blocking {
+		s.Blocking = append(s.Blocking, *b)
+	}
+	sort.Slice(s.Blocking, func(i, j int) bool { return s.Blocking[i].Total > s.Blocking[j].Total })
+
+	if n := len(a.latencies); n > 0 {
+		sort.Slice(a.latencies, func(i, j int) bool { return a.latencies[i] < a.latencies[j] })
+		var sum int64
+		for _, l := range a.latencies {
+			sum += l
+		}
+		at := func(q float64) time.Duration { return time.Duration(a.latencies[int(q*float64(n-1))]) }
+		s.SchedLatency = LatencySummary{
+			Count: n,
+			Mean:  time.Duration(sum / int64(n)),
+			P50:   at(0.50),
+			P90:   at(0.90),
+			P99:   at(0.99),
+			Max:   time.Duration(a.latencies[n-1]),
+		}
+	}
+	return s
+}
@@ -0,0 +1,431 @@
+package dashboard
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Webhook events.
+const (
+	EventRunFinished     = "run.finished"     // every stored run
+	EventGateFailed      = "gate.failed"      // the run failed the quality gate
+	EventCoverageDropped = "coverage.dropped" // overall coverage is below the previous run's
+	EventPackageBroken   = "package.broken"   // a package that passed in the previous run fails
+	EventPing            = "ping"             // sent on demand to check a webhook
+)
+
+var webhookEvents = map[string]bool{
+	EventRunFinished: true, EventGateFailed: true, EventCoverageDropped: true, EventPackageBroken: true,
+}
+
+const (
+	webhookAttempts = 5               // deliveries are tried this often before giving up
+	webhookBackoff  = 2 * time.Second // wait before the first retry, doubled after each
+	webhookTimeout  = 10 * time.Second
+)
+
+// Webhook is an endpoint that receives a signed JSON payload for the events it
+// subscribes to.
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // signs payloads with HMAC-SHA256
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookPayload is the JSON body POSTed to a webhook.
+type WebhookPayload struct {
+	Event            string        `json:"event"`
+	Project          string        `json:"project"`
+	ProjectPath      string        `json:"project_path"`
+	RunID            string        `json:"run_id,omitempty"`
+	Commit           string        `json:"commit,omitempty"`
+	Timestamp        time.Time     `json:"timestamp"`
+	TotalPackages    int           `json:"total_packages"`
+	PassedPackages   int           `json:"passed_packages"`
+	FailedPackages   int           `json:"failed_packages"`
+	GatePassed       bool          `json:"gate_passed"`
+	Coverage         float64       `json:"coverage"`
+	PreviousCoverage *float64      `json:"previous_coverage,omitempty"`
+	CoverageDelta    *float64      `json:"coverage_delta,omitempty"`
+	FailingTests     []FailingTest `json:"failing_tests"`
+	BrokenPackages   []string      `json:"broken_packages,omitempty"` // passed in the previous run, fail now
+}
+
+// FailingTest is a failed test, or a failed package without a failed test.
+type FailingTest struct {
+	Package string `json:"package"`
+	Test    string `json:"test,omitempty"`
+}
+
+// WebhookDelivery is the log entry of one payload sent to one webhook.
+type WebhookDelivery struct {
+	ID        string            `json:"id"`
+	WebhookID string            `json:"webhook_id"`
+	URL       string            `json:"url"`
+	Event     string            `json:"event"`
+	RunID     string            `json:"run_id,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	Delivered bool              `json:"delivered"`
+	Pending   bool              `json:"pending"`
+	Attempts  []DeliveryAttempt `json:"attempts"`
+	Payload   json.RawMessage   `json:"payload"`
+}
+
+// DeliveryAttempt is one try of a delivery.
+type DeliveryAttempt struct {
+	At       time.Time     `json:"at"`
+	Status   int           `json:"status,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Webhooks is the persisted webhook configuration of a project and the log of
+// its deliveries.
+type Webhooks struct {
+	path       string
+	mu         sync.Mutex
+	hooks      []Webhook
+	deliveries recordStore
+	client     *http.Client
+}
+
+// NewWebhooks loads the webhooks stored alongside the project's history.
+func NewWebhooks(projectPath string) *Webhooks {
+	dir := projectDataDir(projectPath)
+	w := &Webhooks{
+		path:       filepath.Join(dir, "webhooks.json"),
+		deliveries: recordStore{Dir: filepath.Join(dir, "webhook-deliveries")},
+		client:     &http.Client{Timeout: webhookTimeout},
+	}
+	if content, err := os.ReadFile(w.path); err == nil {
+		json.Unmarshal(content, &w.hooks)
+	}
+	return w
+}
+
+// List returns the webhooks with their secrets masked.
+func (w *Webhooks) List() []Webhook {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	hooks := make([]Webhook, len(w.hooks))
+	for i, hook := range w.hooks {
+		if hook.Secret != "" {
+			hook.Secret = "********"
+		}
+		hooks[i] = hook
+	}
+	return hooks
+}
+
+// Add validates and stores a new webhook. Without events, it subscribes to all.
+func (w *Webhooks) Add(hook Webhook) (Webhook, error) {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Webhook{}, fmt.Errorf("invalid webhook URL %q", hook.URL)
+	}
+	if len(hook.Events) == 0 {
+		for event := range webhookEvents {
+			hook.Events = append(hook.Events, event)
+		}
+		sort.Strings(hook.Events)
+	}
+	for _, event := range hook.Events {
+		if !webhookEvents[event] {
+			return Webhook{}, fmt.Errorf("unknown webhook event %q", event)
+		}
+	}
+	hook.ID = newDeliveryID(time.Now())
+	hook.CreatedAt = time.Now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.hooks = append(w.hooks, hook)
+	return hook, w.save()
+}
+
+// Remove deletes a webhook. Its past deliveries stay in the log.
+func (w *Webhooks) Remove(id string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, hook := range w.hooks {
+		if hook.ID == id {
+			w.hooks = append(w.hooks[:i], w.hooks[i+1:]...)
+			return w.save()
+		}
+	}
+	return fmt.Errorf("webhook %s not found", id)
+}
+
+// Deliveries returns the logged deliveries, newest first, without payloads.
+func (w *Webhooks) Deliveries() ([]WebhookDelivery, error) {
+	ids, err := w.deliveries.ids()
+	if err != nil {
+		return nil, err
+	}
+	deliveries := make([]WebhookDelivery, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		var d WebhookDelivery
+		if err := w.deliveries.load(ids[i], &d); err != nil {
+			continue
+		}
+		d.Payload = nil
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+// Delivery returns a logged delivery including its payload.
+func (w *Webhooks) Delivery(id string) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+	if err := w.deliveries.load(id, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Ping sends a ping payload to one webhook, regardless of its events.
+func (w *Webhooks) Ping(id string, payload WebhookPayload) (*WebhookDelivery, error) {
+	w.mu.Lock()
+	var hook *Webhook
+	for i := range w.hooks {
+		if w.hooks[i].ID == id {
+			h := w.hooks[i]
+			hook = &h
+		}
+	}
+	w.mu.Unlock()
+	if hook == nil {
+		return nil, fmt.Errorf("webhook %s not found", id)
+	}
+	payload.Event = EventPing
+	return w.deliver(*hook, payload)
+}
+
+// Dispatch sends each payload to the webhooks subscribed to its event, in the
+// background.
+func (w *Webhooks) Dispatch(payloads []WebhookPayload) {
+	w.mu.Lock()
+	hooks := append([]Webhook{}, w.hooks...)
+	w.mu.Unlock()
+	for _, payload := range payloads {
+		for _, hook := range hooks {
+			for _, event := range hook.Events {
+				if event == payload.Event {
+					go w.deliver(hook, payload)
+				}
+			}
+		}
+	}
+}
+
+// deliver POSTs a payload until the webhook accepts it, a 4xx response other
+// than 429 says retrying won't help, or the attempts run out. Every attempt is
+// logged. Payloads are signed in the X-Azlo-Signature header as
+// "sha256=" + hex(HMAC-SHA256(secret, body)).
+func (w *Webhooks) deliver(hook Webhook, payload WebhookPayload) (*WebhookDelivery, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	d := &WebhookDelivery{
+		ID:        newDeliveryID(now),
+		WebhookID: hook.ID,
+		URL:       hook.URL,
+		Event:     payload.Event,
+		RunID:     payload.RunID,
+		CreatedAt: now,
+		Pending:   true,
+		Attempts:  []DeliveryAttempt{},
+		Payload:   body,
+	}
+	w.logDelivery(d)
+
+	backoff := webhookBackoff
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		start := time.Now()
+		status, err := w.post(hook, d.ID, payload.Event, body)
+		record := DeliveryAttempt{At: start, Status: status, Duration: time.Since(start)}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		d.Attempts = append(d.Attempts, record)
+		if err == nil && status >= 200 && status < 300 {
+			d.Delivered = true
+			break
+		}
+		if err == nil && status >= 400 && status < 500 && status != http.StatusTooManyRequests {
+			break
+		}
+		if attempt < webhookAttempts {
+			w.logDelivery(d)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	d.Pending = false
+	w.logDelivery(d)
+	if !d.Delivered {
+		log.Printf("Webhook delivery %s to %s failed after %d attempts", d.ID, hook.URL, len(d.Attempts))
+	}
+	return d, nil
+}
+
+// post makes one delivery attempt and returns the response status.
+func (w *Webhooks) post(hook Webhook, deliveryID, event string, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "azlo-test-suite")
+	req.Header.Set("X-Azlo-Event", event)
+	req.Header.Set("X-Azlo-Delivery", deliveryID)
+	if hook.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(hook.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Azlo-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
+
+func (w *Webhooks) logDelivery(d *WebhookDelivery) {
+	if err := w.deliveries.save(d.ID, d); err != nil {
+		log.Printf("Error logging webhook delivery %s: %v", d.ID, err)
+	}
+}
+
+// save writes the webhooks to disk, readable only by the user as they hold
+// secrets. Callers must hold w.mu.
+func (w *Webhooks) save() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o755); err != nil {
+		return fmt.Errorf("could not create data directory: %w", err)
+	}
+	content, err := json.MarshalIndent(w.hooks, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(w.path, content, 0o600)
+}
+
+// newDeliveryID returns a chronologically sortable, unique ID.
+func newDeliveryID(t time.Time) string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return newRunID(t) + "-" + hex.EncodeToString(suffix)
+}
+
+// webhookPayloads compares a finished run with the run before it and returns
+// a payload for every event the run triggers.
+func (td *TestDashboard) webhookPayloads(run, previous *RunRecord) []WebhookPayload {
+	data := run.Data
+	base := WebhookPayload{
+		Project:        td.ID,
+		ProjectPath:    td.ProjectPath,
+		RunID:          run.ID,
+		Commit:         run.Commit,
+		Timestamp:      time.Now(),
+		TotalPackages:  data.TotalTests,
+		PassedPackages: data.PassedTests,
+		FailedPackages: data.TotalTests - data.PassedTests,
+		GatePassed:     data.GatePassed,
+		Coverage:       data.OverallCoverage,
+		FailingTests:   []FailingTest{},
+	}
+	for _, r := range data.Results {
+		if r.Passed {
+			continue
+		}
+		failed := leafTests(failedTests(r.Tests))
+		if len(failed) == 0 {
+			base.FailingTests = append(base.FailingTests, FailingTest{Package: r.Package})
+		}
+		for _, name := range failed {
+			base.FailingTests = append(base.FailingTests, FailingTest{Package: r.Package, Test: name})
+		}
+	}
+	if previous != nil {
+		prev := previous.Data.OverallCoverage
+		delta := data.OverallCoverage - prev
+		base.PreviousCoverage, base.CoverageDelta = &prev, &delta
+		passedBefore := make(map[string]bool)
+		for _, r := range previous.Data.Results {
+			passedBefore[r.Package] = r.Passed
+		}
+		for _, r := range data.Results {
+			if !r.Passed && passedBefore[r.Package] {
+				base.BrokenPackages = append(base.BrokenPackages, r.Package)
+			}
+		}
+	}
+
+	events := []string{EventRunFinished}
+	if !data.GatePassed {
+		events = append(events, EventGateFailed)
+	}
+	// Ignore rounding noise; coverage is compared to a tenth of a percent.
+	if base.CoverageDelta != nil && *base.CoverageDelta < -0.05 {
+		events = append(events, EventCoverageDropped)
+	}
+	if len(base.BrokenPackages) > 0 {
+		events = append(events, EventPackageBroken)
+	}
+	payloads := make([]WebhookPayload, len(events))
+	for i, event := range events {
+		payloads[i] = base
+		payloads[i].Event = event
+	}
+	return payloads
+}
+
+// leafTests drops the tests that only failed because one of their subtests did.
+func leafTests(names []string) []string {
+	var leaves []string
+	for _, name := range names {
+		leaf := true
+		for _, other := range names {
+			if strings.HasPrefix(other, name+"/") {
+				leaf = false
+				break
+			}
+		}
+		if leaf {
+			leaves = append(leaves, name)
+		}
+	}
+	return leaves
+}
+
+// PingWebhook sends a ping with the current results to one webhook and waits
+// for the delivery to finish.
+func (td *TestDashboard) PingWebhook(id string) (*WebhookDelivery, error) {
+	run := &RunRecord{Data: td.Snapshot()}
+	if latest, err := td.History.Latest(); err == nil {
+		run = latest
+	}
+	payloads := td.webhookPayloads(run, nil)
+	return td.Webhooks.Ping(id, payloads[0])
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"azlo-test-suite/dashboard"
+
+	"github.com/gorilla/mux"
+)
+
+// BenchmarkListResponse lists the stored benchmark runs and the current baseline
+type BenchmarkListResponse struct {
+	Baseline string                   `json:"baseline"`
+	Runs     []dashboard.BenchmarkRun `json:"runs"`
+}
+
+// BaselineRequest represents the request body for choosing the baseline run
+type BaselineRequest struct {
+	ID string `json:"id"`
+}
+
+// HandleRunBenchmarks starts a benchmark run; progress is pushed over the WebSocket
+func (h *Handler) HandleRunBenchmarks(w http.ResponseWriter, r *http.Request) {
+	var opts dashboard.BenchmarkOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.dashboard(r).StartBenchmarks(opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(run)
+}
+
+// HandleListBenchmarks returns the stored benchmark runs, newest first
+func (h *Handler) HandleListBenchmarks(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.dashboard(r).Benchmarks.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BenchmarkListResponse{Baseline: h.dashboard(r).Benchmarks.Baseline(), Runs: runs})
+}
+
+// HandleGetBenchmarkRun returns a single stored benchmark run including its output
+func (h *Handler) HandleGetBenchmarkRun(w http.ResponseWriter, r *http.Request) {
+	run, err := h.dashboard(r).Benchmarks.Load(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Benchmark run not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+// HandleCompareBenchmarks compares two benchmark runs. The base defaults to the
+// baseline run and the head to the latest run.
+func (h *Handler) HandleCompareBenchmarks(w http.ResponseWriter, r *http.Request) {
+	base := r.URL.Query().Get("base")
+	if base == "" {
+		base = h.dashboard(r).Benchmarks.Baseline()
+	}
+	head := r.URL.Query().Get("head")
+	if head == "" {
+		head = h.dashboard(r).Benchmarks.Latest()
+	}
+	if base == "" || head == "" {
+		http.Error(w, "Both a base and a head run are required", http.StatusBadRequest)
+		return
+	}
+
+	comparison, err := h.dashboard(r).Benchmarks.CompareBenchmarks(base, head)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comparison)
+}
+
+// HandleSetBenchmarkBaseline marks a stored benchmark run as the baseline
+func (h *Handler) HandleSetBenchmarkBaseline(w http.ResponseWriter, r *http.Request) {
+	var req BaselineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := h.dashboard(r).Benchmarks.SetBaseline(req.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BaselineRequest{ID: req.ID})
+}
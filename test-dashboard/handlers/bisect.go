@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"azlo-test-suite/dashboard"
+)
+
+// HandleStartBisect starts bisecting a failing test between a good and a bad ref
+func (h *Handler) HandleStartBisect(w http.ResponseWriter, r *http.Request) {
+	var opts dashboard.BisectOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	bisect, err := h.dashboard(r).StartBisect(opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bisect)
+}
+
+// HandleGetBisect returns the most recent bisect of the project
+func (h *Handler) HandleGetBisect(w http.ResponseWriter, r *http.Request) {
+	bisect := h.dashboard(r).LatestBisect()
+	if bisect == nil {
+		http.Error(w, "No bisect has run", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bisect)
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// HandleBlameCoverageFile returns git blame for the statement lines of a file
+// of the current results, given by its cover profile name
+func (h *Handler) HandleBlameCoverageFile(w http.ResponseWriter, r *http.Request) {
+	file := r.URL.Query().Get("file")
+	if file == "" {
+		http.Error(w, "file is required", http.StatusBadRequest)
+		return
+	}
+	lines, err := h.dashboard(r).BlameCoverageFile(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lines)
+}
+
+// HandleBlameReport attributes covered and uncovered statements to authors and
+// commit ages
+func (h *Handler) HandleBlameReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.dashboard(r).BlameReport()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"azlo-test-suite/dashboard"
+)
+
+// HandleCompareRuns compares two stored runs. The head defaults to the latest
+// run and the base to the run before the head
+func (h *Handler) HandleCompareRuns(w http.ResponseWriter, r *http.Request) {
+	history := h.dashboard(r).History
+	query := r.URL.Query()
+
+	threshold := dashboard.DefaultDurationThreshold
+	if v := query.Get("threshold"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil || t <= 0 {
+			http.Error(w, "threshold must be a positive percentage", http.StatusBadRequest)
+			return
+		}
+		threshold = t
+	}
+
+	head := query.Get("head")
+	if head == "" {
+		if latest, err := history.Latest(); err == nil && latest != nil {
+			head = latest.ID
+		}
+	}
+	base := query.Get("base")
+	if base == "" && head != "" {
+		if previous, err := history.Previous(head); err == nil && previous != nil {
+			base = previous.ID
+		}
+	}
+	if base == "" || head == "" {
+		http.Error(w, "Both a base and a head run are required", http.StatusBadRequest)
+		return
+	}
+
+	comparison, err := history.CompareRuns(base, head, threshold)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comparison)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"azlo-test-suite/dashboard"
+)
+
+// StressRequest represents the request body for a stress run
+type StressRequest struct {
+	Package string `json:"package"`
+	Test    string `json:"test"`
+	Count   int    `json:"count"`
+}
+
+// maxStressCount keeps a typo from tying up the machine for hours.
+const maxStressCount = 10000
+
+// HandleFlakyTests returns the flakiness scores computed from the stored runs
+func (h *Handler) HandleFlakyTests(w http.ResponseWriter, r *http.Request) {
+	flaky, err := h.dashboard(r).FlakyTests()
+	if err != nil {
+		log.Printf("Error computing flaky tests: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(flaky)
+}
+
+// HandleStressTest runs one test N times and returns its failure rate; the run
+// stops if the client goes away
+func (h *Handler) HandleStressTest(w http.ResponseWriter, r *http.Request) {
+	var req StressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Package == "" || req.Test == "" {
+		http.Error(w, "Package and test are required", http.StatusBadRequest)
+		return
+	}
+	if req.Count < 1 || req.Count > maxStressCount {
+		http.Error(w, "Count must be between 1 and 10000", http.StatusBadRequest)
+		return
+	}
+
+	result := h.dashboard(r).StressTest(r.Context(), req.Package, req.Test, req.Count)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleQuarantine lists (GET), adds (POST) or removes (DELETE) quarantined tests
+func (h *Handler) HandleQuarantine(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		var entry dashboard.QuarantineEntry
+		if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		var err error
+		if r.Method == http.MethodDelete {
+			err = h.dashboard(r).Quarantine.Remove(entry.Package, entry.Test)
+		} else {
+			err = h.dashboard(r).Quarantine.Add(entry)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// The gate is refreshed once a run in progress is done; the response
+		// doesn't wait for it.
+		go h.dashboard(r).RefreshGate()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.dashboard(r).Quarantine.List())
+}
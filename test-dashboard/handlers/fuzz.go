@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// maxFuzzTime caps a fuzz session, which otherwise keeps every CPU busy for as
+// long as it was asked to.
+const maxFuzzTime = 24 * time.Hour
+
+// FuzzRequest represents the request body for starting, stopping or re-running a fuzz target
+type FuzzRequest struct {
+	Package  string `json:"package"`
+	Target   string `json:"target"`
+	Duration string `json:"duration,omitempty"` // e.g. "30s" or "10m"; start only
+	Input    string `json:"input,omitempty"`    // corpus entry name; re-run only
+}
+
+func decodeFuzzRequest(w http.ResponseWriter, r *http.Request) (FuzzRequest, bool) {
+	var req FuzzRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return req, false
+	}
+	if req.Package == "" || req.Target == "" {
+		http.Error(w, "Package and target are required", http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+// HandleListFuzzTargets returns the fuzz targets found in the project
+func (h *Handler) HandleListFuzzTargets(w http.ResponseWriter, r *http.Request) {
+	targets, err := h.dashboard(r).ListFuzzTargets()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(targets)
+}
+
+// HandleStartFuzz starts fuzzing a target; progress is pushed over the WebSocket
+func (h *Handler) HandleStartFuzz(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeFuzzRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.Duration == "" {
+		req.Duration = "1m"
+	}
+	fuzzTime, err := time.ParseDuration(req.Duration)
+	if err != nil || fuzzTime <= 0 {
+		http.Error(w, "Invalid duration", http.StatusBadRequest)
+		return
+	}
+	if fuzzTime > maxFuzzTime {
+		http.Error(w, "Duration must be at most "+maxFuzzTime.String(), http.StatusBadRequest)
+		return
+	}
+
+	status, err := h.dashboard(r).StartFuzz(req.Package, req.Target, fuzzTime)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(status)
+}
+
+// HandleStopFuzz stops a running fuzz session
+func (h *Handler) HandleStopFuzz(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeFuzzRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := h.dashboard(r).StopFuzz(req.Package, req.Target); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Fuzzing stopped"))
+}
+
+// HandleFuzzStatus returns the current or last session of a fuzz target, including its output
+func (h *Handler) HandleFuzzStatus(w http.ResponseWriter, r *http.Request) {
+	status, ok := h.dashboard(r).FuzzStatusOf(r.URL.Query().Get("package"), r.URL.Query().Get("target"))
+	if !ok {
+		http.Error(w, "No fuzz session for this target", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// HandleFuzzCorpus returns the seed corpus of a fuzz target
+func (h *Handler) HandleFuzzCorpus(w http.ResponseWriter, r *http.Request) {
+	corpus, err := h.dashboard(r).GetFuzzCorpus(r.URL.Query().Get("package"), r.URL.Query().Get("target"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(corpus)
+}
+
+// HandleRerunFuzzInput runs a fuzz target against one corpus entry; the run
+// stops if the client goes away
+func (h *Handler) HandleRerunFuzzInput(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeFuzzRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.Input == "" {
+		http.Error(w, "Input is required", http.StatusBadRequest)
+		return
+	}
+	result := h.dashboard(r).RerunFuzzInput(r.Context(), req.Package, req.Target, req.Input)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
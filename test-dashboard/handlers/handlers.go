@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"azlo-test-suite/dashboard" // <-- IMPORTANT: Replace with your module name
@@ -13,7 +17,62 @@ import (
 
 // Handler holds dependencies for the handlers
 type Handler struct {
-	Dashboard *dashboard.TestDashboard
+	Projects *dashboard.Projects
+}
+
+type projectKey struct{}
+
+// WithProject resolves the project a request is for, from the {project} route
+// variable or, on the unprefixed routes, the default project, and passes it on
+// to next. Unknown projects get a 404.
+func (h *Handler) WithProject(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		td := h.Projects.Default()
+		if id, ok := mux.Vars(r)["project"]; ok {
+			td = h.Projects.Get(id)
+		}
+		if td == nil {
+			http.Error(w, "Project not found", http.StatusNotFound)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), projectKey{}, td)))
+	}
+}
+
+// SameOrigin rejects requests that change state when they come from a page of
+// another origin or carry a body that isn't JSON. Browsers send such simple
+// requests cross-site without asking first, so without this any site open in
+// the user's browser could start runs or register projects.
+func SameOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.Header.Get("Sec-Fetch-Site") == "cross-site" {
+			http.Error(w, "Cross-origin requests are not allowed", http.StatusForbidden)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				http.Error(w, "Cross-origin requests are not allowed", http.StatusForbidden)
+				return
+			}
+		}
+		if r.ContentLength != 0 {
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				http.Error(w, "Request bodies must be application/json", http.StatusUnsupportedMediaType)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// dashboard returns the project resolved by WithProject.
+func (h *Handler) dashboard(r *http.Request) *dashboard.TestDashboard {
+	return r.Context().Value(projectKey{}).(*dashboard.TestDashboard)
 }
 
 // ProjectPathRequest represents the request body for setting project path
@@ -26,21 +85,20 @@ type ProjectPathResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
 	Path    string `json:"path,omitempty"`
+	ID      string `json:"id,omitempty"`
 }
 
 func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := h.Dashboard.Upgrader.Upgrade(w, r, nil)
+	conn, err := h.dashboard(r).Upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
 		return
 	}
 	defer conn.Close()
 
-	h.Dashboard.Clients[conn] = true
-	defer delete(h.Dashboard.Clients, conn)
-
 	// Send current data to new client
-	conn.WriteJSON(h.Dashboard.Data)
+	h.dashboard(r).AddClient(conn)
+	defer h.dashboard(r).RemoveClient(conn)
 
 	for {
 		if _, _, err := conn.ReadMessage(); err != nil {
@@ -50,16 +108,44 @@ func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) HandleRunTests(w http.ResponseWriter, r *http.Request) {
-	go h.Dashboard.RunTests()
+	// The body is optional; without one the run uses the default options.
+	var opts dashboard.RunOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil && err != io.EOF {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if opts.Integration != "" {
+		http.Error(w, "The integration command is read from "+dashboard.ConfigPath(h.dashboard(r).ProjectPath)+", not from requests", http.StatusBadRequest)
+		return
+	}
+	if err := opts.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts.Ref != "" {
+		if _, err := h.dashboard(r).ResolveRef(opts.Ref); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	go h.dashboard(r).RunTests(opts)
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Tests started"))
 }
 
+// HandleRerunFailed re-runs only the packages and tests that failed last time
+func (h *Handler) HandleRerunFailed(w http.ResponseWriter, r *http.Request) {
+	go h.dashboard(r).RerunFailed()
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Re-run started"))
+}
+
 func (h *Handler) ServeCoverageData(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	packageName := vars["package"]
 
-	for _, result := range h.Dashboard.Data.Results {
+	for _, result := range h.dashboard(r).Snapshot().Results {
 		// --- MODIFIED: Make package name matching more robust ---
 		// The backend might store names like "./calculator" while the frontend requests "calculator"
 		if result.Package == packageName || strings.TrimPrefix(result.Package, "./") == packageName {
@@ -72,7 +158,8 @@ func (h *Handler) ServeCoverageData(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Package not found", http.StatusNotFound)
 }
 
-// HandleSetProjectPath sets the project root directory
+// HandleSetProjectPath registers a project and makes it the default one. The
+// UI uses the registry instead; this keeps older clients working.
 func (h *Handler) HandleSetProjectPath(w http.ResponseWriter, r *http.Request) {
 	var req ProjectPathRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -85,7 +172,10 @@ func (h *Handler) HandleSetProjectPath(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.Dashboard.SetProjectPath(req.Path)
+	td, err := h.Projects.Add(req.Path)
+	if err == nil {
+		err = h.Projects.SetDefault(td.ID)
+	}
 	w.Header().Set("Content-Type", "application/json")
 
 	if err != nil {
@@ -101,14 +191,15 @@ func (h *Handler) HandleSetProjectPath(w http.ResponseWriter, r *http.Request) {
 	response := ProjectPathResponse{
 		Success: true,
 		Message: "Project path updated successfully",
-		Path:    req.Path,
+		Path:    td.ProjectPath,
+		ID:      td.ID,
 	}
 	json.NewEncoder(w).Encode(response)
 }
 
 // HandleGetProjectInfo returns current project information
 func (h *Handler) HandleGetProjectInfo(w http.ResponseWriter, r *http.Request) {
-	info := h.Dashboard.GetProjectInfo()
+	info := h.dashboard(r).GetProjectInfo()
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(info)
 }
@@ -123,7 +214,7 @@ func (h *Handler) HandleHTMLCoverage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	htmlContent, err := h.Dashboard.GetHTMLCoverage(filename)
+	htmlContent, err := h.dashboard(r).GetHTMLCoverage(filename)
 	if err != nil {
 		log.Printf("Error serving HTML coverage: %v", err)
 		http.Error(w, "Coverage file not found", http.StatusNotFound)
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"azlo-test-suite/dashboard"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleListRuns returns summaries of the stored runs, newest first,
+// optionally only those of the commits starting with the commit parameter
+func (h *Handler) HandleListRuns(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.dashboard(r).History.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if commit := r.URL.Query().Get("commit"); commit != "" {
+		matching := []dashboard.RunSummary{}
+		for _, run := range runs {
+			if strings.HasPrefix(run.Commit, commit) {
+				matching = append(matching, run)
+			}
+		}
+		runs = matching
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+// HandleListCommits returns the tested commits, most recently tested first
+func (h *Handler) HandleListCommits(w http.ResponseWriter, r *http.Request) {
+	commits, err := h.dashboard(r).History.Commits()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(commits)
+}
+
+// HandleGetRun returns a single stored run
+func (h *Handler) HandleGetRun(w http.ResponseWriter, r *http.Request) {
+	run, err := h.dashboard(r).History.Load(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+// HandleExportRunZip downloads a stored run as a zipped static report
+func (h *Handler) HandleExportRunZip(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	files, err := h.dashboard(r).ExportRun(id)
+	if err != nil {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename="+strconv.Quote("test-report-"+id+".zip"))
+	if err := dashboard.WriteExportZip(w, "test-report-"+id, files); err != nil {
+		log.Printf("Error writing the report of run %s: %v", id, err)
+	}
+}
+
+// HandleRunSummaryMarkdown renders a stored run, or "latest", as Markdown for
+// a pull request comment, optionally compared with another run
+func (h *Handler) HandleRunSummaryMarkdown(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := dashboard.SummaryOptions{Compare: query.Get("compare"), DiffBase: query.Get("base")}
+	if v := query.Get("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "top must be a positive number", http.StatusBadRequest)
+			return
+		}
+		opts.Top = n
+	}
+	if v := query.Get("threshold"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil || t <= 0 {
+			http.Error(w, "threshold must be a positive percentage", http.StatusBadRequest)
+			return
+		}
+		opts.Threshold = t
+	}
+
+	summary, err := h.dashboard(r).SummaryMarkdown(mux.Vars(r)["id"], opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Write([]byte(summary))
+}
+
+// HandleCoverageBadge renders the latest stored run's coverage, or that of the
+// package parameter, as an SVG badge
+func (h *Handler) HandleCoverageBadge(w http.ResponseWriter, r *http.Request) {
+	badge, found := h.dashboard(r).CoverageBadge(r.URL.Query().Get("package"))
+	writeBadge(w, r, badge, found)
+}
+
+// HandleStatusBadge renders whether the latest stored run, or the package
+// parameter, passed as an SVG badge
+func (h *Handler) HandleStatusBadge(w http.ResponseWriter, r *http.Request) {
+	badge, found := h.dashboard(r).StatusBadge(r.URL.Query().Get("package"))
+	writeBadge(w, r, badge, found)
+}
+
+// writeBadge sends a badge that must not be cached, so embedded badges stay
+// live. The label parameter replaces the badge's label.
+func writeBadge(w http.ResponseWriter, r *http.Request, badge dashboard.Badge, found bool) {
+	if label := r.URL.Query().Get("label"); label != "" {
+		badge.Label = label
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+	}
+	w.Write(badge.SVG())
+}
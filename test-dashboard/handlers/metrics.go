@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+)
+
+// HandleMetrics serves the metrics of every project in the Prometheus text format
+func (h *Handler) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := h.Projects.WriteMetrics(w); err != nil {
+		log.Printf("Error writing metrics: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"azlo-test-suite/dashboard"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleRunMutations starts a mutation testing run; progress is pushed over the WebSocket
+func (h *Handler) HandleRunMutations(w http.ResponseWriter, r *http.Request) {
+	var opts dashboard.MutationOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.dashboard(r).StartMutations(opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(run)
+}
+
+// HandleListMutations returns the stored mutation runs without their mutants, newest first
+func (h *Handler) HandleListMutations(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.dashboard(r).Mutations.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+// HandleGetMutationRun returns a single stored mutation run including every mutant
+func (h *Handler) HandleGetMutationRun(w http.ResponseWriter, r *http.Request) {
+	run, err := h.dashboard(r).Mutations.Load(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Mutation run not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultProfileTop is how many functions the profile top table lists by default
+const defaultProfileTop = 30
+
+// HandleGetArtifact downloads a raw artifact of a stored run, e.g. for `go tool pprof`
+func (h *Handler) HandleGetArtifact(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	path, err := h.dashboard(r).ArtifactPath(vars["id"], vars["name"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Disposition", "attachment; filename="+strconv.Quote(filepath.Base(path)))
+	http.ServeFile(w, r, path)
+}
+
+// HandleGetProfile returns the flame graph and top table of a stored profile
+func (h *Handler) HandleGetProfile(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+
+	top := defaultProfileTop
+	if v := query.Get("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "top must be a positive number", http.StatusBadRequest)
+			return
+		}
+		top = n
+	}
+
+	summary, err := h.dashboard(r).ProfileArtifact(vars["id"], vars["name"], query.Get("sample"), top)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
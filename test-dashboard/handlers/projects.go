@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleListProjects returns the registered projects
+func (h *Handler) HandleListProjects(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Projects.List())
+}
+
+// HandleAddProject registers the project at the path in the request body
+func (h *Handler) HandleAddProject(w http.ResponseWriter, r *http.Request) {
+	var req ProjectPathRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Path == "" {
+		http.Error(w, "Path is required", http.StatusBadRequest)
+		return
+	}
+
+	td, err := h.Projects.Add(req.Path)
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ProjectPathResponse{Success: false, Message: err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(ProjectPathResponse{
+		Success: true,
+		Message: "Project added",
+		Path:    td.ProjectPath,
+		ID:      td.ID,
+	})
+}
+
+// HandleRemoveProject unregisters a project
+func (h *Handler) HandleRemoveProject(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["project"]
+	if h.Projects.Get(id) == nil {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	}
+	if err := h.Projects.Remove(id); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
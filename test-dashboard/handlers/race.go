@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// HandleRaceHistory returns race counts over the stored runs made with -race
+func (h *Handler) HandleRaceHistory(w http.ResponseWriter, r *http.Request) {
+	history, err := h.dashboard(r).RaceHistory()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
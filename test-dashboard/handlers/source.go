@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// HandleGetSource returns a Go file of the project for the source viewer
+func (h *Handler) HandleGetSource(w http.ResponseWriter, r *http.Request) {
+	file, err := h.dashboard(r).ReadSource(r.URL.Query().Get("path"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(file)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleLineTests lists the tests of a run that execute a source line
+func (h *Handler) HandleLineTests(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	line, err := strconv.Atoi(query.Get("line"))
+	if err != nil || line < 1 || query.Get("file") == "" {
+		http.Error(w, "file and a positive line are required", http.StatusBadRequest)
+		return
+	}
+
+	tests, err := h.dashboard(r).TestsCoveringLine(mux.Vars(r)["id"], query.Get("file"), line)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tests)
+}
+
+// HandleImpactedTests lists the tests of a run that execute code changed since a git ref
+func (h *Handler) HandleImpactedTests(w http.ResponseWriter, r *http.Request) {
+	base := r.URL.Query().Get("base")
+	if base == "" {
+		base = "HEAD"
+	}
+
+	report, err := h.dashboard(r).ImpactedTests(mux.Vars(r)["id"], base)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
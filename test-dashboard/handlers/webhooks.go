@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"azlo-test-suite/dashboard"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleWebhooks lists (GET), adds (POST) or removes (DELETE, by id) webhooks
+func (h *Handler) HandleWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks := h.dashboard(r).Webhooks
+	if r.Method != http.MethodGet {
+		var hook dashboard.Webhook
+		if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		var err error
+		if r.Method == http.MethodDelete {
+			err = webhooks.Remove(hook.ID)
+		} else {
+			_, err = webhooks.Add(hook)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhooks.List())
+}
+
+// HandlePingWebhook sends a ping to a webhook and returns the delivery
+func (h *Handler) HandlePingWebhook(w http.ResponseWriter, r *http.Request) {
+	delivery, err := h.dashboard(r).PingWebhook(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	delivery.Payload = nil
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// HandleWebhookDeliveries returns the delivery log, newest first
+func (h *Handler) HandleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := h.dashboard(r).Webhooks.Deliveries()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// HandleGetWebhookDelivery returns a logged delivery with its payload
+func (h *Handler) HandleGetWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	delivery, err := h.dashboard(r).Webhooks.Delivery(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(delivery)
+}
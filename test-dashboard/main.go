@@ -3,11 +3,14 @@ package main
 import (
 	"bytes" // Import the bytes package
 	"embed"
+	"flag"
 	"fmt"
 	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"time" // Import the time package
 
 	"azlo-test-suite/dashboard" // <-- Replace with your module path
@@ -20,25 +23,39 @@ import (
 var staticFiles embed.FS
 
 func main() {
-	// 1. Initialize the core application
-	dash := dashboard.NewTestDashboard()
-	go dash.BroadcastUpdates()
+	if len(os.Args) > 1 && os.Args[1] == "summary" {
+		os.Exit(summaryCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		os.Exit(exportCommand(os.Args[2:]))
+	}
+
+	// 1. Initialize the project registry; the working directory is the default project
+	workDir, _ := os.Getwd()
+	projects := dashboard.NewProjects(workDir)
 
-	// 2. Initialize the handlers with the dashboard instance
-	h := &handlers.Handler{Dashboard: dash}
+	// 2. Initialize the handlers with the registry
+	h := &handlers.Handler{Projects: projects}
 
 	// 3. Set up the router
 	r := mux.NewRouter()
+	r.Use(handlers.SameOrigin)
 
-	// API and WebSocket routes
-	r.HandleFunc("/ws", h.HandleWebSocket)
-	r.HandleFunc("/run-tests", h.HandleRunTests).Methods("POST")
-	r.HandleFunc("/coverage/{package}", h.ServeCoverageData)
-	r.HandleFunc("/html-coverage/{filename}", h.HandleHTMLCoverage).Methods("GET")
+	// Project registry
+	r.HandleFunc("/projects", h.HandleListProjects).Methods("GET")
+	r.HandleFunc("/projects", h.HandleAddProject).Methods("POST")
+	r.HandleFunc("/projects/{project}", h.HandleRemoveProject).Methods("DELETE")
+
+	// Prometheus metrics of all projects
+	r.HandleFunc("/metrics", h.HandleMetrics).Methods("GET")
+
+	// Every project has its routes under /projects/{project}; the unprefixed
+	// routes serve the default project.
+	registerProjectRoutes(r.PathPrefix("/projects/{project}").Subrouter(), h)
+	registerProjectRoutes(r, h)
 
-	// New project path management routes
+	// Kept for older clients: registers a project and makes it the default
 	r.HandleFunc("/set-project-path", h.HandleSetProjectPath).Methods("POST")
-	r.HandleFunc("/project-info", h.HandleGetProjectInfo).Methods("GET")
 
 	// Create a sub-filesystem for the static directory
 	staticFS, err := fs.Sub(staticFiles, "static")
@@ -78,3 +95,180 @@ func main() {
 	fmt.Printf("📊 Open in your browser to see live test results and coverage\n")
 	log.Fatal(http.ListenAndServe(":"+port, r))
 }
+
+// registerProjectRoutes adds the routes that act on a single project to r.
+func registerProjectRoutes(r *mux.Router, h *handlers.Handler) {
+	handle := func(path string, f http.HandlerFunc) *mux.Route {
+		return r.HandleFunc(path, h.WithProject(f))
+	}
+
+	// API and WebSocket routes
+	handle("/ws", h.HandleWebSocket)
+	handle("/run-tests", h.HandleRunTests).Methods("POST")
+	handle("/rerun-failed", h.HandleRerunFailed).Methods("POST")
+	handle("/coverage/{package}", h.ServeCoverageData)
+	handle("/html-coverage/{filename}", h.HandleHTMLCoverage).Methods("GET")
+	handle("/source", h.HandleGetSource).Methods("GET")
+	handle("/project-info", h.HandleGetProjectInfo).Methods("GET")
+
+	// Run history, flaky test tracking and quarantine
+	handle("/runs", h.HandleListRuns).Methods("GET")
+	handle("/runs/{id}", h.HandleGetRun).Methods("GET")
+	handle("/commits", h.HandleListCommits).Methods("GET")
+	handle("/compare", h.HandleCompareRuns).Methods("GET")
+	handle("/badge/coverage.svg", h.HandleCoverageBadge).Methods("GET")
+	handle("/badge/status.svg", h.HandleStatusBadge).Methods("GET")
+	handle("/blame", h.HandleBlameCoverageFile).Methods("GET")
+	handle("/blame/report", h.HandleBlameReport).Methods("GET")
+	handle("/bisect", h.HandleGetBisect).Methods("GET")
+	handle("/bisect", h.HandleStartBisect).Methods("POST")
+	handle("/runs/{id}/artifacts/{name}", h.HandleGetArtifact).Methods("GET")
+	handle("/runs/{id}/profile/{name}", h.HandleGetProfile).Methods("GET")
+	handle("/runs/{id}/line-tests", h.HandleLineTests).Methods("GET")
+	handle("/runs/{id}/impacted", h.HandleImpactedTests).Methods("GET")
+	handle("/runs/{id}/export", h.HandleExportRunZip).Methods("GET")
+	handle("/runs/{id}/summary.md", h.HandleRunSummaryMarkdown).Methods("GET")
+	handle("/flaky", h.HandleFlakyTests).Methods("GET")
+	handle("/stress", h.HandleStressTest).Methods("POST")
+	handle("/quarantine", h.HandleQuarantine).Methods("GET", "POST", "DELETE")
+	handle("/races", h.HandleRaceHistory).Methods("GET")
+
+	// Benchmarks
+	handle("/benchmarks", h.HandleListBenchmarks).Methods("GET")
+	handle("/benchmarks/run", h.HandleRunBenchmarks).Methods("POST")
+	handle("/benchmarks/compare", h.HandleCompareBenchmarks).Methods("GET")
+	handle("/benchmarks/baseline", h.HandleSetBenchmarkBaseline).Methods("POST")
+	handle("/benchmarks/{id}", h.HandleGetBenchmarkRun).Methods("GET")
+
+	// Mutation testing
+	handle("/mutations", h.HandleListMutations).Methods("GET")
+	handle("/mutations/run", h.HandleRunMutations).Methods("POST")
+	handle("/mutations/{id}", h.HandleGetMutationRun).Methods("GET")
+
+	// Webhooks
+	handle("/webhooks", h.HandleWebhooks).Methods("GET", "POST", "DELETE")
+	handle("/webhooks/deliveries", h.HandleWebhookDeliveries).Methods("GET")
+	handle("/webhooks/deliveries/{id}", h.HandleGetWebhookDelivery).Methods("GET")
+	handle("/webhooks/{id}/ping", h.HandlePingWebhook).Methods("POST")
+
+	// Fuzzing
+	handle("/fuzz/targets", h.HandleListFuzzTargets).Methods("GET")
+	handle("/fuzz/start", h.HandleStartFuzz).Methods("POST")
+	handle("/fuzz/stop", h.HandleStopFuzz).Methods("POST")
+	handle("/fuzz/status", h.HandleFuzzStatus).Methods("GET")
+	handle("/fuzz/corpus", h.HandleFuzzCorpus).Methods("GET")
+	handle("/fuzz/rerun", h.HandleRerunFuzzInput).Methods("POST")
+}
+
+// summaryCommand prints the Markdown summary of a stored run, e.g. for CI to
+// post as a pull request comment. It reads the run history directly, so no
+// server needs to be running.
+func summaryCommand(args []string) int {
+	fs := flag.NewFlagSet("summary", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s summary [flags]\n\nPrints a Markdown summary of a stored test run.\n\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	project := fs.String("project", ".", "project directory")
+	run := fs.String("run", "latest", "ID of the run to summarize")
+	compare := fs.String("compare", "", `ID of a run to compare against, or "previous"`)
+	base := fs.String("base", "", "git ref to measure diff coverage against (default: the compared run's commit)")
+	top := fs.Int("top", 10, "length of the lists of uncovered lines, failing tests and slowest packages")
+	threshold := fs.Float64("threshold", dashboard.DefaultDurationThreshold, "slowdown in percent that counts as a duration regression")
+	output := fs.String("o", "", "write the summary to this file instead of standard output")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	path, err := filepath.Abs(*project)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	td := dashboard.NewTestDashboard(path)
+	summary, err := td.SummaryMarkdown(*run, dashboard.SummaryOptions{
+		Compare:   *compare,
+		DiffBase:  *base,
+		Top:       *top,
+		Threshold: *threshold,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "summary:", err)
+		return 1
+	}
+	if *output == "" {
+		fmt.Print(summary)
+		return 0
+	}
+	if err := os.WriteFile(*output, []byte(summary), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// exportCommand writes the static report of a stored run to a directory or a
+// zip archive, e.g. for CI to publish as an artifact. Like summary, it reads
+// the run history directly.
+func exportCommand(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s export [flags]\n\nWrites a stored test run as a static HTML report.\n\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	project := fs.String("project", ".", "project directory")
+	run := fs.String("run", "latest", "ID of the run to export")
+	dir := fs.String("o", "", "directory to write the report to")
+	zipFile := fs.String("zip", "", "write the report to this zip archive instead of a directory")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if (*dir == "") == (*zipFile == "") {
+		fmt.Fprintln(os.Stderr, "export: exactly one of -o and -zip is required")
+		return 2
+	}
+
+	path, err := filepath.Abs(*project)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	td := dashboard.NewTestDashboard(path)
+	if *dir != "" {
+		target, err := filepath.Abs(*dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		written, err := td.ExportRunDir(*run, target)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "export:", err)
+			return 1
+		}
+		fmt.Printf("Wrote %d files to %s\n", len(written), target)
+		return 0
+	}
+
+	files, err := td.ExportRun(*run)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "export:", err)
+		return 1
+	}
+	out, err := os.Create(*zipFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	root := strings.TrimSuffix(filepath.Base(*zipFile), ".zip")
+	if err := dashboard.WriteExportZip(out, root, files); err != nil {
+		out.Close()
+		fmt.Fprintln(os.Stderr, "export:", err)
+		return 1
+	}
+	if err := out.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Wrote %s\n", *zipFile)
+	return 0
+}
@@ -1,8 +1,16 @@
 let ws;
+let currentData = {};
+// The project shown; every request goes to its /projects/{id} routes.
+let currentProject = localStorage.getItem('project') || '';
+
+// api returns the URL of a route of the current project.
+function api(path) {
+    return currentProject ? `/projects/${encodeURIComponent(currentProject)}${path}` : path;
+}
 
 function connectWebSocket() {
     const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-    ws = new WebSocket(`${protocol}//${window.location.host}/ws`);
+    const socket = ws = new WebSocket(`${protocol}//${window.location.host}${api('/ws')}`);
 
     ws.onopen = function() {
         console.log('WebSocket connected');
@@ -10,14 +18,20 @@ function connectWebSocket() {
     };
 
     ws.onmessage = function(event) {
+        if (socket !== ws) return;
         const data = JSON.parse(event.data);
-        updateDashboard(data);
+        if (data.type) {
+            handleEvent(data.type, data.payload);
+        } else {
+            updateDashboard(data);
+        }
     };
 
     ws.onclose = function() {
+        if (socket !== ws) return; // replaced after switching projects
         console.log('WebSocket disconnected');
         document.getElementById('results').innerHTML = '<div class="loading">Connection lost. Retrying...</div>';
-        setTimeout(connectWebSocket, 3000);
+        setTimeout(() => { if (socket === ws) connectWebSocket(); }, 3000);
     };
 
     ws.onerror = function(error) {
@@ -25,7 +39,37 @@ function connectWebSocket() {
     };
 }
 
+// Typed events carry progress of long-running jobs next to the regular snapshots.
+function handleEvent(type, payload) {
+    switch (type) {
+        case 'benchmark':
+            updateBenchmarkProgress(payload);
+            break;
+        case 'fuzz':
+            updateFuzzProgress(payload);
+            break;
+        case 'package':
+            updatePackageProgress(payload);
+            break;
+        case 'mutation':
+            updateMutationProgress(payload);
+            break;
+        case 'bisect':
+            renderBisect(payload);
+            break;
+        case 'run':
+            showRunFailure(payload);
+            break;
+        case 'stress':
+            updateStressProgress(payload);
+            break;
+        default:
+            console.log('Unhandled event', type, payload);
+    }
+}
+
 function updateDashboard(data) {
+    currentData = data;
     updateProjectInfo(data);
 
     const overallCoverage = data.overall_coverage || 0;
@@ -37,9 +81,15 @@ function updateDashboard(data) {
     const coverageEl = document.getElementById('overall-coverage');
     coverageEl.className = `stat-number ${getCoverageClass(overallCoverage)}`;
 
+    updateQualityGate(data);
+    updateUntestedPackages(data);
+    updateIntegrationSummary(data);
+
     const resultsEl = document.getElementById('results');
     if (data.results && data.results.length > 0) {
-        resultsEl.innerHTML = data.results.map(result => createPackageHTML(result)).join('');
+        resultsEl.innerHTML = data.modules && data.modules.length > 1
+            ? createModuleGroupsHTML(data.modules, data.results)
+            : data.results.map(result => createPackageHTML(result)).join('');
         delete resultsEl.dataset.isRunning;
     } else if (data.results && resultsEl.dataset.isRunning === "true") {
         resultsEl.innerHTML = '';
@@ -51,7 +101,106 @@ function updateDashboard(data) {
         const lastRunEl = document.getElementById('last-run');
         const lastRun = new Date(data.last_run);
         lastRunEl.textContent = `Last run: ${lastRun.toLocaleTimeString()}`;
+        if (data.run_id) {
+            lastRunEl.insertAdjacentHTML('beforeend',
+                ` · <a href="${api(`/runs/${encodeURIComponent(data.run_id)}/export`)}" title="A static copy of this run's results and coverage that opens offline">📦 Download report</a>`);
+        }
+    }
+}
+
+// Packages that have been running for a while, keyed by package.
+const slowPackages = {};
+
+function updatePackageProgress(progress) {
+    if (progress.status === 'finished') {
+        delete slowPackages[progress.package];
+    } else {
+        slowPackages[progress.package] = progress;
+    }
+    const target = document.getElementById('run-progress');
+    target.innerHTML = Object.values(slowPackages).map(p => {
+        const elapsed = formatNanos(p.elapsed);
+        if (p.status === 'hung') {
+            return `<div class="run-progress-item hung">⚠ ${escapeHtml(p.package)} hung after ${elapsed} — collecting goroutine dump...</div>`;
+        }
+        return `<div class="run-progress-item">⏳ ${escapeHtml(p.package)} still running (${elapsed}, watchdog at ${formatNanos(p.timeout)})</div>`;
+    }).join('');
+}
+
+// showRunFailure replaces the "Running tests..." placeholder when a run could
+// not start, e.g. because its ref doesn't exist.
+function showRunFailure(failure) {
+    const resultsEl = document.getElementById('results');
+    if (resultsEl.dataset.isRunning !== "true") return;
+    delete resultsEl.dataset.isRunning;
+    resultsEl.innerHTML = `<div class="loading">Tests could not run: ${escapeHtml(failure.error)}</div>`;
+}
+
+// updateUntestedPackages lists packages without test files, which count as 0%
+// in the overall coverage, largest first.
+function updateUntestedPackages(data) {
+    const target = document.getElementById('untested-packages');
+    const untested = (data.results || []).filter(result => result.no_tests)
+        .sort((a, b) => (b.statements || 0) - (a.statements || 0));
+    if (untested.length === 0) {
+        target.innerHTML = '';
+        return;
+    }
+    const total = (data.results || []).reduce((sum, result) => sum + (result.statements || 0), 0);
+    const missing = untested.reduce((sum, result) => sum + (result.statements || 0), 0);
+    const share = total > 0 ? ` (${(missing / total * 100).toFixed(1)}% of all statements)` : '';
+    target.innerHTML = `
+        <div class="untested-header">🕳 Untested packages: ${untested.length}, ${missing} statements${share}</div>
+        <ul class="untested-list">
+            ${untested.map(result => `
+                <li>
+                    <span class="untested-package">${escapeHtml(result.import_path || result.package)}</span>
+                    <span class="untested-statements">${result.statements || 0} statements</span>
+                </li>`).join('')}
+        </ul>`;
+}
+
+// updateIntegrationSummary shows how the integration command of the run went
+// and how much of the project it covered.
+function updateIntegrationSummary(data) {
+    const target = document.getElementById('integration-summary');
+    const run = data.integration;
+    if (!run) {
+        target.innerHTML = '';
+        return;
+    }
+    const status = run.timed_out ? 'TIMED OUT' : run.passed ? 'PASSED' : 'FAILED';
+    const coverage = run.artifact
+        ? `<span class="${getCoverageClass(run.coverage)}">${run.coverage.toFixed(1)}%</span> of statements in ${run.packages} package${run.packages === 1 ? '' : 's'}`
+        : '';
+    target.innerHTML = `
+        <div class="integration-header">
+            🔗 Integration <code>${escapeHtml(run.command)}</code>
+            <span class="status-badge ${run.passed ? 'passed' : 'failed'}">${status}</span>
+            <span class="duration">${formatNanos(run.duration)}</span>
+            ${coverage}
+        </div>
+        ${run.error ? `<div class="integration-error">⚠ ${escapeHtml(run.error)}</div>` : ''}
+        <details class="integration-output">
+            <summary>Output</summary>
+            <pre>${escapeHtml(run.output || '(no output)')}</pre>
+        </details>`;
+}
+
+function updateQualityGate(data) {
+    const gateEl = document.getElementById('quality-gate');
+    const labelEl = document.getElementById('quality-gate-label');
+    if (!data.results || data.results.length === 0) {
+        gateEl.textContent = '–';
+        gateEl.className = 'stat-number';
+        labelEl.textContent = 'Quality Gate';
+        return;
     }
+    gateEl.textContent = data.gate_passed ? 'PASS' : 'FAIL';
+    gateEl.className = `stat-number ${data.gate_passed ? 'gate-passed' : 'gate-failed'}`;
+    labelEl.textContent = data.quarantined_failures
+        ? `Quality Gate (${data.quarantined_failures} quarantined)`
+        : 'Quality Gate';
 }
 
 function updateProjectInfo(data) {
@@ -61,6 +210,16 @@ function updateProjectInfo(data) {
     if (data.project_path) {
         document.getElementById('project-path-text').textContent = data.project_path;
     }
+    const gitEl = document.getElementById('git-info');
+    const git = data.git;
+    if (!git) {
+        gitEl.innerHTML = '';
+        return;
+    }
+    gitEl.title = git.subject || '';
+    gitEl.innerHTML = `⎇ ${escapeHtml(git.ref || git.branch || 'detached')} @ ${escapeHtml(git.commit.substring(0, 8))}`
+        + (git.ref ? ' (worktree)' : '')
+        + (git.dirty ? ' <span class="dirty">+ local changes</span>' : '');
 }
 
 function getCoverageClass(coverage) {
@@ -75,12 +234,24 @@ function escapeHtml(text) {
     }
     const div = document.createElement('div');
     div.textContent = text;
-    return div.innerHTML;
+    return div.innerHTML.replace(/"/g, '&quot;').replace(/'/g, '&#39;');
+}
+
+// onAction calls handler with the data- attributes of any element marked
+// data-action="name" when it is clicked. Values travel in data- attributes
+// rather than inline onclick code, so they are never parsed as script.
+function onAction(name, handler) {
+    document.addEventListener('click', event => {
+        const el = event.target.closest(`[data-action="${name}"]`);
+        if (!el) return;
+        event.preventDefault();
+        handler(el.dataset);
+    });
 }
 
 function createPackageHTML(result) {
-    const statusClass = result.passed ? 'passed' : 'failed';
-    const statusText = result.passed ? 'PASSED' : 'FAILED';
+    const statusClass = result.no_tests ? 'untested' : result.passed ? 'passed' : 'failed';
+    const statusText = result.no_tests ? 'NO TESTS' : result.passed ? 'PASSED' : (result.timed_out ? 'TIMED OUT' : 'FAILED');
     const coverageClass = getCoverageClass(result.coverage || 0);
     const duration = result.duration ? (result.duration / 1000000).toFixed(0) : '0';
 
@@ -91,24 +262,175 @@ function createPackageHTML(result) {
     if (result.html_coverage_file) {
         coverageButtons += `<button class="coverage-link html-coverage-link" onclick="event.stopPropagation(); openHTMLCoverage('${escapeHtml(result.html_coverage_file || '')}')">📋 HTML Report</button>`;
     }
+    if (result.races && result.races.length > 0) {
+        coverageButtons += `<button class="coverage-link dump-link" onclick="event.stopPropagation(); showRaces()">🏁 ${result.races.length} Data Race${result.races.length === 1 ? '' : 's'}</button>`;
+    }
+    if (result.goroutine_dump) {
+        coverageButtons += `<button class="coverage-link dump-link" data-action="goroutine-dump" data-package="${escapeHtml(result.package || '')}">🧵 Goroutine Dump</button>`;
+    }
+    if (result.trace) {
+        coverageButtons += `<button class="coverage-link trace-link" data-action="trace" data-package="${escapeHtml(result.package || '')}">🧵 Trace Summary</button>`;
+    }
+    (result.artifacts || []).forEach(artifact => {
+        if (artifact.name.endsWith('.pprof')) {
+            coverageButtons += `<button class="coverage-link profile-link" data-action="profile" data-run="${escapeHtml(currentData.run_id || '')}" data-name="${escapeHtml(artifact.name)}">🔥 ${escapeHtml(artifact.kind.toUpperCase())} profile</button>`;
+        }
+    });
 
     return `
         <div class="package-result ${statusClass}">
             <div class="package-header" onclick="togglePackage(this)">
-                <div class="package-name">${escapeHtml(result.package || '')}</div>
+                <div class="package-name" title="${escapeHtml(result.import_path || '')}">${escapeHtml(result.package || '')}</div>
                 <div class="package-stats">
-                    <div class="coverage-badge ${coverageClass}">${(result.coverage || 0).toFixed(1)}%</div>
+                    <div class="coverage-badge ${coverageClass}"${result.unit_coverage !== undefined ? ` title="Unit tests alone: ${result.unit_coverage.toFixed(1)}%"` : ''}>${(result.coverage || 0).toFixed(1)}%</div>
                     <div class="status-badge ${statusClass}">${statusText}</div>
                     <div class="duration">${duration}ms</div>
                 </div>
             </div>
             <div class="package-details">
+                ${createTestListHTML(result.package, result.tests)}
+                ${createFailuresHTML(result.failures)}
                 <div class="test-output">${escapeHtml(result.output || '')}</div>
                 <div class="coverage-buttons">${coverageButtons}</div>
             </div>
         </div>`;
 }
 
+// createModuleGroupsHTML lists packages under the module they belong to, for
+// repositories with nested modules or a go.work.
+function createModuleGroupsHTML(modules, results) {
+    return modules.map(module => {
+        const packages = results.filter(result => (result.module || '') === module.path);
+        const failed = module.packages - module.passed_tests;
+        return `
+            <div class="module-group">
+                <div class="module-header">
+                    <span class="module-path">📦 ${escapeHtml(module.path || 'outside any module')}</span>
+                    ${module.dir && module.dir !== '.' ? `<span class="module-dir">${escapeHtml(module.dir)}</span>` : ''}
+                    <span class="module-stats">
+                        ${module.packages} packages${failed > 0 ? `, <span class="failed">${failed} failed</span>` : ''} ·
+                        <span class="${getCoverageClass(module.coverage)}">${module.coverage.toFixed(1)}%</span>
+                    </span>
+                </div>
+                ${packages.map(result => createPackageHTML(result)).join('')}
+            </div>`;
+    }).join('');
+}
+
+function createFailuresHTML(failures) {
+    if (!failures || failures.length === 0) return '';
+
+    const rows = failures.map(failure => {
+        const records = (failure.records || []).map(record => {
+            const location = record.source
+                ? `<a class="failure-location" data-action="source" data-source="${escapeHtml(record.source)}" data-line="${record.line}">${escapeHtml(record.source)}:${record.line}</a>`
+                : (record.file ? `<span class="failure-location">${escapeHtml(record.file)}:${record.line}</span>` : '');
+            const trace = record.trace && record.trace.length > 1
+                ? `<details class="failure-trace"><summary>${record.kind === 'panic' ? 'stack' : 'trace'}</summary><div class="stack-frames">${record.trace.map(renderStackFrame).join('')}</div></details>`
+                : '';
+            return `
+                <div class="failure-record ${escapeHtml(record.kind)}">
+                    <span class="failure-kind">${escapeHtml(record.kind)}</span>
+                    ${location}
+                    <pre class="failure-message">${escapeHtml(record.message)}</pre>
+                    ${record.messages ? `<pre class="failure-message">${escapeHtml(record.messages)}</pre>` : ''}
+                    ${trace}
+                </div>`;
+        }).join('');
+        return `
+            <div class="failure" style="margin-left: ${failure.depth * 1.5}rem">
+                <div class="failure-test">✗ ${escapeHtml(failure.test || 'package')}</div>
+                ${records}
+            </div>`;
+    }).join('');
+
+    return `<div class="failure-list">${rows}</div>`;
+}
+
+function createTestListHTML(pkg, tests) {
+    if (!tests || tests.length === 0) return '';
+
+    // Only failures and anything touched by a re-run; passing tests are in the raw output.
+    const interesting = tests.filter(t => t.status === 'fail' || t.rerun);
+    if (interesting.length === 0) return '';
+
+    const rows = interesting.map(test => {
+        let rerunBadge = '';
+        if (test.possibly_flaky) {
+            rerunBadge = '<span class="rerun-badge flaky" title="Failed before, passed on re-run">possibly flaky</span>';
+        } else if (test.reproduced) {
+            rerunBadge = '<span class="rerun-badge reproduced" title="Failed again on re-run">reproduced</span>';
+        } else if (test.rerun) {
+            rerunBadge = '<span class="rerun-badge">re-run</span>';
+        }
+        const duration = test.duration ? (test.duration / 1000000).toFixed(0) : '0';
+        return `
+            <div class="test-case ${escapeHtml(test.status)}">
+                <span class="test-case-status">${escapeHtml(test.status.toUpperCase())}</span>
+                <span class="test-case-name">${escapeHtml(test.name)}</span>
+                ${rerunBadge}
+                ${test.quarantined ? '<span class="rerun-badge quarantined">quarantined</span>' : ''}
+                <span class="duration">${duration}ms</span>
+                <button class="small-button" data-action="quarantine" data-package="${escapeHtml(pkg)}" data-test="${escapeHtml(test.name)}" data-quarantined="${!test.quarantined}">${test.quarantined ? 'Release' : 'Quarantine'}</button>
+                ${test.status === 'fail' ? `<button class="small-button" title="Find the commit that broke this test" data-action="bisect" data-package="${escapeHtml(pkg)}" data-test="${escapeHtml(test.name)}">Bisect</button>` : ''}
+            </div>`;
+    }).join('');
+
+    return `<div class="test-case-list">${rows}</div>`;
+}
+
+// loadProjects fills the project switcher from the registry. A project with a
+// run in progress is marked, as runs of different projects go on side by side.
+async function loadProjects() {
+    try {
+        const response = await fetch('/projects');
+        if (!response.ok) throw new Error(await response.text());
+        const projects = await response.json();
+        if (!projects.some(project => project.id === currentProject)) {
+            const fallback = projects.find(project => project.default) || projects[0];
+            if (fallback && fallback.id !== currentProject) {
+                switchProject(fallback.id);
+                return;
+            }
+        }
+        const select = document.getElementById('project-select');
+        select.innerHTML = projects.map(project => `
+            <option value="${escapeHtml(project.id)}" title="${escapeHtml(project.path)}" ${project.id === currentProject ? 'selected' : ''}>
+                ${project.running ? '⏳ ' : ''}${escapeHtml(project.name)}${project.last_run && !project.running && project.total_tests ? ` (${project.passed_tests}/${project.total_tests}, ${project.overall_coverage.toFixed(0)}%)` : ''}
+            </option>`).join('');
+    } catch (error) {
+        console.error('Error loading projects:', error);
+    }
+}
+
+// switchProject shows another project: its results, runs and live updates.
+function switchProject(id) {
+    if (id === currentProject && ws) return;
+    currentProject = id;
+    localStorage.setItem('project', id);
+    currentData = {};
+    Object.keys(slowPackages).forEach(pkg => delete slowPackages[pkg]);
+    document.getElementById('run-progress').innerHTML = '';
+    document.getElementById('results').innerHTML = '<div class="loading">Loading project...</div>';
+    const previous = ws;
+    connectWebSocket();
+    if (previous) previous.close();
+    loadProjects();
+}
+
+async function removeCurrentProject() {
+    if (!confirm('Remove this project from the dashboard? Its run history is kept.')) return;
+    const response = await fetch(`/projects/${encodeURIComponent(currentProject)}`, { method: 'DELETE' });
+    if (!response.ok) {
+        alert(`Error: ${await response.text()}`);
+        return;
+    }
+    closeProjectModal();
+    currentProject = '';
+    localStorage.removeItem('project');
+    loadProjects();
+}
+
 function showProjectModal() {
     document.getElementById('project-modal').classList.add('show');
     document.body.style.overflow = 'hidden';
@@ -128,7 +450,7 @@ async function setProjectPath() {
         return;
     }
     try {
-        const response = await fetch('/set-project-path', {
+        const response = await fetch('/projects', {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify({ path: path })
@@ -136,7 +458,9 @@ async function setProjectPath() {
         const result = await response.json();
         if (result.success) {
             closeProjectModal();
-            setTimeout(() => alert('Project path updated successfully!'), 100);
+            pathInput.value = '';
+            await loadProjects();
+            switchProject(result.id);
         } else {
             alert(`Error: ${result.message}`);
         }
@@ -148,30 +472,35 @@ async function setProjectPath() {
 
 async function loadCurrentProjectInfo() {
     try {
-        const response = await fetch('/project-info');
+        const response = await fetch(api('/project-info'));
         const info = await response.json();
         const infoContainer = document.getElementById('current-project-info');
         let packagesHtml = '';
         if (info.packages && info.packages.length > 0) {
             packagesHtml = `
                 <div class="project-info-item">
-                    <span class="project-info-label">Test Packages:</span>
+                    <span class="project-info-label">Packages:</span>
                     <span class="project-info-value">${info.packages_found}</span>
                 </div>
                 <div class="project-packages-list">
-                    ${info.packages.map(pkg => `<div class="project-package-item">${pkg}</div>`).join('')}
+                    ${info.packages.map(pkg => `<div class="project-package-item" title="${escapeHtml(pkg.dir)}">${escapeHtml(pkg.import_path)}${pkg.error ? ' ⚠ load error' : (pkg.test_go_files || pkg.xtest_go_files) ? '' : ' (no tests)'}</div>`).join('')}
                 </div>`;
         }
         infoContainer.innerHTML = `
             <div class="project-info-item">
                 <span class="project-info-label">Current Path:</span>
-                <span class="project-info-value">${info.project_path || 'Not set'}</span>
+                <span class="project-info-value">${escapeHtml(info.project_path || 'Not set')}</span>
             </div>
             <div class="project-info-item">
                 <span class="project-info-label">Project Name:</span>
-                <span class="project-info-value">${info.project_name || 'Unknown'}</span>
+                <span class="project-info-value">${escapeHtml(info.project_name || 'Unknown')}</span>
             </div>
-            ${packagesHtml}`;
+            <div class="project-info-item">
+                <span class="project-info-label">Integration:</span>
+                <span class="project-info-value mono" title="Set &quot;integration&quot; in ${escapeHtml(info.config_path)} to run a command with GOCOVERDIR after the unit tests">${escapeHtml(info.integration || 'none')}</span>
+            </div>
+            ${packagesHtml}
+            <button class="small-button" onclick="removeCurrentProject()">Remove from dashboard</button>`;
     } catch (error) {
         console.error('Error loading project info:', error);
         document.getElementById('current-project-info').innerHTML = '<div class="loading">Error loading project information</div>';
@@ -183,16 +512,24 @@ function openHTMLCoverage(filename) {
         alert('HTML coverage report not available');
         return;
     }
-    const url = `/html-coverage/${encodeURIComponent(filename)}`;
+    const url = api(`/html-coverage/${encodeURIComponent(filename)}`);
     window.open(url, '_blank', 'width=1200,height=800,scrollbars=yes,resizable=yes');
 }
 
+// Which coverage the explorer shows once integration coverage is merged in:
+// 'all', 'unit' or 'integration'.
+let coverageSource = 'all';
+let coverageFiles = [];
+
 function showCoverage(packageName) {
     document.getElementById('coverage-package-name').textContent = `${packageName} Coverage`;
+    const sourceSelect = document.getElementById('coverage-source-select');
+    sourceSelect.hidden = !(currentData.integration && currentData.integration.artifact);
+    sourceSelect.value = coverageSource = 'all';
     document.getElementById('coverage-modal').classList.add('show');
     document.body.style.overflow = 'hidden';
 
-    fetch(`/coverage/${encodeURIComponent(packageName)}`)
+    fetch(api(`/coverage/${encodeURIComponent(packageName)}`))
         .then(response => response.ok ? response.json() : Promise.reject('Failed to fetch coverage data'))
         .then(files => displayCoverageFiles(files))
         .catch(error => {
@@ -202,7 +539,28 @@ function showCoverage(packageName) {
         });
 }
 
-function displayCoverageFiles(files) {
+function setCoverageSource(source) {
+    coverageSource = source;
+    const active = document.querySelector('#file-list .file-item.active');
+    const index = active ? Array.from(active.parentNode.children).indexOf(active) : 0;
+    displayCoverageFiles(coverageFiles, index);
+}
+
+// blockCovered tells whether a block ran under the selected coverage source.
+function blockCovered(block) {
+    const integration = block.integration_count || 0;
+    if (coverageSource === 'unit') return block.count - integration > 0;
+    if (coverageSource === 'integration') return integration > 0;
+    return block.covered;
+}
+
+function fileCoverageFor(file) {
+    if (coverageSource === 'all' || !file.blocks || file.blocks.length === 0) return file.coverage;
+    return file.blocks.filter(blockCovered).length / file.blocks.length * 100;
+}
+
+function displayCoverageFiles(files, selected = 0) {
+    coverageFiles = files || [];
     const fileList = document.getElementById('file-list');
     fileList.innerHTML = '';
     document.getElementById('source-code').innerHTML = '<div class="loading">Select a file to view coverage...</div>';
@@ -218,12 +576,12 @@ function displayCoverageFiles(files) {
         fileItem.onclick = () => selectFile(file, fileItem);
         fileItem.innerHTML = `
             <div class="file-name">${escapeHtml(getFileName(file.filename))}</div>
-            <div class="file-coverage">${file.coverage.toFixed(1)}% coverage</div>`;
+            <div class="file-coverage">${fileCoverageFor(file).toFixed(1)}% coverage</div>`;
         fileList.appendChild(fileItem);
     });
 
     if (files.length > 0) {
-        fileList.firstChild.click();
+        (fileList.children[selected] || fileList.firstChild).click();
     }
 }
 
@@ -231,6 +589,46 @@ function selectFile(file, fileItem) {
     document.querySelectorAll('.file-item.active').forEach(item => item.classList.remove('active'));
     fileItem.classList.add('active');
     displaySourceCode(file);
+    annotateBlame(file);
+}
+
+// annotateBlame shows who last changed the uncovered lines of a file, once per
+// run of lines from the same commit.
+async function annotateBlame(file) {
+    if (!currentData.git) return;
+    try {
+        const response = await fetch(api(`/blame?file=${encodeURIComponent(file.filename)}`));
+        if (!response.ok) throw new Error(await response.text());
+        const lines = await response.json();
+        const sourceCode = document.getElementById('source-code');
+        let previous = null;
+        lines.forEach(blame => {
+            const lineDiv = sourceCode.querySelector(`.code-line[data-line="${blame.line}"]`);
+            if (!lineDiv || !lineDiv.classList.contains('uncovered')) {
+                previous = null;
+                return;
+            }
+            if (previous === blame.commit) return;
+            previous = blame.commit;
+            const span = document.createElement('span');
+            span.className = 'line-blame';
+            span.textContent = blame.uncommitted
+                ? 'not committed yet'
+                : `${blame.author} · ${formatAge(blame.time)} · ${blame.commit.substring(0, 8)}`;
+            span.title = blame.uncommitted ? '' : `${blame.summary}\n${new Date(blame.time).toLocaleString()}`;
+            lineDiv.appendChild(span);
+        });
+    } catch (error) {
+        console.error('Error loading blame:', error);
+    }
+}
+
+function formatAge(time) {
+    const days = (Date.now() - new Date(time).getTime()) / 86400000;
+    if (days < 1) return 'today';
+    if (days < 30) return `${Math.floor(days)}d ago`;
+    if (days < 365) return `${Math.floor(days / 30)}mo ago`;
+    return `${(days / 365).toFixed(1)}y ago`;
 }
 
 // ===================================================================================
@@ -284,7 +682,7 @@ function tokenizeGoCode(rawCode) {
  * This avoids innerHTML parsing issues and is much more reliable.
  * @param {object} file - The file object containing the code.
  */
-function displaySourceCode(file) {
+function displaySourceCode(file, highlightLine, annotations) {
     const sourceCode = document.getElementById('source-code');
     sourceCode.innerHTML = ''; // Clear previous content to prevent memory leaks.
 
@@ -294,23 +692,33 @@ function displaySourceCode(file) {
     if (file.blocks) {
         file.blocks.forEach(block => {
             for (let i = block.start_line; i <= block.end_line; i++) {
-                lineCoverage[i] = block.covered;
+                lineCoverage[i] = blockCovered(block);
             }
         });
     }
 
+    // With per-test coverage, clicking a line number lists the tests covering it.
+    const sourcePath = hasPerTestCoverage() ? (file.filename || file.path) : null;
+
     lines.forEach((line, index) => {
         const lineNumber = index + 1;
         const covered = lineCoverage[lineNumber];
 
         const lineDiv = document.createElement('div');
         lineDiv.className = 'code-line';
+        lineDiv.dataset.line = lineNumber;
         if (covered === true) lineDiv.classList.add('covered');
         else if (covered === false && line.trim() !== '') lineDiv.classList.add('uncovered');
+        if (lineNumber === highlightLine) lineDiv.classList.add('highlighted');
 
         const numberDiv = document.createElement('div');
         numberDiv.className = 'line-number';
         numberDiv.textContent = lineNumber;
+        if (sourcePath && covered !== undefined) {
+            numberDiv.classList.add('clickable');
+            numberDiv.title = 'Which tests cover this line?';
+            numberDiv.onclick = () => showLineTests(sourcePath, lineNumber, lineDiv);
+        }
 
         const contentDiv = document.createElement('div');
         contentDiv.className = 'line-content';
@@ -336,13 +744,131 @@ function displaySourceCode(file) {
         lineDiv.appendChild(numberDiv);
         lineDiv.appendChild(contentDiv);
         sourceCode.appendChild(lineDiv);
+
+        // Notes attached to a line, e.g. the mutants that survived on it.
+        if (annotations && annotations[lineNumber]) {
+            lineDiv.classList.add('annotated');
+            const note = document.createElement('div');
+            note.className = 'line-annotation';
+            note.innerHTML = annotations[lineNumber].join('<br>');
+            sourceCode.appendChild(note);
+        }
     });
+
+    const highlighted = sourceCode.querySelector('.code-line.highlighted');
+    if (highlighted) highlighted.scrollIntoView({ block: 'center' });
 }
 
 // ===================================================================================
 // END OF THE NEW LOGIC
 // ===================================================================================
 
+// showSource opens a project file in the source viewer with one line highlighted,
+// e.g. from a stack frame.
+async function showSource(path, line, annotations) {
+    document.getElementById('coverage-package-name').textContent = `${path}:${line}`;
+    document.getElementById('coverage-source-select').hidden = true;
+    coverageSource = 'all';
+    document.getElementById('coverage-modal').classList.add('show');
+    document.body.style.overflow = 'hidden';
+    const fileList = document.getElementById('file-list');
+    const sourceCode = document.getElementById('source-code');
+    fileList.innerHTML = `<div class="file-item active"><div class="file-name">${escapeHtml(getFileName(path))}</div><div class="file-coverage">line ${line}</div></div>`;
+    sourceCode.innerHTML = '<div class="loading">Loading source...</div>';
+    try {
+        const response = await fetch(api(`/source?path=${encodeURIComponent(path)}`));
+        if (!response.ok) throw new Error(await response.text());
+        const file = await response.json();
+        displaySourceCode(file, line, annotations);
+    } catch (error) {
+        console.error('Error loading source:', error);
+        sourceCode.innerHTML = `<div class="loading">Could not load source: ${escapeHtml(error.message)}</div>`;
+    }
+}
+
+function hasPerTestCoverage() {
+    return !!currentData.run_id && (currentData.results || []).some(result =>
+        (result.artifacts || []).some(artifact => artifact.kind === 'testcoverage'));
+}
+
+// showLineTests toggles a list of the tests covering a line right below it.
+async function showLineTests(path, line, lineDiv) {
+    const next = lineDiv.nextElementSibling;
+    if (next && next.classList.contains('line-tests')) {
+        next.remove();
+        return;
+    }
+    const panel = document.createElement('div');
+    panel.className = 'line-tests';
+    panel.textContent = 'Loading...';
+    lineDiv.after(panel);
+    try {
+        const response = await fetch(api(`/runs/${encodeURIComponent(currentData.run_id)}/line-tests?file=${encodeURIComponent(path)}&line=${line}`));
+        if (!response.ok) throw new Error(await response.text());
+        const result = await response.json();
+        if (!result.statement) {
+            panel.textContent = 'No statement on this line.';
+        } else if (result.tests.length === 0) {
+            panel.textContent = 'No test covers this line.';
+        } else {
+            panel.innerHTML = `<strong>${result.tests.length} test${result.tests.length === 1 ? '' : 's'} cover line ${line}:</strong> ` +
+                result.tests.map(t => `<span class="line-test" title="${escapeHtml(t.package)}">${escapeHtml(t.test)}</span>`).join('');
+        }
+    } catch (error) {
+        console.error('Error loading tests for line:', error);
+        panel.textContent = `Could not load covering tests: ${error.message}`;
+    }
+}
+
+function showImpactedTests() {
+    const note = hasPerTestCoverage() ? '' :
+        '<p class="tool-note">The current run has no per-test coverage. Tick "Per-test coverage" and run the tests first.</p>';
+    openToolModal('🎯 Impacted Tests', `
+        <div class="tool-section">
+            <p class="tool-note">Tests of the current run that execute lines changed in the working tree since a git ref, plus tests whose own code changed.</p>
+            ${note}
+            <div class="path-input-group">
+                <input type="text" id="impact-base" placeholder="git ref, e.g. HEAD or main" value="HEAD" />
+                <button class="set-path-button" onclick="loadImpactedTests()">Find tests</button>
+            </div>
+        </div>
+        <div class="tool-section" id="impact-results"></div>`);
+}
+
+async function loadImpactedTests() {
+    const target = document.getElementById('impact-results');
+    const base = document.getElementById('impact-base').value.trim() || 'HEAD';
+    target.innerHTML = '<div class="loading">Looking for impacted tests...</div>';
+    try {
+        const response = await fetch(api(`/runs/${encodeURIComponent(currentData.run_id || '')}/impacted?base=${encodeURIComponent(base)}`));
+        if (!response.ok) throw new Error(await response.text());
+        const report = await response.json();
+        if (report.files.length === 0) {
+            target.innerHTML = `<p class="tool-note">No Go files changed since ${escapeHtml(report.base)}.</p>`;
+            return;
+        }
+        const files = report.files.map(f => `
+            <tr><td class="mono">${escapeHtml(f.path)}</td><td>${f.changed}</td><td>${f.covered}</td></tr>`).join('');
+        const packages = report.packages.map(pkg => `
+            <h3>${escapeHtml(pkg.package)}</h3>
+            <table class="tool-table">
+                <tr><th>Test</th><th>Changed lines executed</th><th></th></tr>
+                ${pkg.tests.map(t => `<tr><td class="mono">${escapeHtml(t.name)}</td><td>${t.lines}</td><td>${t.changed ? 'test edited' : ''}</td></tr>`).join('')}
+            </table>
+            <pre class="impact-command">${escapeHtml(pkg.command)}</pre>`).join('');
+        target.innerHTML = `
+            <h3>${report.changed_lines} changed lines, ${report.covered_lines} executed by tests</h3>
+            <table class="tool-table">
+                <tr><th>File</th><th>Changed</th><th>Covered</th></tr>
+                ${files}
+            </table>
+            <div style="margin-top: 1.5rem">${packages || '<p class="tool-note">No test executes the changed lines.</p>'}</div>`;
+    } catch (error) {
+        console.error('Error loading impacted tests:', error);
+        target.innerHTML = `<p class="tool-note">Could not find impacted tests: ${escapeHtml(error.message)}</p>`;
+    }
+}
+
 function getFileName(fullPath) {
     return fullPath.split('/').pop();
 }
@@ -362,18 +888,1405 @@ function runTests() {
     resultsEl.innerHTML = '<div class="loading">Running tests...</div>';
     resultsEl.dataset.isRunning = "true";
 
-    fetch('/run-tests', { method: 'POST' })
+    const options = {
+        profile: document.getElementById('profile-select').value,
+        trace: document.getElementById('trace-checkbox').checked,
+        timeout: document.getElementById('timeout-input').value.trim(),
+        race: document.getElementById('race-checkbox').checked,
+        per_test: document.getElementById('pertest-checkbox').checked,
+        tags: document.getElementById('tags-input').value.trim(),
+        ref: document.getElementById('ref-input').value.trim()
+    };
+    Object.keys(slowPackages).forEach(pkg => delete slowPackages[pkg]);
+    document.getElementById('run-progress').innerHTML = '';
+    fetch(api('/run-tests'), {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(options)
+    })
+        .then(async response => {
+            if (!response.ok) throw new Error(await response.text());
+        })
         .catch(error => {
             console.error('Error running tests:', error);
-            resultsEl.innerHTML = '<div class="loading">Failed to start tests.</div>';
+            resultsEl.innerHTML = `<div class="loading">Failed to start tests: ${escapeHtml(error.message)}</div>`;
             delete resultsEl.dataset.isRunning;
         });
 }
 
+function rerunFailed() {
+    fetch(api('/rerun-failed'), { method: 'POST' })
+        .catch(error => {
+            console.error('Error re-running failed tests:', error);
+            alert('Failed to start re-run.');
+        });
+}
+
+function openToolModal(title, bodyHTML) {
+    document.getElementById('tool-modal-title').textContent = title;
+    document.getElementById('tool-modal-body').innerHTML = bodyHTML;
+    document.getElementById('tool-modal').classList.add('show');
+    document.body.style.overflow = 'hidden';
+}
+
+function setToolModalBody(bodyHTML) {
+    document.getElementById('tool-modal-body').innerHTML = bodyHTML;
+}
+
+function closeToolModal() {
+    document.getElementById('tool-modal').classList.remove('show');
+    document.body.style.overflow = '';
+}
+
+async function showFlakyTests() {
+    openToolModal('🎲 Flaky Tests', '<div class="loading">Analysing stored runs...</div>');
+    try {
+        const response = await fetch(api('/flaky'));
+        if (!response.ok) throw new Error(await response.text());
+        const flaky = await response.json();
+        if (!flaky || flaky.length === 0) {
+            setToolModalBody('<div class="loading">No flaky tests found in the stored runs.</div>');
+            return;
+        }
+        const rows = flaky.map(test => `
+            <tr>
+                <td class="mono">${escapeHtml(test.package)}</td>
+                <td class="mono">${escapeHtml(test.name)}</td>
+                <td>${(test.score * 100).toFixed(0)}%</td>
+                <td>${test.flips} / ${test.runs}</td>
+                <td>${test.failures}</td>
+                <td>${escapeHtml(test.last_status)}</td>
+                <td>
+                    <button class="small-button" data-action="stress" data-package="${escapeHtml(test.package)}" data-test="${escapeHtml(test.name)}">Stress</button>
+                    <button class="small-button" data-action="flaky-quarantine" data-package="${escapeHtml(test.package)}" data-test="${escapeHtml(test.name)}" data-quarantined="${!test.quarantined}">${test.quarantined ? 'Release' : 'Quarantine'}</button>
+                </td>
+            </tr>`).join('');
+        setToolModalBody(`
+            <p class="tool-note">Score is the share of re-runs on identical code (same tree hash) that flipped between pass and fail.</p>
+            <table class="tool-table">
+                <tr><th>Package</th><th>Test</th><th>Score</th><th>Flips / Runs</th><th>Failures</th><th>Last</th><th></th></tr>
+                ${rows}
+            </table>
+            <div id="stress-result"></div>`);
+    } catch (error) {
+        console.error('Error loading flaky tests:', error);
+        setToolModalBody('<div class="loading">Error loading flaky tests.</div>');
+    }
+}
+
+onAction('stress', data => stressTest(data.package, data.test));
+onAction('flaky-quarantine', data => setQuarantine(data.package, data.test, data.quarantined === 'true').then(showFlakyTests));
+
+async function stressTest(pkg, test) {
+    const count = parseInt(prompt(`Run ${test} how many times?`, '50'), 10);
+    if (!count) return;
+    const target = document.getElementById('stress-result');
+    if (target) target.innerHTML = `<div class="loading">Running ${escapeHtml(test)} ${count} times...</div>`;
+    try {
+        const response = await fetch(api('/stress'), {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ package: pkg, test: test, count: count })
+        });
+        if (!response.ok) throw new Error(await response.text());
+        const result = await response.json();
+        const html = result.error
+            ? `<div class="tool-section">
+                <h3>Stress: ${escapeHtml(test)}</h3>
+                <p class="tool-note">${result.timed_out ? '⚠ ' : ''}${escapeHtml(result.error)}</p>
+                ${result.failure_output ? `<div class="test-output">${escapeHtml(result.failure_output)}</div>` : ''}
+               </div>`
+            : `<div class="tool-section">
+                <h3>Stress: ${escapeHtml(test)}</h3>
+                <p class="tool-note">${result.failures} of ${result.runs} runs failed (${(result.failure_rate * 100).toFixed(1)}%) in ${(result.duration / 1e9).toFixed(1)}s</p>
+                ${result.failure_output ? `<div class="test-output">${escapeHtml(result.failure_output)}</div>` : ''}
+               </div>`;
+        if (target) target.innerHTML = html;
+        else openToolModal('🎲 Stress Run', html);
+    } catch (error) {
+        console.error('Error running stress test:', error);
+        alert(`Stress run failed: ${error.message}`);
+    }
+}
+
+// updateStressProgress shows the counts of a stress run while it is going.
+function updateStressProgress(progress) {
+    const target = document.getElementById('stress-result');
+    if (!target || progress.status !== 'running' || !target.querySelector('.loading')) return;
+    target.innerHTML = `<div class="loading">Running ${escapeHtml(progress.test)}: ${progress.runs} of ${progress.count} done, ${progress.failures} failed (${formatNanos(progress.elapsed)})...</div>`;
+}
+
+onAction('quarantine', data => setQuarantine(data.package, data.test, data.quarantined === 'true'));
+
+async function setQuarantine(pkg, test, quarantined) {
+    try {
+        const response = await fetch(api('/quarantine'), {
+            method: quarantined ? 'POST' : 'DELETE',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ package: pkg, test: test })
+        });
+        if (!response.ok) throw new Error(await response.text());
+    } catch (error) {
+        console.error('Error updating quarantine:', error);
+        alert(`Could not update quarantine: ${error.message}`);
+    }
+}
+
+onAction('bisect', data => showBisect(data.package, data.test));
+
+async function showBisect(pkg, test) {
+    openToolModal('🔍 Bisect', `
+        <div class="tool-section">
+            <h3>Find the commit that broke a test</h3>
+            <div class="path-input-group">
+                <input type="text" id="bisect-package" placeholder="Package" value="${escapeHtml(pkg || '')}" />
+                <input type="text" id="bisect-test" placeholder="Test" value="${escapeHtml(test || '')}" />
+            </div>
+            <div class="path-input-group">
+                <input type="text" id="bisect-good" placeholder="Good ref, e.g. v1.2.0" />
+                <input type="text" id="bisect-bad" placeholder="Bad ref" value="HEAD" />
+                <button class="set-path-button" onclick="startBisect()">Bisect</button>
+            </div>
+            <p class="tool-note">Runs only this test at every commit git bisect picks, in a temporary worktree. Commits where the test doesn't exist or the package doesn't build are skipped. Test runs wait until the bisect is done.</p>
+        </div>
+        <div id="bisect-progress"></div>`);
+    try {
+        const response = await fetch(api('/bisect'));
+        if (response.ok) renderBisect(await response.json());
+    } catch (error) {
+        console.error('Error loading bisect:', error);
+    }
+}
+
+async function startBisect() {
+    try {
+        const response = await fetch(api('/bisect'), {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({
+                package: document.getElementById('bisect-package').value.trim(),
+                test: document.getElementById('bisect-test').value.trim(),
+                good: document.getElementById('bisect-good').value.trim(),
+                bad: document.getElementById('bisect-bad').value.trim(),
+                tags: document.getElementById('tags-input').value.trim(),
+                timeout: document.getElementById('timeout-input').value.trim()
+            })
+        });
+        if (!response.ok) throw new Error(await response.text());
+        renderBisect(await response.json());
+    } catch (error) {
+        console.error('Error starting bisect:', error);
+        alert(`Could not start bisect: ${error.message}`);
+    }
+}
+
+function renderBisect(bisect) {
+    const target = document.getElementById('bisect-progress');
+    if (!target) return;
+    const opts = bisect.options;
+    let status;
+    if (bisect.status === 'running') {
+        status = `<p class="tool-note">Bisecting ${escapeHtml(opts.test)} in ${escapeHtml(opts.package)}: ${bisect.steps.length} commits tested, about ${bisect.remaining} revisions left...</p>`;
+    } else if (bisect.status === 'failed') {
+        status = `<div class="test-output">${escapeHtml(bisect.error)}</div>`;
+    } else if (bisect.first_bad) {
+        const c = bisect.first_bad;
+        status = `
+            <div class="failure-record">
+                <span class="failure-kind">first bad commit</span>
+                <span class="mono">${escapeHtml(c.commit.substring(0, 12))}</span> ${escapeHtml(c.subject)}
+                <p class="tool-note">${escapeHtml(c.author)} · ${new Date(c.date).toLocaleString()} · ${c.files_changed} files changed, <span class="passed">+${c.insertions}</span> <span class="failed">-${c.deletions}</span></p>
+                <pre class="failure-message">${escapeHtml(c.message)}</pre>
+                <pre class="failure-message">${escapeHtml(c.stat)}</pre>
+            </div>`;
+    } else {
+        status = `<p class="tool-note">Skipped commits leave the first bad commit ambiguous. It is one of:</p>
+            <div class="test-output">${(bisect.candidates || []).map(escapeHtml).join('\n')}</div>`;
+    }
+    const resultClass = { good: 'passed', bad: 'failed', skip: 'untested' };
+    const steps = bisect.steps.map(step => `
+        <tr>
+            <td class="mono">${escapeHtml(step.commit.substring(0, 12))}</td>
+            <td>${escapeHtml(step.subject)}</td>
+            <td><span class="status-badge ${resultClass[step.result]}">${escapeHtml(step.result)}</span></td>
+            <td>${(step.duration / 1e9).toFixed(1)}s</td>
+            <td>${step.output ? `<details><summary>output</summary><div class="test-output">${escapeHtml(step.output)}</div></details>` : ''}</td>
+        </tr>`).join('');
+    target.innerHTML = `
+        <div class="tool-section">
+            <h3>Bisect ${escapeHtml(bisect.id)}</h3>
+            ${status}
+            ${steps ? `<table class="tool-table">
+                <tr><th>Commit</th><th>Subject</th><th>Result</th><th>Time</th><th></th></tr>
+                ${steps}
+            </table>` : ''}
+        </div>`;
+}
+
+async function showBlameReport() {
+    openToolModal('🕵 Untested Code by Author and Age', '<div class="loading">Running git blame on the covered files...</div>');
+    try {
+        const response = await fetch(api('/blame/report'));
+        if (!response.ok) throw new Error(await response.text());
+        const report = await response.json();
+        const table = (buckets, label, nameCell) => `
+            <table class="tool-table">
+                <tr><th>${label}</th><th>Statements</th><th>Uncovered</th><th>Coverage</th></tr>
+                ${buckets.map(b => `
+                <tr>
+                    <td>${nameCell(b)}</td>
+                    <td>${b.statements}</td>
+                    <td>${b.uncovered}</td>
+                    <td class="${b.statements ? getCoverageClass(b.coverage) : ''}">${b.statements ? `${b.coverage.toFixed(1)}%` : '–'}</td>
+                </tr>`).join('')}
+            </table>`;
+        setToolModalBody(`
+            <p class="tool-note">${report.uncovered} of ${report.statements} statements are uncovered${report.revision ? ` at ${escapeHtml(report.revision.substring(0, 12))}` : ''}. A coverage block counts towards the commit that last changed any of its lines.</p>
+            ${(report.errors || []).length ? `<p class="tool-note">Could not blame ${report.errors.length} files: ${escapeHtml(report.errors[0])}</p>` : ''}
+            <div class="tool-section">
+                <h3>By age of the last change</h3>
+                ${table(report.ages, 'Changed', b => escapeHtml(b.name))}
+            </div>
+            <div class="tool-section">
+                <h3>By author</h3>
+                ${table(report.authors, 'Author', b => escapeHtml(b.name))}
+            </div>
+            <div class="tool-section">
+                <h3>Commits with the most uncovered statements</h3>
+                ${table(report.commits, 'Commit', b => b.name === '0000000000000000000000000000000000000000'
+                    ? 'not committed yet'
+                    : `<span class="mono">${escapeHtml(b.name.substring(0, 8))}</span> ${escapeHtml(b.detail || '')} <span class="duration">${b.time ? formatAge(b.time) : ''}</span>`)}
+            </div>`);
+    } catch (error) {
+        console.error('Error loading blame report:', error);
+        setToolModalBody(`<div class="loading">Could not build the report: ${escapeHtml(error.message)}</div>`);
+    }
+}
+
+async function showCommits() {
+    openToolModal('🌿 Commits', '<div class="loading">Loading tested commits...</div>');
+    try {
+        const response = await fetch(api('/commits'));
+        if (!response.ok) throw new Error(await response.text());
+        const commits = await response.json();
+        if (commits.length === 0) {
+            setToolModalBody('<div class="loading">No runs of a git checkout stored yet.</div>');
+            return;
+        }
+        const rows = commits.map((c, i) => {
+            const older = commits[i + 1];
+            const delta = older ? c.overall_coverage - older.overall_coverage : null;
+            const deltaHtml = delta === null || Math.abs(delta) < 0.05 ? '–'
+                : `<span class="${delta > 0 ? 'passed' : 'failed'}">${delta > 0 ? '+' : ''}${delta.toFixed(1)}%</span>`;
+            return `
+            <tr>
+                <td class="mono" title="${escapeHtml(c.commit)}">${escapeHtml(c.commit.substring(0, 8))}${c.dirty ? ' <span class="duration">+ local changes</span>' : ''}</td>
+                <td>${escapeHtml(c.subject || '')}</td>
+                <td>${escapeHtml(c.branch || '')}</td>
+                <td>${c.runs}</td>
+                <td class="${c.passed_tests === c.total_tests ? 'passed' : 'failed'}">${c.passed_tests} / ${c.total_tests}</td>
+                <td class="${getCoverageClass(c.overall_coverage)}">${c.overall_coverage.toFixed(1)}%</td>
+                <td>${deltaHtml}</td>
+                <td>${c.gate_passed ? '✅' : '❌'}</td>
+                <td><button class="small-button" data-action="run-at" data-ref="${escapeHtml(c.commit)}">Run again</button></td>
+            </tr>`;
+        }).join('');
+        setToolModalBody(`
+            <p class="tool-note">Figures come from the latest run of each commit without local changes, if there is one. Δ is the coverage change from the commit tested before it.</p>
+            <table class="tool-table">
+                <tr><th>Commit</th><th>Subject</th><th>Branch</th><th>Runs</th><th>Passed</th><th>Coverage</th><th>Δ</th><th>Gate</th><th></th></tr>
+                ${rows}
+            </table>`);
+    } catch (error) {
+        console.error('Error loading commits:', error);
+        setToolModalBody('<div class="loading">Error loading commits.</div>');
+    }
+}
+
+onAction('run-at', data => runTestsAt(data.ref));
+
+function runTestsAt(ref) {
+    document.getElementById('ref-input').value = ref;
+    closeToolModal();
+    runTests();
+}
+
+function runLabel(run) {
+    const commit = run.commit ? ` ${run.commit.substring(0, 8)}${run.dirty ? '+' : ''}` : '';
+    return `${new Date(run.started_at).toLocaleString()}${commit} · ${run.passed_tests}/${run.total_tests} · ${run.overall_coverage.toFixed(1)}%`;
+}
+
+async function showCompareRuns() {
+    openToolModal('⚖ Compare Runs', '<div class="loading">Loading runs...</div>');
+    try {
+        const response = await fetch(api('/runs'));
+        if (!response.ok) throw new Error(await response.text());
+        const runs = await response.json();
+        if (runs.length < 2) {
+            setToolModalBody('<div class="loading">At least two stored runs are needed to compare.</div>');
+            return;
+        }
+        const options = selected => runs.map(run =>
+            `<option value="${escapeHtml(run.id)}"${run.id === selected ? ' selected' : ''}>${escapeHtml(runLabel(run))}</option>`).join('');
+        setToolModalBody(`
+            <div class="tool-section">
+                <div class="path-input-group">
+                    <label for="compare-base">Base</label>
+                    <select id="compare-base">${options(runs[1].id)}</select>
+                    <label for="compare-head">Head</label>
+                    <select id="compare-head">${options(runs[0].id)}</select>
+                    <label for="compare-threshold" title="Packages and tests that got slower by more than this count as duration regressions">Slower by</label>
+                    <input type="number" id="compare-threshold" value="20" min="1" />%
+                    <button class="set-path-button" onclick="loadRunComparison()">Compare</button>
+                </div>
+            </div>
+            <div id="compare-results"></div>`);
+        loadRunComparison();
+    } catch (error) {
+        console.error('Error loading runs:', error);
+        setToolModalBody('<div class="loading">Error loading runs.</div>');
+    }
+}
+
+async function loadRunComparison() {
+    const target = document.getElementById('compare-results');
+    const base = document.getElementById('compare-base').value;
+    const head = document.getElementById('compare-head').value;
+    const threshold = document.getElementById('compare-threshold').value || '20';
+    target.innerHTML = '<div class="loading">Comparing runs...</div>';
+    try {
+        const response = await fetch(api(`/compare?base=${encodeURIComponent(base)}&head=${encodeURIComponent(head)}&threshold=${encodeURIComponent(threshold)}`));
+        if (!response.ok) throw new Error(await response.text());
+        target.innerHTML = renderRunComparison(await response.json());
+    } catch (error) {
+        console.error('Error comparing runs:', error);
+        target.innerHTML = `<p class="tool-note">Could not compare the runs: ${escapeHtml(error.message)}</p>`;
+    }
+}
+
+function renderRunComparison(c) {
+    const delta = value => Math.abs(value) < 0.05 ? '–'
+        : `<span class="${value > 0 ? 'passed' : 'failed'}">${value > 0 ? '+' : ''}${value.toFixed(1)}%</span>`;
+    const testName = t => `<td>${escapeHtml(t.package)}</td><td class="mono">${escapeHtml(t.test || '(package)')}</td>`;
+    const section = (title, items, header, row) => `
+        <div class="tool-section">
+            <h3>${title} (${items.length})</h3>
+            ${items.length === 0 ? '<p class="tool-note">None.</p>' : `
+            <table class="tool-table">
+                <tr>${header}</tr>
+                ${items.map(row).join('')}
+            </table>`}
+        </div>`;
+
+    const packages = c.packages.filter(p => p.status || Math.abs(p.delta) >= 0.05 || p.base_passed !== p.head_passed);
+    const files = c.files.map(f => `
+        <details class="compare-file"${f.lost_lines.length > 0 ? ' open' : ''}>
+            <summary>
+                <span class="mono">${escapeHtml(f.filename)}</span>
+                <span class="failed">−${f.lost_lines.length}</span>
+                <span class="passed">+${f.gained_lines}</span>
+                ${delta(f.head_coverage - f.base_coverage)}
+                ${f.changed ? '<span class="duration">edited</span>' : ''}
+            </summary>
+            ${f.lost_lines.length === 0 ? '' : `<div class="test-output">${f.lost_lines.map(l =>
+                `${String(l.line).padStart(5)}  ${escapeHtml(l.content)}`).join('\n')}</div>`}
+        </details>`).join('');
+
+    return `
+        <p class="tool-note">Overall coverage ${c.base.overall_coverage.toFixed(1)}% → ${c.head.overall_coverage.toFixed(1)}% (${delta(c.coverage_delta)}),
+            packages passed ${c.base.passed_tests}/${c.base.total_tests} → ${c.head.passed_tests}/${c.head.total_tests}.</p>
+        ${section('❌ Newly failing', c.newly_failing, '<th>Package</th><th>Test</th><th>Before</th>',
+            t => `<tr>${testName(t)}<td>${escapeHtml(t.base_status || '')}</td></tr>`)}
+        ${section('✅ Newly passing', c.newly_passing, '<th>Package</th><th>Test</th>',
+            t => `<tr>${testName(t)}</tr>`)}
+        ${section('➕ Added tests', c.added, '<th>Package</th><th>Test</th><th>Status</th>',
+            t => `<tr>${testName(t)}<td><span class="status-badge ${t.head_status === 'fail' ? 'failed' : 'passed'}">${escapeHtml(t.head_status)}</span></td></tr>`)}
+        ${section('➖ Removed tests', c.removed, '<th>Package</th><th>Test</th><th>Last status</th>',
+            t => `<tr>${testName(t)}<td>${escapeHtml(t.base_status)}</td></tr>`)}
+        ${section('📊 Package coverage', packages, '<th>Package</th><th>Base</th><th>Head</th><th>Δ</th><th></th>',
+            p => `<tr>
+                <td>${escapeHtml(p.package)}</td>
+                <td>${p.status === 'added' ? '–' : `${p.base_coverage.toFixed(1)}%`}</td>
+                <td>${p.status === 'removed' ? '–' : `<span class="${getCoverageClass(p.head_coverage)}">${p.head_coverage.toFixed(1)}%</span>`}</td>
+                <td>${delta(p.delta)}</td>
+                <td>${p.status ? escapeHtml(p.status) : p.base_passed !== p.head_passed ? (p.head_passed ? 'now passing' : 'now failing') : ''}</td>
+            </tr>`)}
+        <div class="tool-section">
+            <h3>📄 Lines that lost coverage (${c.files.reduce((n, f) => n + f.lost_lines.length, 0)})</h3>
+            ${files || '<p class="tool-note">No line changed coverage.</p>'}
+        </div>
+        ${section(`🐢 Slower by more than ${c.duration_threshold}%`, c.duration_regressions, '<th>Package</th><th>Test</th><th>Base</th><th>Head</th><th>Change</th>',
+            d => `<tr>${testName(d)}<td>${formatNanos(d.base)}</td><td>${formatNanos(d.head)}</td><td class="failed">+${d.increase.toFixed(0)}%</td></tr>`)}`;
+}
+
+const WEBHOOK_EVENTS = ['run.finished', 'gate.failed', 'coverage.dropped', 'package.broken'];
+
+async function showWebhooks() {
+    openToolModal('🪝 Webhooks', '<div class="loading">Loading webhooks...</div>');
+    try {
+        const [hooksResponse, deliveriesResponse] = await Promise.all([
+            fetch(api('/webhooks')),
+            fetch(api('/webhooks/deliveries'))
+        ]);
+        if (!hooksResponse.ok) throw new Error(await hooksResponse.text());
+        if (!deliveriesResponse.ok) throw new Error(await deliveriesResponse.text());
+        const hooks = await hooksResponse.json();
+        const deliveries = await deliveriesResponse.json();
+
+        const hookRows = hooks.map(hook => `
+            <tr>
+                <td class="mono">${escapeHtml(hook.url)}</td>
+                <td>${hook.events.map(escapeHtml).join(', ')}</td>
+                <td>${hook.secret ? 'signed' : 'unsigned'}</td>
+                <td>
+                    <button class="small-button" data-action="webhook-ping" data-id="${escapeHtml(hook.id)}">Ping</button>
+                    <button class="small-button" data-action="webhook-remove" data-id="${escapeHtml(hook.id)}">Delete</button>
+                </td>
+            </tr>`).join('');
+        const deliveryRows = deliveries.slice(0, 50).map(d => {
+            const last = d.attempts[d.attempts.length - 1];
+            const status = d.pending ? '<span class="status-badge pending">pending</span>'
+                : d.delivered ? '<span class="status-badge passed">delivered</span>'
+                : '<span class="status-badge failed">failed</span>';
+            return `
+            <tr>
+                <td>${new Date(d.created_at).toLocaleString()}</td>
+                <td>${escapeHtml(d.event)}</td>
+                <td class="mono">${escapeHtml(d.url)}</td>
+                <td>${status}</td>
+                <td>${d.attempts.length}</td>
+                <td>${last ? escapeHtml(last.error || `HTTP ${last.status}`) : '–'}</td>
+                <td><button class="small-button" data-action="webhook-delivery" data-id="${escapeHtml(d.id)}">Payload</button></td>
+            </tr>`;
+        }).join('');
+
+        setToolModalBody(`
+            <div class="tool-section">
+                <h3>Add webhook</h3>
+                <div class="path-input-group">
+                    <input type="text" id="webhook-url" placeholder="https://example.com/hooks/azlo" />
+                    <input type="text" id="webhook-secret" placeholder="Secret (optional)" />
+                    <button class="set-path-button" onclick="addWebhook()">Add</button>
+                </div>
+                <div class="bench-packages">
+                    ${WEBHOOK_EVENTS.map(event => `<label class="bench-package"><input type="checkbox" class="webhook-event" value="${event}" checked> ${event}</label>`).join('')}
+                </div>
+                <p class="tool-note">Payloads are signed in the X-Azlo-Signature header as sha256=HMAC-SHA256(secret, body). Failed deliveries are retried with backoff.</p>
+            </div>
+            <div class="tool-section">
+                <h3>Webhooks</h3>
+                ${hooks.length === 0 ? '<p class="tool-note">No webhooks configured.</p>' : `
+                <table class="tool-table">
+                    <tr><th>URL</th><th>Events</th><th>Signature</th><th></th></tr>
+                    ${hookRows}
+                </table>`}
+            </div>
+            <div class="tool-section">
+                <h3>Deliveries</h3>
+                ${deliveries.length === 0 ? '<p class="tool-note">Nothing delivered yet.</p>' : `
+                <table class="tool-table">
+                    <tr><th>When</th><th>Event</th><th>URL</th><th>Status</th><th>Attempts</th><th>Last response</th><th></th></tr>
+                    ${deliveryRows}
+                </table>`}
+                <div id="webhook-delivery"></div>
+            </div>`);
+    } catch (error) {
+        console.error('Error loading webhooks:', error);
+        setToolModalBody('<div class="loading">Error loading webhooks.</div>');
+    }
+}
+
+async function addWebhook() {
+    const events = Array.from(document.querySelectorAll('.webhook-event:checked')).map(input => input.value);
+    if (events.length === 0) {
+        alert('Select at least one event.');
+        return;
+    }
+    try {
+        const response = await fetch(api('/webhooks'), {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({
+                url: document.getElementById('webhook-url').value.trim(),
+                secret: document.getElementById('webhook-secret').value,
+                events: events
+            })
+        });
+        if (!response.ok) throw new Error(await response.text());
+        showWebhooks();
+    } catch (error) {
+        console.error('Error adding webhook:', error);
+        alert(`Could not add webhook: ${error.message}`);
+    }
+}
+
+onAction('webhook-ping', data => pingWebhook(data.id));
+onAction('webhook-remove', data => removeWebhook(data.id));
+onAction('webhook-delivery', data => showWebhookDelivery(data.id));
+
+async function removeWebhook(id) {
+    if (!confirm('Delete this webhook?')) return;
+    try {
+        const response = await fetch(api('/webhooks'), {
+            method: 'DELETE',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ id: id })
+        });
+        if (!response.ok) throw new Error(await response.text());
+        showWebhooks();
+    } catch (error) {
+        console.error('Error deleting webhook:', error);
+        alert(`Could not delete webhook: ${error.message}`);
+    }
+}
+
+async function pingWebhook(id) {
+    setToolModalBody('<div class="loading">Pinging webhook (failed pings are retried with backoff)...</div>');
+    try {
+        const response = await fetch(api(`/webhooks/${encodeURIComponent(id)}/ping`), { method: 'POST' });
+        if (!response.ok) throw new Error(await response.text());
+    } catch (error) {
+        console.error('Error pinging webhook:', error);
+        alert(`Ping failed: ${error.message}`);
+    }
+    showWebhooks();
+}
+
+async function showWebhookDelivery(id) {
+    const target = document.getElementById('webhook-delivery');
+    try {
+        const response = await fetch(api(`/webhooks/deliveries/${encodeURIComponent(id)}`));
+        if (!response.ok) throw new Error(await response.text());
+        const delivery = await response.json();
+        const attempts = delivery.attempts.map(a =>
+            `${new Date(a.at).toLocaleString()} · ${a.error ? escapeHtml(a.error) : `HTTP ${a.status}`} · ${(a.duration / 1e6).toFixed(0)}ms`).join('<br>');
+        target.innerHTML = `
+            <h3>Delivery ${escapeHtml(delivery.id)}</h3>
+            <p class="tool-note">${attempts || 'No attempts yet.'}</p>
+            <div class="test-output">${escapeHtml(JSON.stringify(delivery.payload, null, 2))}</div>`;
+    } catch (error) {
+        console.error('Error loading delivery:', error);
+        target.innerHTML = '<div class="loading">Error loading delivery.</div>';
+    }
+}
+
+function formatBenchValue(value, unit) {
+    if (unit === 'ns/op') {
+        if (value >= 1e9) return `${(value / 1e9).toFixed(2)}s`;
+        if (value >= 1e6) return `${(value / 1e6).toFixed(2)}ms`;
+        if (value >= 1e3) return `${(value / 1e3).toFixed(2)}µs`;
+        return `${value.toFixed(2)}ns`;
+    }
+    return Number.isInteger(value) ? `${value}` : value.toFixed(2);
+}
+
+function formatSampleSummary(summary, unit) {
+    if (!summary || !summary.n) return '–';
+    let text = formatBenchValue(summary.median, unit);
+    if (summary.has_ci && summary.median !== 0) {
+        const spread = Math.max(summary.ci_high - summary.median, summary.median - summary.ci_low);
+        text += ` ±${(spread / Math.abs(summary.median) * 100).toFixed(0)}%`;
+    } else if (!summary.has_ci) {
+        text += ' ±∞';
+    }
+    return `${text} <span class="duration">(n=${summary.n})</span>`;
+}
+
+async function showBenchmarks() {
+    const packages = (currentData.results || []).map(r => r.package);
+    const packageOptions = packages.length > 0
+        ? packages.map(pkg => `<label class="bench-package"><input type="checkbox" value="${escapeHtml(pkg)}" checked> ${escapeHtml(pkg)}</label>`).join('')
+        : '<p class="tool-note">Run the tests once so the package list is known.</p>';
+
+    openToolModal('⏱ Benchmarks', `
+        <div class="tool-section">
+            <h3>Run benchmarks</h3>
+            <div class="bench-packages" id="bench-packages">${packageOptions}</div>
+            <div class="path-input-group">
+                <input type="text" id="bench-pattern" placeholder="-bench pattern" value="." />
+                <input type="number" id="bench-count" min="1" max="50" value="6" title="-count (6+ samples give a 95% confidence interval)" />
+                <button class="set-path-button" onclick="runBenchmarks()">Run</button>
+            </div>
+            <p class="tool-note" id="bench-progress"></p>
+        </div>
+        <div class="tool-section">
+            <h3>Stored runs</h3>
+            <div id="bench-runs"><div class="loading">Loading...</div></div>
+        </div>
+        <div class="tool-section" id="bench-comparison"></div>`);
+    loadBenchmarkRuns();
+}
+
+async function loadBenchmarkRuns() {
+    const target = document.getElementById('bench-runs');
+    if (!target) return;
+    try {
+        const response = await fetch(api('/benchmarks'));
+        const list = await response.json();
+        if (!list.runs || list.runs.length === 0) {
+            target.innerHTML = '<p class="tool-note">No benchmark runs stored yet.</p>';
+            return;
+        }
+        const rows = list.runs.map(run => `
+            <tr>
+                <td class="mono">${escapeHtml(run.id)}${run.id === list.baseline ? ' <span class="rerun-badge">baseline</span>' : ''}</td>
+                <td class="mono">${escapeHtml((run.commit || '').substring(0, 8))}</td>
+                <td>${(run.benchmarks || []).length}</td>
+                <td>${run.passed ? 'ok' : 'failed'}</td>
+                <td>
+                    <button class="small-button" data-action="bench-baseline" data-id="${escapeHtml(run.id)}">Set baseline</button>
+                    ${list.baseline && run.id !== list.baseline ? `<button class="small-button" data-action="bench-compare" data-base="${escapeHtml(list.baseline)}" data-head="${escapeHtml(run.id)}">Compare to baseline</button>` : ''}
+                </td>
+            </tr>`).join('');
+        target.innerHTML = `
+            <table class="tool-table">
+                <tr><th>Run</th><th>Commit</th><th>Benchmarks</th><th>Status</th><th></th></tr>
+                ${rows}
+            </table>`;
+    } catch (error) {
+        console.error('Error loading benchmark runs:', error);
+        target.innerHTML = '<p class="tool-note">Error loading benchmark runs.</p>';
+    }
+}
+
+async function runBenchmarks() {
+    const packages = Array.from(document.querySelectorAll('#bench-packages input:checked')).map(input => input.value);
+    if (packages.length === 0) {
+        alert('Select at least one package');
+        return;
+    }
+    const body = {
+        packages: packages,
+        pattern: document.getElementById('bench-pattern').value.trim() || '.',
+        count: parseInt(document.getElementById('bench-count').value, 10) || 6
+    };
+    try {
+        const response = await fetch(api('/benchmarks/run'), {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(body)
+        });
+        if (!response.ok) throw new Error(await response.text());
+        document.getElementById('bench-progress').textContent = 'Waiting for the test runner...';
+    } catch (error) {
+        console.error('Error starting benchmarks:', error);
+        alert(`Could not start benchmarks: ${error.message}`);
+    }
+}
+
+function updateBenchmarkProgress(progress) {
+    const target = document.getElementById('bench-progress');
+    if (target) {
+        target.textContent = progress.status === 'finished'
+            ? `Run ${progress.run_id} finished.`
+            : `Benchmarking ${progress.package} (${progress.done + 1}/${progress.total})...`;
+    }
+    if (progress.status === 'finished') loadBenchmarkRuns();
+}
+
+onAction('bench-baseline', data => setBenchmarkBaseline(data.id));
+onAction('bench-compare', data => compareBenchmarks(data.base, data.head));
+
+async function setBenchmarkBaseline(id) {
+    await fetch(api('/benchmarks/baseline'), {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ id: id })
+    });
+    loadBenchmarkRuns();
+}
+
+async function compareBenchmarks(base, head) {
+    const target = document.getElementById('bench-comparison');
+    target.innerHTML = '<div class="loading">Comparing...</div>';
+    try {
+        const response = await fetch(api(`/benchmarks/compare?base=${encodeURIComponent(base)}&head=${encodeURIComponent(head)}`));
+        if (!response.ok) throw new Error(await response.text());
+        const comparison = await response.json();
+        const rows = (comparison.deltas || []).map(delta => {
+            const rowClass = delta.verdict === 'regression' ? 'regression' : (delta.verdict === 'improvement' ? 'improvement' : '');
+            const change = delta.verdict === '~' ? '~' : (delta.base.n && delta.head.n ? `${delta.delta_percent >= 0 ? '+' : ''}${delta.delta_percent.toFixed(2)}%` : delta.verdict);
+            return `
+                <tr class="${rowClass}">
+                    <td class="mono">${escapeHtml(delta.package)} ${escapeHtml(delta.name)}</td>
+                    <td>${escapeHtml(delta.unit)}</td>
+                    <td>${formatSampleSummary(delta.base, delta.unit)}</td>
+                    <td>${formatSampleSummary(delta.head, delta.unit)}</td>
+                    <td>${change}</td>
+                    <td>${delta.base.n && delta.head.n ? `p=${delta.p_value.toFixed(3)}` : ''}</td>
+                </tr>`;
+        }).join('');
+        target.innerHTML = `
+            <h3>${escapeHtml(comparison.base)} → ${escapeHtml(comparison.head)}</h3>
+            <p class="tool-note">Medians with 95% confidence intervals; deltas with p ≥ ${comparison.alpha} are shown as ~.</p>
+            <table class="tool-table">
+                <tr><th>Benchmark</th><th>Unit</th><th>Base</th><th>Head</th><th>Delta</th><th></th></tr>
+                ${rows}
+            </table>`;
+    } catch (error) {
+        console.error('Error comparing benchmarks:', error);
+        target.innerHTML = `<p class="tool-note">Comparison failed: ${escapeHtml(error.message)}</p>`;
+    }
+}
+
+// The profile currently shown in the tool modal, kept so the flame graph can zoom.
+let currentProfile = null;
+
+// mutationRun is the mutation run last opened in the mutants panel.
+let mutationRun = null;
+
+const mutationOperators = {
+    'conditional': 'Flip conditionals',
+    'boundary': 'Change boundaries',
+    'arithmetic': 'Swap arithmetic',
+    'remove-statement': 'Remove statements'
+};
+
+async function showMutations() {
+    const packages = (currentData.results || []).filter(r => r.passed && r.coverage > 0).map(r => r.package);
+    const packageOptions = packages.length > 0
+        ? packages.map(pkg => `<label class="bench-package"><input type="checkbox" value="${escapeHtml(pkg)}"> ${escapeHtml(pkg)}</label>`).join('')
+        : '<p class="tool-note">Mutation testing needs passing packages with coverage. Run the tests first.</p>';
+    const operatorOptions = Object.entries(mutationOperators)
+        .map(([op, label]) => `<label class="bench-package"><input type="checkbox" value="${op}" checked> ${label}</label>`).join('');
+
+    openToolModal('🧬 Mutation Testing', `
+        <div class="tool-section">
+            <p class="tool-note">Each mutant changes one covered line and re-runs the package tests. Mutants the tests don't notice survive and point at code that is run but not checked.</p>
+            <h3>Packages</h3>
+            <div class="bench-packages" id="mutation-packages">${packageOptions}</div>
+            <h3>Operators</h3>
+            <div class="bench-packages" id="mutation-operators">${operatorOptions}</div>
+            <button class="set-path-button" onclick="runMutations()">Run</button>
+            <p class="tool-note" id="mutation-progress"></p>
+        </div>
+        <div class="tool-section">
+            <h3>Stored runs</h3>
+            <div id="mutation-runs"><div class="loading">Loading...</div></div>
+        </div>
+        <div class="tool-section" id="mutation-report"></div>`);
+    loadMutationRuns();
+}
+
+async function loadMutationRuns() {
+    const target = document.getElementById('mutation-runs');
+    if (!target) return;
+    try {
+        const response = await fetch(api('/mutations'));
+        const runs = await response.json();
+        if (runs.length === 0) {
+            target.innerHTML = '<p class="tool-note">No mutation runs stored yet.</p>';
+            return;
+        }
+        const rows = runs.map(run => `
+            <tr>
+                <td class="mono">${escapeHtml(run.id)}</td>
+                <td class="mono">${escapeHtml((run.commit || '').substring(0, 8))}</td>
+                <td>${run.packages.map(p => escapeHtml(p.package)).join(', ')}</td>
+                <td class="${getCoverageClass(run.score)}">${run.score.toFixed(1)}%</td>
+                <td>${run.killed} / ${run.killed + run.survived}</td>
+                <td><button class="small-button" data-action="mutation-run" data-id="${escapeHtml(run.id)}">View</button></td>
+            </tr>`).join('');
+        target.innerHTML = `
+            <table class="tool-table">
+                <tr><th>Run</th><th>Commit</th><th>Packages</th><th>Score</th><th>Killed</th><th></th></tr>
+                ${rows}
+            </table>`;
+    } catch (error) {
+        console.error('Error loading mutation runs:', error);
+        target.innerHTML = '<p class="tool-note">Error loading mutation runs.</p>';
+    }
+}
+
+async function runMutations() {
+    const packages = Array.from(document.querySelectorAll('#mutation-packages input:checked')).map(input => input.value);
+    const operators = Array.from(document.querySelectorAll('#mutation-operators input:checked')).map(input => input.value);
+    if (packages.length === 0 || operators.length === 0) {
+        alert('Select at least one package and one operator');
+        return;
+    }
+    try {
+        const response = await fetch(api('/mutations/run'), {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ packages: packages, operators: operators })
+        });
+        if (!response.ok) throw new Error(await response.text());
+        document.getElementById('mutation-progress').textContent = 'Waiting for the test runner...';
+    } catch (error) {
+        console.error('Error starting mutation testing:', error);
+        alert(`Could not start mutation testing: ${error.message}`);
+    }
+}
+
+function updateMutationProgress(progress) {
+    const target = document.getElementById('mutation-progress');
+    if (target) {
+        target.textContent = progress.status === 'finished'
+            ? `Run ${progress.run_id} finished: ${progress.killed} killed, ${progress.survived} survived.`
+            : `Testing mutants of ${progress.package} (${progress.done}/${progress.total}, ${progress.survived} survived so far)...`;
+    }
+    if (progress.status === 'finished') {
+        loadMutationRuns();
+        loadMutationRun(progress.run_id);
+    }
+}
+
+onAction('mutation-run', data => loadMutationRun(data.id));
+
+async function loadMutationRun(id) {
+    const target = document.getElementById('mutation-report');
+    if (!target) return;
+    target.innerHTML = '<div class="loading">Loading mutants...</div>';
+    try {
+        const response = await fetch(api(`/mutations/${encodeURIComponent(id)}`));
+        if (!response.ok) throw new Error(await response.text());
+        mutationRun = await response.json();
+        const packages = mutationRun.packages.map(p => `
+            <tr>
+                <td class="mono">${escapeHtml(p.package)}</td>
+                ${p.error
+                    ? `<td colspan="4" class="mono">${escapeHtml(p.error)}</td>`
+                    : `<td class="${getCoverageClass(p.score)}">${p.score.toFixed(1)}%</td><td>${p.killed}</td><td>${p.survived}</td><td>${p.invalid}</td>`}
+            </tr>`).join('');
+        const survivors = mutationRun.mutants.filter(m => m.status === 'survived').map(m => `
+            <tr>
+                <td class="mono"><a href="#" data-action="mutant-source" data-file="${escapeHtml(m.file)}" data-line="${m.line}">${escapeHtml(m.file)}:${m.line}</a></td>
+                <td>${escapeHtml(mutationOperators[m.operator] || m.operator)}</td>
+                <td class="mono">${describeMutant(m)}</td>
+            </tr>`).join('');
+        target.innerHTML = `
+            <h3>Run ${escapeHtml(mutationRun.id)}: score ${mutationRun.score.toFixed(1)}%</h3>
+            <table class="tool-table">
+                <tr><th>Package</th><th>Score</th><th>Killed</th><th>Survived</th><th>Did not compile</th></tr>
+                ${packages}
+            </table>
+            <h3 style="margin-top: 1.5rem">Surviving mutants</h3>
+            ${survivors ? `<table class="tool-table">
+                <tr><th>Location</th><th>Operator</th><th>Change</th></tr>
+                ${survivors}
+            </table>` : '<p class="tool-note">Every mutant was killed.</p>'}`;
+    } catch (error) {
+        console.error('Error loading mutation run:', error);
+        target.innerHTML = `<p class="tool-note">Could not load mutation run: ${escapeHtml(error.message)}</p>`;
+    }
+}
+
+function describeMutant(mutant) {
+    return mutant.replacement === ''
+        ? `removed <code>${escapeHtml(mutant.original)}</code>`
+        : `<code>${escapeHtml(mutant.original)}</code> → <code>${escapeHtml(mutant.replacement)}</code>`;
+}
+
+// showMutantSource opens a file in the source viewer with the mutants that
+// survived in it listed under their lines.
+onAction('mutant-source', data => showMutantSource(data.file, parseInt(data.line, 10)));
+
+async function showMutantSource(path, line) {
+    const annotations = {};
+    (mutationRun ? mutationRun.mutants : [])
+        .filter(m => m.file === path && m.status === 'survived')
+        .forEach(m => {
+            (annotations[m.line] = annotations[m.line] || []).push(
+                `🧬 survived: ${describeMutant(m)} <span class="duration">(${escapeHtml(mutationOperators[m.operator] || m.operator)}, col ${m.column})</span>`);
+        });
+    await showSource(path, line, annotations);
+}
+
+function formatProfileValue(value, unit) {
+    if (unit === 'nanoseconds') {
+        if (value >= 1e9) return `${(value / 1e9).toFixed(2)}s`;
+        if (value >= 1e6) return `${(value / 1e6).toFixed(2)}ms`;
+        if (value >= 1e3) return `${(value / 1e3).toFixed(2)}µs`;
+        return `${value}ns`;
+    }
+    if (unit === 'bytes') {
+        if (value >= 1 << 30) return `${(value / (1 << 30)).toFixed(2)}GB`;
+        if (value >= 1 << 20) return `${(value / (1 << 20)).toFixed(2)}MB`;
+        if (value >= 1 << 10) return `${(value / (1 << 10)).toFixed(2)}kB`;
+        return `${value}B`;
+    }
+    return `${value.toLocaleString()} ${unit}`;
+}
+
+onAction('profile', data => showProfile(data.run, data.name));
+
+async function showProfile(runId, name, sampleType) {
+    if (!sampleType) openToolModal(`🔥 ${name}`, '<div class="loading">Parsing profile...</div>');
+    try {
+        const query = sampleType ? `?sample=${encodeURIComponent(sampleType)}` : '';
+        const response = await fetch(api(`/runs/${encodeURIComponent(runId)}/profile/${encodeURIComponent(name)}${query}`));
+        if (!response.ok) throw new Error(await response.text());
+        const summary = await response.json();
+        currentProfile = { runId: runId, name: name, summary: summary, focus: [] };
+
+        const unit = summary.sample_type.unit;
+        const sampleOptions = (summary.sample_types || []).map(t =>
+            `<option value="${escapeHtml(t.type)}" ${t.type === summary.sample_type.type ? 'selected' : ''}>${escapeHtml(t.type)} (${escapeHtml(t.unit)})</option>`).join('');
+        const rows = (summary.top || []).map(entry => `
+            <tr>
+                <td class="mono" title="${escapeHtml(entry.file)}">${escapeHtml(entry.function)}</td>
+                <td>${formatProfileValue(entry.flat, unit)}</td>
+                <td>${summary.total ? (entry.flat / summary.total * 100).toFixed(1) : '0.0'}%</td>
+                <td>${formatProfileValue(entry.cum, unit)}</td>
+                <td>${summary.total ? (entry.cum / summary.total * 100).toFixed(1) : '0.0'}%</td>
+            </tr>`).join('');
+
+        setToolModalBody(`
+            <div class="tool-section">
+                <div class="run-options">
+                    <label for="profile-sample-select">Sample:</label>
+                    <select id="profile-sample-select" onchange="showProfile('${escapeHtml(runId)}', '${escapeHtml(name)}', this.value)">${sampleOptions}</select>
+                    <span>Total ${formatProfileValue(summary.total, unit)}</span>
+                    <a class="small-button" href="${api(`/runs/${encodeURIComponent(runId)}/artifacts/${encodeURIComponent(name)}`)}">Download</a>
+                </div>
+            </div>
+            <div class="tool-section">
+                <h3>Flame graph</h3>
+                <p class="tool-note" id="flame-note">Click a frame to zoom in, click the root to zoom out.</p>
+                <div class="flame-graph" id="flame-graph"></div>
+            </div>
+            <div class="tool-section">
+                <h3>Top functions</h3>
+                ${rows ? `<table class="tool-table">
+                    <tr><th>Function</th><th>Flat</th><th>Flat%</th><th>Cum</th><th>Cum%</th></tr>
+                    ${rows}
+                </table>` : '<p class="tool-note">No samples recorded for this sample type.</p>'}
+            </div>`);
+        renderFlameGraph();
+    } catch (error) {
+        console.error('Error loading profile:', error);
+        setToolModalBody(`<p class="tool-note">Could not load profile: ${escapeHtml(error.message)}</p>`);
+    }
+}
+
+// flameColor gives each function a stable warm colour derived from its name.
+function flameColor(name) {
+    let hash = 0;
+    for (let i = 0; i < name.length; i++) hash = (hash * 31 + name.charCodeAt(i)) | 0;
+    const hue = 10 + Math.abs(hash) % 45;
+    return `hsl(${hue}, 85%, ${55 + Math.abs(hash >> 8) % 15}%)`;
+}
+
+function renderFlameGraph() {
+    const target = document.getElementById('flame-graph');
+    if (!target || !currentProfile) return;
+    const summary = currentProfile.summary;
+    if (!summary.flame || !summary.total) {
+        target.innerHTML = '<p class="tool-note">No samples recorded for this sample type.</p>';
+        return;
+    }
+
+    // Walk down to the zoomed-in frame; its path is a list of child indexes.
+    let root = summary.flame;
+    currentProfile.focus.forEach(i => { root = root.children[i]; });
+    const unit = summary.sample_type.unit;
+
+    const renderNode = (node, path, width) => {
+        const percent = (node.value / summary.total * 100).toFixed(2);
+        const title = `${node.name}\n${formatProfileValue(node.value, unit)} (${percent}%)${node.file ? '\n' + node.file : ''}`;
+        const children = (node.children || []).map((child, i) =>
+            renderNode(child, path.concat(i), child.value / node.value * 100)).join('');
+        return `
+            <div class="flame-node" style="width: ${width}%">
+                <div class="flame-frame" style="background: ${flameColor(node.name)}" title="${escapeHtml(title)}"
+                     onclick="zoomFlameGraph(${JSON.stringify(path)})">${escapeHtml(node.name)}</div>
+                <div class="flame-children">${children}</div>
+            </div>`;
+    };
+    target.innerHTML = renderNode(root, currentProfile.focus, 100);
+}
+
+function zoomFlameGraph(path) {
+    if (!currentProfile) return;
+    // Clicking the current root zooms back out one level.
+    const same = path.length === currentProfile.focus.length && path.every((v, i) => v === currentProfile.focus[i]);
+    currentProfile.focus = same ? path.slice(0, -1) : path;
+    renderFlameGraph();
+}
+
+const traceStates = [
+    { key: 'running', label: 'running', color: '#10b981' },
+    { key: 'runnable', label: 'runnable', color: '#f59e0b' },
+    { key: 'waiting', label: 'waiting', color: '#6366f1' },
+    { key: 'syscall', label: 'syscall', color: '#ef4444' }
+];
+
+function formatNanos(value) {
+    return formatProfileValue(value || 0, 'nanoseconds');
+}
+
+function renderGoroutineTimeline(trace) {
+    const samples = trace.goroutines || [];
+    if (samples.length === 0 || !trace.duration) {
+        return '<p class="tool-note">No goroutine activity recorded.</p>';
+    }
+    const maxY = Math.max(trace.max_goroutines, 1);
+    const lines = traceStates.map(state => {
+        // Step lines: a count holds until the next sample.
+        const points = [];
+        let previousY = null;
+        samples.forEach(sample => {
+            const x = (sample.offset / trace.duration * 1000).toFixed(1);
+            const y = (200 - sample[state.key] / maxY * 190).toFixed(1);
+            if (previousY !== null) points.push(`${x},${previousY}`);
+            points.push(`${x},${y}`);
+            previousY = y;
+        });
+        return `<polyline fill="none" stroke="${state.color}" stroke-width="2" vector-effect="non-scaling-stroke" points="${points.join(' ')}" />`;
+    }).join('');
+    const legend = traceStates.map(state => `<span style="--swatch: ${state.color}">${state.label}</span>`).join('');
+    return `
+        <svg class="trace-timeline" viewBox="0 0 1000 200" preserveAspectRatio="none">${lines}</svg>
+        <div class="trace-legend">${legend}<span style="--swatch: transparent">peak ${trace.max_goroutines} goroutines over ${formatNanos(trace.duration)}</span></div>`;
+}
+
+onAction('trace', data => showTraceSummary(data.package));
+
+function showTraceSummary(pkg) {
+    const result = (currentData.results || []).find(r => r.package === pkg);
+    if (!result || !result.trace) return;
+    const trace = result.trace;
+    const artifact = (result.artifacts || []).find(a => a.kind === 'trace');
+
+    const blockingRows = (trace.blocking || []).map(b => `
+        <tr>
+            <td>${escapeHtml(b.reason)}</td>
+            <td>${b.count}</td>
+            <td>${formatNanos(b.total)}</td>
+            <td>${formatNanos(b.max)}</td>
+        </tr>`).join('');
+    const gc = trace.gc || {};
+    const latency = trace.sched_latency || {};
+
+    openToolModal(`🧵 Trace: ${pkg}`, `
+        <div class="tool-section">
+            <h3>Goroutines over time</h3>
+            ${renderGoroutineTimeline(trace)}
+        </div>
+        <div class="tool-section">
+            <h3>Blocking</h3>
+            ${blockingRows ? `<table class="tool-table">
+                <tr><th>Reason</th><th>Count</th><th>Total</th><th>Longest</th></tr>
+                ${blockingRows}
+            </table>` : '<p class="tool-note">No goroutine blocked during the trace.</p>'}
+        </div>
+        <div class="tool-section">
+            <h3>Garbage collection</h3>
+            <table class="tool-table">
+                <tr><th>Cycles</th><th>Pauses</th><th>Total pause</th><th>Longest pause</th><th>Mark assist</th></tr>
+                <tr><td>${gc.cycles || 0}</td><td>${gc.pauses || 0}</td><td>${formatNanos(gc.pause_total)}</td><td>${formatNanos(gc.pause_max)}</td><td>${formatNanos(gc.mark_assist)}</td></tr>
+            </table>
+        </div>
+        <div class="tool-section">
+            <h3>Scheduler latency</h3>
+            <p class="tool-note">Time runnable goroutines waited for a processor.</p>
+            <table class="tool-table">
+                <tr><th>Count</th><th>Mean</th><th>p50</th><th>p90</th><th>p99</th><th>Max</th></tr>
+                <tr><td>${latency.count || 0}</td><td>${formatNanos(latency.mean)}</td><td>${formatNanos(latency.p50)}</td><td>${formatNanos(latency.p90)}</td><td>${formatNanos(latency.p99)}</td><td>${formatNanos(latency.max)}</td></tr>
+            </table>
+        </div>
+        ${artifact ? `<p class="tool-note">
+            <a class="small-button" href="${api(`/runs/${encodeURIComponent(currentData.run_id || '')}/artifacts/${encodeURIComponent(artifact.name)}`)}">Download trace</a>
+            Open it with <code>go tool trace</code> for the full timeline.
+        </p>` : ''}`);
+}
+
+onAction('source', data => showSource(data.source, parseInt(data.line, 10)));
+
+function renderStackFrame(frame) {
+    const location = `${frame.file}:${frame.line}`;
+    const file = frame.source
+        ? `<a data-action="source" data-source="${escapeHtml(frame.source)}" data-line="${frame.line}">${escapeHtml(location)}</a>`
+        : escapeHtml(location);
+    const runtime = /^(runtime\.|internal\/|testing\.)/.test(frame.function || '') ? 'runtime' : '';
+    return `<div class="stack-frame ${runtime}">${escapeHtml(frame.function || '')}<span class="stack-file">${file}</span></div>`;
+}
+
+function renderGoroutineGroup(group) {
+    const ids = group.ids.length > 8 ? `${group.ids.slice(0, 8).join(', ')}, …` : group.ids.join(', ');
+    return `
+        <div class="goroutine-group">
+            <div class="goroutine-group-header">
+                <strong>${group.count} × [${escapeHtml(group.state)}]</strong>
+                ${group.wait_time ? `<span class="duration">${escapeHtml(group.wait_time)}</span>` : ''}
+                <span class="duration">goroutine ${escapeHtml(ids)}</span>
+            </div>
+            <div class="stack-frames">
+                ${group.frames.map(renderStackFrame).join('')}
+                ${group.created_by ? `<div class="tool-note">created by</div>${renderStackFrame(group.created_by)}` : ''}
+            </div>
+        </div>`;
+}
+
+onAction('goroutine-dump', data => showGoroutineDump(data.package));
+
+function showGoroutineDump(pkg) {
+    const result = (currentData.results || []).find(r => r.package === pkg);
+    if (!result || !result.goroutine_dump) return;
+    const dump = result.goroutine_dump;
+    const groups = dump.groups || [];
+    const user = groups.filter(g => !g.system);
+    const system = groups.filter(g => g.system);
+
+    openToolModal(`🧵 Goroutine dump: ${pkg}`, `
+        <p class="tool-note">${dump.total} goroutines in ${groups.length} distinct stacks, captured after the package ran into the hang timeout.</p>
+        ${user.map(renderGoroutineGroup).join('')}
+        ${system.length > 0 ? `<details class="system-goroutines">
+            <summary>${system.length} runtime-internal stacks</summary>
+            ${system.map(renderGoroutineGroup).join('')}
+        </details>` : ''}`);
+}
+
+function renderDataRace(race, pkg) {
+    const accesses = race.accesses.map(access => `
+        <div class="race-access">
+            <div class="goroutine-group-header">
+                <strong>${escapeHtml(access.operation)}</strong>
+                <span class="duration">${access.goroutine ? `goroutine ${access.goroutine}` : 'main goroutine'}${access.state ? ` (${escapeHtml(access.state)})` : ''}</span>
+            </div>
+            <div class="stack-frames">${access.stack.map(renderStackFrame).join('')}</div>
+            ${access.created_at ? `<div class="tool-note">goroutine created at</div><div class="stack-frames">${access.created_at.map(renderStackFrame).join('')}</div>` : ''}
+        </div>`).join('');
+    const tests = (race.tests || []).map(escapeHtml).join(', ');
+    return `
+        <div class="goroutine-group">
+            <div class="goroutine-group-header">
+                ${pkg ? `<strong>${escapeHtml(pkg)}</strong>` : ''}
+                ${tests ? `<span class="duration">in ${tests}</span>` : ''}
+                ${race.count > 1 ? `<span class="rerun-badge">${race.count} reports</span>` : ''}
+            </div>
+            <div class="race-accesses">${accesses}</div>
+        </div>`;
+}
+
+async function showRaces() {
+    const current = [];
+    (currentData.results || []).forEach(result => {
+        (result.races || []).forEach(race => current.push(renderDataRace(race, result.package)));
+    });
+    openToolModal('🏁 Data Races', `
+        <div class="tool-section">
+            <h3>This run</h3>
+            ${current.length > 0 ? current.join('') : '<p class="tool-note">No data races in the current run. Tick "Race" before running tests to enable the race detector.</p>'}
+        </div>
+        <div class="tool-section" id="race-history"><div class="loading">Loading race history...</div></div>`);
+
+    const target = document.getElementById('race-history');
+    try {
+        const response = await fetch(api('/races'));
+        if (!response.ok) throw new Error(await response.text());
+        const history = await response.json();
+        if (history.runs.length === 0) {
+            target.innerHTML = '<h3>History</h3><p class="tool-note">No stored runs used the race detector yet.</p>';
+            return;
+        }
+        const maxRaces = Math.max(1, ...history.runs.map(run => run.races));
+        const bars = history.runs.slice(-30).map(run => `
+            <tr>
+                <td class="mono">${escapeHtml(run.run_id)}</td>
+                <td class="mono">${escapeHtml((run.commit || '').substring(0, 8))}</td>
+                <td><div class="race-bar" style="width: ${run.races / maxRaces * 100}%"></div></td>
+                <td>${run.races}</td>
+            </tr>`).join('');
+        const occurrences = history.races.map(o => `
+            <tr>
+                <td class="mono">${escapeHtml(o.race.key)}</td>
+                <td class="mono">${escapeHtml(((o.race.accesses[0] || {}).stack || [{}])[0].function || '')}</td>
+                <td>${o.runs}</td>
+                <td>${new Date(o.first_seen).toLocaleString()}</td>
+                <td>${new Date(o.last_seen).toLocaleString()}</td>
+            </tr>`).join('');
+        target.innerHTML = `
+            <h3>Races per run</h3>
+            <table class="tool-table">
+                <tr><th>Run</th><th>Commit</th><th></th><th>Races</th></tr>
+                ${bars}
+            </table>
+            <h3 style="margin-top: 1.5rem">Distinct races</h3>
+            ${occurrences ? `<table class="tool-table">
+                <tr><th>Race</th><th>Where</th><th>Runs</th><th>First seen</th><th>Last seen</th></tr>
+                ${occurrences}
+            </table>` : '<p class="tool-note">No races found in stored runs.</p>'}`;
+    } catch (error) {
+        console.error('Error loading race history:', error);
+        target.innerHTML = `<p class="tool-note">Could not load race history: ${escapeHtml(error.message)}</p>`;
+    }
+}
+
+function fuzzElementId(pkg, target) {
+    return `fuzz-${pkg}-${target}`.replace(/[^a-zA-Z0-9_-]/g, '_');
+}
+
+function describeFuzzStatus(status) {
+    if (!status) return 'idle';
+    let text = `${status.state}`;
+    if (status.elapsed) {
+        text += ` · ${status.elapsed} · ${status.execs.toLocaleString()} execs (${status.execs_per_sec.toLocaleString()}/sec) · ${status.new_interesting} new interesting · corpus ${status.corpus_size}`;
+    }
+    return text;
+}
+
+async function showFuzzTargets() {
+    openToolModal('🐛 Fuzzing', '<div class="loading">Looking for fuzz targets...</div>');
+    try {
+        const response = await fetch(api('/fuzz/targets'));
+        if (!response.ok) throw new Error(await response.text());
+        const targets = await response.json();
+        if (!targets || targets.length === 0) {
+            setToolModalBody('<div class="loading">No FuzzXxx targets found.</div>');
+            return;
+        }
+        const rows = targets.map(target => {
+            const id = fuzzElementId(target.package, target.name);
+            const running = target.session && target.session.state === 'running';
+            return `
+                <tr>
+                    <td class="mono">${escapeHtml(target.package)}</td>
+                    <td class="mono">${escapeHtml(target.name)}</td>
+                    <td>${target.seed_corpus}</td>
+                    <td id="${id}-status">${escapeHtml(describeFuzzStatus(target.session))}</td>
+                    <td>
+                        <button class="small-button" data-action="fuzz-start" data-package="${escapeHtml(target.package)}" data-target="${escapeHtml(target.name)}" ${running ? 'disabled' : ''}>Start</button>
+                        <button class="small-button" data-action="fuzz-stop" data-package="${escapeHtml(target.package)}" data-target="${escapeHtml(target.name)}">Stop</button>
+                        <button class="small-button" data-action="fuzz-corpus" data-package="${escapeHtml(target.package)}" data-target="${escapeHtml(target.name)}">Corpus</button>
+                    </td>
+                </tr>`;
+        }).join('');
+        setToolModalBody(`
+            <div class="tool-section">
+                <div class="path-input-group">
+                    <input type="text" id="fuzz-duration" value="1m" title="-fuzztime, e.g. 30s or 10m" />
+                </div>
+            </div>
+            <table class="tool-table">
+                <tr><th>Package</th><th>Target</th><th>Seeds</th><th>Status</th><th></th></tr>
+                ${rows}
+            </table>
+            <div class="tool-section" id="fuzz-detail"></div>`);
+    } catch (error) {
+        console.error('Error loading fuzz targets:', error);
+        setToolModalBody('<div class="loading">Error loading fuzz targets.</div>');
+    }
+}
+
+onAction('fuzz-start', data => startFuzz(data.package, data.target));
+onAction('fuzz-stop', data => stopFuzz(data.package, data.target));
+onAction('fuzz-corpus', data => showFuzzCorpus(data.package, data.target));
+onAction('fuzz-rerun', data => rerunFuzzInput(data.package, data.target, data.input));
+
+async function startFuzz(pkg, target) {
+    const durationInput = document.getElementById('fuzz-duration');
+    try {
+        const response = await fetch(api('/fuzz/start'), {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ package: pkg, target: target, duration: durationInput ? durationInput.value.trim() : '1m' })
+        });
+        if (!response.ok) throw new Error(await response.text());
+        updateFuzzProgress(await response.json());
+    } catch (error) {
+        console.error('Error starting fuzzing:', error);
+        alert(`Could not start fuzzing: ${error.message}`);
+    }
+}
+
+async function stopFuzz(pkg, target) {
+    const response = await fetch(api('/fuzz/stop'), {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ package: pkg, target: target })
+    });
+    if (!response.ok) alert(await response.text());
+}
+
+function updateFuzzProgress(status) {
+    const statusEl = document.getElementById(`${fuzzElementId(status.package, status.target)}-status`);
+    if (statusEl) statusEl.textContent = describeFuzzStatus(status);
+
+    if (status.state === 'failed' && status.crasher) {
+        const detail = document.getElementById('fuzz-detail');
+        if (detail) {
+            detail.innerHTML = `
+                <h3>💥 ${escapeHtml(status.target)} found a failing input</h3>
+                <p class="tool-note">Written to testdata/fuzz/${escapeHtml(status.target)}/${escapeHtml(status.crasher)}</p>
+                <div class="test-output">${escapeHtml(status.crasher_input || '')}</div>
+                <button class="coverage-link" data-action="fuzz-rerun" data-package="${escapeHtml(status.package)}" data-target="${escapeHtml(status.target)}" data-input="${escapeHtml(status.crasher)}">↻ Re-run this input</button>
+                <div class="test-output">${escapeHtml(status.output || '')}</div>
+                <div id="fuzz-rerun-result"></div>`;
+        }
+    }
+}
+
+async function showFuzzCorpus(pkg, target) {
+    const detail = document.getElementById('fuzz-detail');
+    detail.innerHTML = '<div class="loading">Loading corpus...</div>';
+    try {
+        const response = await fetch(api(`/fuzz/corpus?package=${encodeURIComponent(pkg)}&target=${encodeURIComponent(target)}`));
+        if (!response.ok) throw new Error(await response.text());
+        const corpus = await response.json();
+        const entries = (corpus.entries || []).map(entry => `
+            <div class="tool-section">
+                <div class="test-case">
+                    <span class="test-case-name">${escapeHtml(entry.name)}</span>
+                    <span class="duration">${entry.size} bytes · ${new Date(entry.mod_time).toLocaleString()}</span>
+                    <button class="small-button" data-action="fuzz-rerun" data-package="${escapeHtml(pkg)}" data-target="${escapeHtml(target)}" data-input="${escapeHtml(entry.name)}">Re-run</button>
+                </div>
+                <div class="test-output">${escapeHtml(entry.content)}${entry.truncated ? '\n…' : ''}</div>
+            </div>`).join('');
+        detail.innerHTML = `
+            <h3>${escapeHtml(target)} corpus</h3>
+            <p class="tool-note">${(corpus.entries || []).length} seed entries in testdata/fuzz · ${corpus.cached_entries} generated entries in the build cache</p>
+            <div id="fuzz-rerun-result"></div>
+            ${entries}`;
+    } catch (error) {
+        console.error('Error loading corpus:', error);
+        detail.innerHTML = `<p class="tool-note">Could not load corpus: ${escapeHtml(error.message)}</p>`;
+    }
+}
+
+async function rerunFuzzInput(pkg, target, input) {
+    const resultEl = document.getElementById('fuzz-rerun-result');
+    if (resultEl) resultEl.innerHTML = '<div class="loading">Re-running...</div>';
+    try {
+        const response = await fetch(api('/fuzz/rerun'), {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ package: pkg, target: target, input: input })
+        });
+        if (!response.ok) throw new Error(await response.text());
+        const result = await response.json();
+        const verdict = result.error
+            ? `${result.timed_out ? '⚠ ' : ''}${escapeHtml(result.error)}`
+            : (result.failures > 0 ? '❌ Still failing' : '✅ Passes now');
+        if (resultEl) {
+            resultEl.innerHTML = `<p class="tool-note">${escapeHtml(input)}: ${verdict}</p>
+                ${result.failure_output ? `<div class="test-output">${escapeHtml(result.failure_output)}</div>` : ''}`;
+        }
+    } catch (error) {
+        console.error('Error re-running fuzz input:', error);
+        alert(`Re-run failed: ${error.message}`);
+    }
+}
+
 connectWebSocket();
+loadProjects();
+setInterval(loadProjects, 5000);
 
 document.addEventListener('DOMContentLoaded', () => {
     const runButton = document.getElementById('run-button');
+    const rerunButton = document.getElementById('rerun-button');
+    const flakyButton = document.getElementById('flaky-button');
+    const benchButton = document.getElementById('bench-button');
+    const fuzzButton = document.getElementById('fuzz-button');
+    const raceButton = document.getElementById('race-button');
+    const impactButton = document.getElementById('impact-button');
+    const mutationButton = document.getElementById('mutation-button');
+    const webhookButton = document.getElementById('webhook-button');
+    const commitsButton = document.getElementById('commits-button');
+    const blameButton = document.getElementById('blame-button');
+    const compareButton = document.getElementById('compare-button');
+    const toolModal = document.getElementById('tool-modal');
     const projectButton = document.getElementById('project-button');
     const setPathButton = document.getElementById('set-path-button');
     const manualPathInput = document.getElementById('manual-path-input');
@@ -381,8 +2294,20 @@ document.addEventListener('DOMContentLoaded', () => {
     const coverageModal = document.getElementById('coverage-modal');
 
     runButton.addEventListener('click', runTests);
+    rerunButton.addEventListener('click', rerunFailed);
+    flakyButton.addEventListener('click', showFlakyTests);
+    benchButton.addEventListener('click', showBenchmarks);
+    fuzzButton.addEventListener('click', showFuzzTargets);
+    raceButton.addEventListener('click', showRaces);
+    impactButton.addEventListener('click', showImpactedTests);
+    mutationButton.addEventListener('click', showMutations);
+    webhookButton.addEventListener('click', showWebhooks);
+    commitsButton.addEventListener('click', showCommits);
+    blameButton.addEventListener('click', showBlameReport);
+    compareButton.addEventListener('click', showCompareRuns);
     projectButton.addEventListener('click', showProjectModal);
     setPathButton.addEventListener('click', setProjectPath);
+    document.getElementById('project-select').addEventListener('change', (event) => switchProject(event.target.value));
 
     manualPathInput.addEventListener('keypress', (e) => {
         if (e.key === 'Enter') setProjectPath();
@@ -396,10 +2321,15 @@ document.addEventListener('DOMContentLoaded', () => {
         if (event.target === coverageModal) closeCoverage();
     });
 
+    toolModal.addEventListener('click', (event) => {
+        if (event.target === toolModal) closeToolModal();
+    });
+
     document.addEventListener('keydown', (event) => {
         if (event.key === 'Escape') {
             if (projectModal.classList.contains('show')) closeProjectModal();
             if (coverageModal.classList.contains('show')) closeCoverage();
+            if (toolModal.classList.contains('show')) closeToolModal();
         }
     });
 